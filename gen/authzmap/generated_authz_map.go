@@ -2,157 +2,1008 @@
 
 package authzmap
 
-import "strings"
+import (
+	"context"
+	"strings"
+)
 
 // AuthzRule represents authorization rules for a method
 type AuthzRule struct {
-	Permissions    []string
-	NoAuthRequired bool
+	Permissions       []string
+	RawPermissions    []string        // permissions as declared, set when wildcards were expanded
+	DeniedPermissions []string        // permissions rejecting the caller, whatever the other permissions it holds
+	Roles             []string        // roles the caller must hold one of, on top of satisfying the permissions
+	AllowedScopes     []string        // OAuth scopes granting access like Permissions, kept apart from them
+	Require           *PermissionExpr // boolean requirement, when set it supersedes the any-of semantics of Permissions and AllowedScopes
+	NoAuthRequired    bool
+	Deprecated        bool // whether the method is marked with option deprecated = true
+	// EnvOverrides is the rule in effect per environment, e.g. staging, for the environments overriding it
+	EnvOverrides map[string]AuthzRule
+	// TemplatedPermissions is set when Permissions reference path variables, e.g. project:{project_id}:read
+	TemplatedPermissions bool
+	// PermissionParams are the path variables or request fields referenced by the placeholders of Permissions in order
+	PermissionParams []string
+	// Level is the proto level the rule was declared at: file, service or method
+	Level string
+	// StreamingType is the streaming kind of the method: none, client, server or bidi
+	StreamingType string
+	// Transport is http, or grpc for rules of methods without HTTP annotation keyed by their gRPC full method name
+	Transport string
+	// GRPCMethod is the gRPC full method name, e.g. /package.Service/Method
+	GRPCMethod string
+	// PathParams are the flat names of the variables of the HTTP path template in order, e.g. item_id for /v1/{item.id}
+	PathParams []string
+	// PathParamPatterns is the pattern of the path variables declaring one, e.g. ** for {name=**}
+	PathParamPatterns map[string]string
+	// PathParamFields is the request field path of the nested path variables, e.g. item.id for item_id
+	PathParamFields map[string]string
+	// Body is the request field mapped to the HTTP body, * for the whole request
+	Body string
+	// ResponseBody is the response field mapped to the HTTP body, empty for the whole response
+	ResponseBody string
+	// ProtoPackage, ServiceName and MethodName identify the method the rule was extracted from
+	ProtoPackage string
+	ServiceName  string
+	MethodName   string
+}
+
+// PermissionChecker resolves the permissions of the caller of a request
+type PermissionChecker interface {
+	// Permissions returns the permissions granted to the caller, an error means the caller is not authenticated
+	Permissions(ctx context.Context) ([]string, error)
+}
+
+// RoleChecker is optionally implemented by a PermissionChecker to resolve the roles of the caller as well
+// Callers of a PermissionChecker not implementing it hold no role
+type RoleChecker interface {
+	// Roles returns the roles granted to the caller, an error means the caller is not authenticated
+	Roles(ctx context.Context) ([]string, error)
+}
+
+// ScopeChecker is optionally implemented by a PermissionChecker to resolve the OAuth scopes of the caller apart
+// from its permissions. The AllowedScopes of the rules are checked against the permissions of callers of a
+// PermissionChecker not implementing it
+type ScopeChecker interface {
+	// Scopes returns the OAuth scopes granted to the caller, an error means the caller is not authenticated
+	Scopes(ctx context.Context) ([]string, error)
+}
+
+// Checker tells whether the caller holds a permission or a role
+type Checker interface {
+	HasPermission(permission string) bool
+	HasRole(role string) bool
+}
+
+// ScopedChecker is a Checker telling the OAuth scopes of the caller apart from its permissions
+// The AllowedScopes of the rules are checked with HasPermission on the Checkers not implementing it
+type ScopedChecker interface {
+	Checker
+	HasScope(scope string) bool
+}
+
+// grants is a ScopedChecker over the permissions, roles and scopes of a caller, compared case-insensitively
+type grants struct {
+	permissions map[string]bool
+	roles       map[string]bool
+	scopes      map[string]bool // nil when the scopes are not told apart from the permissions
+}
+
+// newGrants returns the Checker of a caller holding permissions and roles
+func newGrants(permissions, roles []string) grants {
+	g := grants{permissions: make(map[string]bool, len(permissions)), roles: make(map[string]bool, len(roles))}
+	for _, permission := range permissions {
+		g.permissions[strings.ToLower(permission)] = true
+	}
+	for _, role := range roles {
+		g.roles[strings.ToLower(role)] = true
+	}
+	return g
+}
+
+func (g grants) HasPermission(permission string) bool {
+	return g.permissions[strings.ToLower(permission)]
+}
+
+func (g grants) HasRole(role string) bool {
+	return g.roles[strings.ToLower(role)]
+}
+
+func (g grants) HasScope(scope string) bool {
+	if g.scopes == nil {
+		return g.HasPermission(scope)
+	}
+	return g.scopes[strings.ToLower(scope)]
+}
+
+// callerGrants resolves the permissions of the caller, its roles when checker implements RoleChecker and its
+// scopes when checker implements ScopeChecker
+func callerGrants(ctx context.Context, checker PermissionChecker) (grants, error) {
+	permissions, err := checker.Permissions(ctx)
+	if err != nil {
+		return grants{}, err
+	}
+	var roles []string
+	if roleChecker, ok := checker.(RoleChecker); ok {
+		if roles, err = roleChecker.Roles(ctx); err != nil {
+			return grants{}, err
+		}
+	}
+	g := newGrants(permissions, roles)
+	if scopeChecker, ok := checker.(ScopeChecker); ok {
+		scopes, err := scopeChecker.Scopes(ctx)
+		if err != nil {
+			return grants{}, err
+		}
+		g.scopes = make(map[string]bool, len(scopes))
+		for _, scope := range scopes {
+			g.scopes[strings.ToLower(scope)] = true
+		}
+	}
+	return g, nil
+}
+
+// PermissionExpr is a boolean combination of permissions
+// It is satisfied when every non-empty clause is satisfied
+type PermissionExpr struct {
+	AnyOf []string         // at least one of these permissions
+	AllOf []string         // every one of these permissions
+	All   []PermissionExpr // every nested expression
+	Any   []PermissionExpr // at least one nested expression
+}
+
+// Evaluate reports whether the expression is satisfied given a function telling whether a permission is granted
+func (e PermissionExpr) Evaluate(hasPermission func(permission string) bool) bool {
+	if len(e.AnyOf) > 0 {
+		granted := false
+		for _, permission := range e.AnyOf {
+			if hasPermission(permission) {
+				granted = true
+				break
+			}
+		}
+		if !granted {
+			return false
+		}
+	}
+	for _, permission := range e.AllOf {
+		if !hasPermission(permission) {
+			return false
+		}
+	}
+	for _, nested := range e.All {
+		if !nested.Evaluate(hasPermission) {
+			return false
+		}
+	}
+	if len(e.Any) > 0 {
+		for _, nested := range e.Any {
+			if nested.Evaluate(hasPermission) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// ForEnv returns the rule in effect in env, the rule itself when env does not override it
+func (rule AuthzRule) ForEnv(env string) AuthzRule {
+	if override, ok := rule.EnvOverrides[env]; ok {
+		return override
+	}
+	return rule
+}
+
+// Allows reports whether a caller with the given permissions and no role satisfies the rule
+func (rule AuthzRule) Allows(userPermissions []string) bool {
+	return rule.Check(newGrants(userPermissions, nil))
+}
+
+// AllowsWithRoles reports whether a caller with the given permissions and roles satisfies the rule
+func (rule AuthzRule) AllowsWithRoles(userPermissions, userRoles []string) bool {
+	return rule.Check(newGrants(userPermissions, userRoles))
+}
+
+// Check reports whether the caller described by checker satisfies the rule
+// A rule declaring both roles and permissions requires one of the roles and the permissions
+// The allowed scopes grant access like the permissions, checked with HasScope when checker is a ScopedChecker
+func (rule AuthzRule) Check(checker Checker) bool {
+	// If no auth is required, always allow
+	if rule.NoAuthRequired {
+		return true
+	}
+
+	// Explicitly reject callers holding a denied permission
+	for _, permission := range rule.DeniedPermissions {
+		if checker.HasPermission(permission) {
+			return false
+		}
+	}
+
+	// Check if user has any of the required roles, which is enough when no permission nor scope is declared
+	if len(rule.Roles) > 0 {
+		hasRole := false
+		for _, role := range rule.Roles {
+			if checker.HasRole(role) {
+				hasRole = true
+				break
+			}
+		}
+		if !hasRole {
+			return false
+		}
+		if rule.Require == nil && len(rule.Permissions) == 0 && len(rule.AllowedScopes) == 0 {
+			return true
+		}
+	}
+
+	// Evaluate the boolean requirement when declared
+	if rule.Require != nil {
+		return rule.Require.Evaluate(checker.HasPermission)
+	}
+
+	// Check if user has any of the required permissions
+	for _, permission := range rule.Permissions {
+		if checker.HasPermission(permission) {
+			return true
+		}
+	}
+
+	// Or any of the allowed OAuth scopes
+	scopedChecker, scoped := checker.(ScopedChecker)
+	for _, scope := range rule.AllowedScopes {
+		if (scoped && scopedChecker.HasScope(scope)) || (!scoped && checker.HasPermission(scope)) {
+			return true
+		}
+	}
+	return false
 }
 
 // generatedAuthzMap contains authorization rules extracted from proto definitions
 // This map is automatically generated during go tool buf generate
 var generatedAuthzMap = map[string]AuthzRule{
+	"/proto.v1.TestGRPCService/TestGRPCNoPermissions": {
+		Permissions:    []string{},
+		NoAuthRequired: true,
+		Level:          "method",
+		StreamingType:  "none",
+		Transport:      "grpc",
+		GRPCMethod:     "/proto.v1.TestGRPCService/TestGRPCNoPermissions",
+		ProtoPackage:   "proto.v1",
+		ServiceName:    "TestGRPCService",
+		MethodName:     "TestGRPCNoPermissions",
+	},
+	"/proto.v1.TestGRPCService/TestGRPCWithPermissions": {
+		Permissions:    []string{"read:all"},
+		NoAuthRequired: false,
+		Level:          "method",
+		StreamingType:  "none",
+		Transport:      "grpc",
+		GRPCMethod:     "/proto.v1.TestGRPCService/TestGRPCWithPermissions",
+		ProtoPackage:   "proto.v1",
+		ServiceName:    "TestGRPCService",
+		MethodName:     "TestGRPCWithPermissions",
+	},
+	"/v1/defaults/{foo_id}|GET": {
+		Permissions:    []string{"admin:all"},
+		NoAuthRequired: false,
+		Level:          "service",
+		StreamingType:  "none",
+		Transport:      "http",
+		GRPCMethod:     "/proto.v1.TestDefaultsService/TestDefaultOnly",
+		PathParams:     []string{"foo_id"},
+		ProtoPackage:   "proto.v1",
+		ServiceName:    "TestDefaultsService",
+		MethodName:     "TestDefaultOnly",
+	},
+	"/v1/defaults/{foo_id}|POST": {
+		Permissions:    []string{"read:all"},
+		NoAuthRequired: false,
+		Level:          "method",
+		StreamingType:  "none",
+		Transport:      "http",
+		GRPCMethod:     "/proto.v1.TestDefaultsService/TestDefaultOverride",
+		PathParams:     []string{"foo_id"},
+		Body:           "*",
+		ProtoPackage:   "proto.v1",
+		ServiceName:    "TestDefaultsService",
+		MethodName:     "TestDefaultOverride",
+	},
+	"/v1/defaults/{foo_id}/public|GET": {
+		Permissions:    []string{},
+		NoAuthRequired: true,
+		Level:          "method",
+		StreamingType:  "none",
+		Transport:      "http",
+		GRPCMethod:     "/proto.v1.TestDefaultsService/TestDefaultOverrideNoAuth",
+		PathParams:     []string{"foo_id"},
+		ProtoPackage:   "proto.v1",
+		ServiceName:    "TestDefaultsService",
+		MethodName:     "TestDefaultOverrideNoAuth",
+	},
+	"/v1/foos/{foo_id}/test3|GET": {
+		Permissions:    []string{"read:all"},
+		NoAuthRequired: false,
+		Level:          "method",
+		StreamingType:  "none",
+		Transport:      "http",
+		GRPCMethod:     "/proto.v1.TestService/TestWithAdditionalBindings",
+		PathParams:     []string{"foo_id"},
+		ProtoPackage:   "proto.v1",
+		ServiceName:    "TestService",
+		MethodName:     "TestWithAdditionalBindings",
+	},
+	"/v1/groups|GET": {
+		Permissions:    []string{"groups:list"},
+		NoAuthRequired: false,
+		Level:          "method",
+		StreamingType:  "none",
+		Transport:      "http",
+		GRPCMethod:     "/proto.v1.TestGroupsService/List",
+		ProtoPackage:   "proto.v1",
+		ServiceName:    "TestGroupsService",
+		MethodName:     "List",
+	},
+	"/v1/merge-defaults/{foo_id}|GET": {
+		Permissions:    []string{"admin:all", "read:all"},
+		NoAuthRequired: false,
+		Level:          "method",
+		StreamingType:  "none",
+		Transport:      "http",
+		GRPCMethod:     "/proto.v1.TestMergeDefaultsService/TestMergeDefault",
+		PathParams:     []string{"foo_id"},
+		ProtoPackage:   "proto.v1",
+		ServiceName:    "TestMergeDefaultsService",
+		MethodName:     "TestMergeDefault",
+	},
+	"/v1/merge-defaults/{foo_id}/public|GET": {
+		Permissions:    []string{},
+		NoAuthRequired: true,
+		Level:          "method",
+		StreamingType:  "none",
+		Transport:      "http",
+		GRPCMethod:     "/proto.v1.TestMergeDefaultsService/TestMergeDefaultNoAuth",
+		PathParams:     []string{"foo_id"},
+		ProtoPackage:   "proto.v1",
+		ServiceName:    "TestMergeDefaultsService",
+		MethodName:     "TestMergeDefaultNoAuth",
+	},
+	"/v1/metrics:report|REPORT": {
+		Permissions:    []string{"read:all"},
+		NoAuthRequired: false,
+		Level:          "method",
+		StreamingType:  "none",
+		Transport:      "http",
+		GRPCMethod:     "/proto.v1.TestService/TestWithCustomReportVerb",
+		ProtoPackage:   "proto.v1",
+		ServiceName:    "TestService",
+		MethodName:     "TestWithCustomReportVerb",
+	},
+	"/v1/streaming/{foo_id}|GET": {
+		Permissions:    []string{},
+		NoAuthRequired: true,
+		Level:          "method",
+		StreamingType:  "server",
+		Transport:      "http",
+		GRPCMethod:     "/proto.v1.TestStreamingService/TestServerStreaming",
+		PathParams:     []string{"foo_id"},
+		ProtoPackage:   "proto.v1",
+		ServiceName:    "TestStreamingService",
+		MethodName:     "TestServerStreaming",
+	},
+	"/v1/streaming/{foo_id}|POST": {
+		Permissions:    []string{"stream:all"},
+		NoAuthRequired: false,
+		Level:          "method",
+		StreamingType:  "bidi",
+		Transport:      "http",
+		GRPCMethod:     "/proto.v1.TestStreamingService/TestBidiStreaming",
+		PathParams:     []string{"foo_id"},
+		Body:           "*",
+		ProtoPackage:   "proto.v1",
+		ServiceName:    "TestStreamingService",
+		MethodName:     "TestBidiStreaming",
+	},
 	"/v1/test/{foo_id}|POST": {
 		Permissions:    []string{},
 		NoAuthRequired: true,
+		Level:          "method",
+		StreamingType:  "none",
+		Transport:      "http",
+		GRPCMethod:     "/proto.v1.TestService/TestNoPermissions",
+		PathParams:     []string{"foo_id"},
+		Body:           "*",
+		ProtoPackage:   "proto.v1",
+		ServiceName:    "TestService",
+		MethodName:     "TestNoPermissions",
+	},
+	"/v1/test10/{foo_id}/{path=files/**}|GET": {
+		Permissions:       []string{"read:all"},
+		NoAuthRequired:    false,
+		Level:             "method",
+		StreamingType:     "none",
+		Transport:         "http",
+		GRPCMethod:        "/proto.v1.TestService/TestWithGlobPath",
+		PathParams:        []string{"foo_id", "path"},
+		PathParamPatterns: map[string]string{"path": "files/**"},
+		ProtoPackage:      "proto.v1",
+		ServiceName:       "TestService",
+		MethodName:        "TestWithGlobPath",
+	},
+	"/v1/test11/{item.owner.id}|PATCH": {
+		Permissions:     []string{"write:all"},
+		NoAuthRequired:  false,
+		Level:           "method",
+		StreamingType:   "none",
+		Transport:       "http",
+		GRPCMethod:      "/proto.v1.TestService/TestWithNestedField",
+		PathParams:      []string{"item_owner_id"},
+		PathParamFields: map[string]string{"item_owner_id": "item.owner.id"},
+		ProtoPackage:    "proto.v1",
+		ServiceName:     "TestService",
+		MethodName:      "TestWithNestedField",
+	},
+	"/v1/test12/{foo_id}|GET": {
+		Permissions:    []string{"read:all"},
+		AllowedScopes:  []string{"read:test"},
+		NoAuthRequired: false,
+		Level:          "method",
+		StreamingType:  "none",
+		Transport:      "http",
+		GRPCMethod:     "/proto.v1.TestService/TestWithScopes",
+		PathParams:     []string{"foo_id"},
+		ProtoPackage:   "proto.v1",
+		ServiceName:    "TestService",
+		MethodName:     "TestWithScopes",
+	},
+	"/v1/test13/{foo_id}|GET": {
+		Permissions:          []string{"read:all", "foo:{foo_id}:read"},
+		NoAuthRequired:       false,
+		TemplatedPermissions: true,
+		PermissionParams:     []string{"foo_id"},
+		Level:                "method",
+		StreamingType:        "none",
+		Transport:            "http",
+		GRPCMethod:           "/proto.v1.TestService/TestWithTemplatedPermissions",
+		PathParams:           []string{"foo_id"},
+		ProtoPackage:         "proto.v1",
+		ServiceName:          "TestService",
+		MethodName:           "TestWithTemplatedPermissions",
 	},
 	"/v1/test2/{foo_id}|POST": {
 		Permissions:    []string{"read:all"},
 		NoAuthRequired: false,
+		EnvOverrides: map[string]AuthzRule{
+			"staging": {
+				Permissions:    []string{},
+				NoAuthRequired: true,
+				Level:          "method",
+				StreamingType:  "none",
+				Transport:      "http",
+				GRPCMethod:     "/proto.v1.TestService/TestWithPermissions",
+				PathParams:     []string{"foo_id"},
+				Body:           "*",
+				ProtoPackage:   "proto.v1",
+				ServiceName:    "TestService",
+				MethodName:     "TestWithPermissions",
+			},
+		},
+		Level:         "method",
+		StreamingType: "none",
+		Transport:     "http",
+		GRPCMethod:    "/proto.v1.TestService/TestWithPermissions",
+		PathParams:    []string{"foo_id"},
+		Body:          "*",
+		ProtoPackage:  "proto.v1",
+		ServiceName:   "TestService",
+		MethodName:    "TestWithPermissions",
+	},
+	"/v1/test3/{foo_id}|GET": {
+		Permissions:    []string{"read:all"},
+		NoAuthRequired: false,
+		Level:          "method",
+		StreamingType:  "none",
+		Transport:      "http",
+		GRPCMethod:     "/proto.v1.TestService/TestWithAdditionalBindings",
+		PathParams:     []string{"foo_id"},
+		ProtoPackage:   "proto.v1",
+		ServiceName:    "TestService",
+		MethodName:     "TestWithAdditionalBindings",
+	},
+	"/v1/test4/{foo_id}|OPTIONS": {
+		Permissions:    []string{},
+		NoAuthRequired: true,
+		Level:          "method",
+		StreamingType:  "none",
+		Transport:      "http",
+		GRPCMethod:     "/proto.v1.TestService/TestWithCustomVerb",
+		PathParams:     []string{"foo_id"},
+		ProtoPackage:   "proto.v1",
+		ServiceName:    "TestService",
+		MethodName:     "TestWithCustomVerb",
+	},
+	"/v1/test5/{foo_id}|POST": {
+		Permissions:    []string{"read:all", "read:test", "write:test", "admin:all", "owner:test"},
+		Require:        &PermissionExpr{AnyOf: []string{"read:all", "read:test"}, AllOf: []string{"write:test"}, Any: []PermissionExpr{{AllOf: []string{"admin:all"}}, {AllOf: []string{"owner:test"}}}},
+		NoAuthRequired: false,
+		Level:          "method",
+		StreamingType:  "none",
+		Transport:      "http",
+		GRPCMethod:     "/proto.v1.TestService/TestWithRequirement",
+		PathParams:     []string{"foo_id"},
+		Body:           "*",
+		ProtoPackage:   "proto.v1",
+		ServiceName:    "TestService",
+		MethodName:     "TestWithRequirement",
+	},
+	"/v1/test6/{foo_id}|GET": {
+		Permissions:    []string{"read:all", "read:test"},
+		NoAuthRequired: false,
+		Deprecated:     true,
+		Level:          "method",
+		StreamingType:  "none",
+		Transport:      "http",
+		GRPCMethod:     "/proto.v1.TestService/TestWithFieldSyntax",
+		PathParams:     []string{"foo_id"},
+		ProtoPackage:   "proto.v1",
+		ServiceName:    "TestService",
+		MethodName:     "TestWithFieldSyntax",
+	},
+	"/v1/test7/{foo_id}|GET": {
+		Permissions:    []string{"read:all", "read:test"},
+		RawPermissions: []string{"read:*"},
+		NoAuthRequired: false,
+		Level:          "method",
+		StreamingType:  "none",
+		Transport:      "http",
+		GRPCMethod:     "/proto.v1.TestService/TestWithWildcard",
+		PathParams:     []string{"foo_id"},
+		ProtoPackage:   "proto.v1",
+		ServiceName:    "TestService",
+		MethodName:     "TestWithWildcard",
+	},
+	"/v1/test8/{foo_id}|POST": {
+		Permissions:       []string{"read:all"},
+		DeniedPermissions: []string{"banned:all"},
+		NoAuthRequired:    false,
+		Level:             "method",
+		StreamingType:     "none",
+		Transport:         "http",
+		GRPCMethod:        "/proto.v1.TestService/TestWithDeniedPermission",
+		PathParams:        []string{"foo_id"},
+		Body:              "*",
+		ProtoPackage:      "proto.v1",
+		ServiceName:       "TestService",
+		MethodName:        "TestWithDeniedPermission",
+	},
+	"/v1/test9/{foo_id}|GET": {
+		Permissions:    []string{},
+		Roles:          []string{"admin", "support"},
+		NoAuthRequired: false,
+		Level:          "method",
+		StreamingType:  "none",
+		Transport:      "http",
+		GRPCMethod:     "/proto.v1.TestService/TestWithRoles",
+		PathParams:     []string{"foo_id"},
+		ProtoPackage:   "proto.v1",
+		ServiceName:    "TestService",
+		MethodName:     "TestWithRoles",
+	},
+	"/v1/test9/{foo_id}|POST": {
+		Permissions:    []string{"write:all"},
+		Roles:          []string{"admin"},
+		NoAuthRequired: false,
+		Level:          "method",
+		StreamingType:  "none",
+		Transport:      "http",
+		GRPCMethod:     "/proto.v1.TestService/TestWithRolesAndPermissions",
+		PathParams:     []string{"foo_id"},
+		Body:           "*",
+		ProtoPackage:   "proto.v1",
+		ServiceName:    "TestService",
+		MethodName:     "TestWithRolesAndPermissions",
+	},
+	"/v1/users|GET": {
+		Permissions:    []string{"users:list"},
+		NoAuthRequired: false,
+		Level:          "method",
+		StreamingType:  "none",
+		Transport:      "http",
+		GRPCMethod:     "/proto.v1.TestUsersService/List",
+		ProtoPackage:   "proto.v1",
+		ServiceName:    "TestUsersService",
+		MethodName:     "List",
+	},
+	"/v1/without-defaults/{foo_id}|GET": {
+		Permissions:    []string{"internal:all"},
+		NoAuthRequired: false,
+		Level:          "file",
+		StreamingType:  "none",
+		Transport:      "http",
+		GRPCMethod:     "/proto.v1.TestWithoutDefaultsService/TestWithoutDefault",
+		PathParams:     []string{"foo_id"},
+		ProtoPackage:   "proto.v1",
+		ServiceName:    "TestWithoutDefaultsService",
+		MethodName:     "TestWithoutDefault",
+	},
+	"/v1/without-defaults/{foo_id}/permissions|GET": {
+		Permissions:    []string{"read:all"},
+		NoAuthRequired: false,
+		Level:          "method",
+		StreamingType:  "none",
+		Transport:      "http",
+		GRPCMethod:     "/proto.v1.TestWithoutDefaultsService/TestWithoutDefaultWithPermissions",
+		PathParams:     []string{"foo_id"},
+		ProtoPackage:   "proto.v1",
+		ServiceName:    "TestWithoutDefaultsService",
+		MethodName:     "TestWithoutDefaultWithPermissions",
 	},
 }
 
-// normalizePathForAuthzWithMap converts a path with actual values to its template form
-// by matching against all known parameterized paths in the provided authz map for the given method
-// e.g., "/v1/foo/123" -> "/v1/foo/{foo_id}"
-func normalizePathForAuthzWithMap(authzMap map[string]AuthzRule, actualPath, method string) string {
-	actualParts := strings.Split(strings.Trim(actualPath, "/"), "/")
+// ResolvePermissions returns the permissions of the rule, the placeholders of templated permissions such as
+// project:{project_id}:read being replaced by the values of params, keyed by path variable or request field
+// It returns false when a placeholder is left unresolved, or its value is empty or contains :, *, {, } or /,
+// which would let a request name the permission of another resource or a wildcard one
+// The permissions of rules without template are returned as is
+func ResolvePermissions(rule AuthzRule, params map[string]string) ([]string, bool) {
+	if !rule.TemplatedPermissions {
+		return rule.Permissions, true
+	}
 
-	// Try to match against all known parameterized paths in the map for this specific method
-	for templatePath := range authzMap {
-		// Only consider templates with matching HTTP method
-		pathAndMethod := strings.Split(templatePath, "|")
-		if len(pathAndMethod) != 2 || pathAndMethod[1] != method {
-			continue
+	replacements := make([]string, 0, 2*len(rule.PermissionParams))
+	for _, name := range rule.PermissionParams {
+		value, ok := params[name]
+		if !ok || value == "" || strings.ContainsAny(value, ":*{}/") {
+			return nil, false
+		}
+		replacements = append(replacements, "{"+name+"}", value)
+	}
+	replacer := strings.NewReplacer(replacements...)
+	resolved := make([]string, len(rule.Permissions))
+	for i, permission := range rule.Permissions {
+		resolved[i] = replacer.Replace(permission)
+		if strings.ContainsAny(resolved[i], "{}") {
+			return nil, false
 		}
-		path := pathAndMethod[0]
-		templateParts := strings.Split(strings.Trim(path, "/"), "/")
+	}
+	return resolved, true
+}
+
+// routeNode is a node of the route trie, each level matching one path segment
+type routeNode struct {
+	literals map[string]*routeNode // children matching a literal segment
+	param    *routeNode            // child matching any single segment, e.g. {id}
+	routes   map[string]string     // templates ending at this node, keyed by custom verb
+	globs    map[string]string     // templates matching the remaining segments with **, keyed by custom verb
+}
 
-		// Skip if different number of path segments
-		if len(actualParts) != len(templateParts) {
+// routeTrie matches request paths against the path templates of an authz map, by HTTP method
+type routeTrie map[string]*routeNode
+
+// generatedRouteTrie is the route trie of the default authz map
+var generatedRouteTrie = newRouteTrie(generatedAuthzMap)
+
+// newRouteTrie builds the route trie of the HTTP rules of an authz map
+func newRouteTrie(authzMap map[string]AuthzRule) routeTrie {
+	trie := make(routeTrie)
+	for key := range authzMap {
+		// gRPC rules are keyed by their full method name, without method
+		template, method, ok := strings.Cut(key, "|")
+		if !ok {
 			continue
 		}
+		if trie[method] == nil {
+			trie[method] = &routeNode{}
+		}
+		trie[method].insert(template)
+	}
+	return trie
+}
 
-		// Check if the path matches the template
-		matches := true
-		for i := 0; i < len(actualParts); i++ {
-			templatePart := templateParts[i]
-			actualPart := actualParts[i]
-
-			// If template part is a parameter (enclosed in {})
-			if strings.HasPrefix(templatePart, "{") && strings.HasSuffix(templatePart, "}") {
-				// Accept any non-empty value for parameter
-				if actualPart == "" {
-					matches = false
-					break
-				}
-				// Parameter matches, continue
-				continue
+// insert adds a path template below the node
+func (n *routeNode) insert(template string) {
+	segments, verb := templateSegments(template)
+	node := n
+	for _, segment := range segments {
+		switch segment {
+		case "**":
+			if node.globs == nil {
+				node.globs = make(map[string]string)
 			}
-
-			// If template part is literal, it must match exactly
-			if templatePart != actualPart {
-				matches = false
-				break
+			node.globs[verb] = template
+			return
+		case "*":
+			if node.param == nil {
+				node.param = &routeNode{}
+			}
+			node = node.param
+		default:
+			if node.literals == nil {
+				node.literals = make(map[string]*routeNode)
 			}
+			if node.literals[segment] == nil {
+				node.literals[segment] = &routeNode{}
+			}
+			node = node.literals[segment]
 		}
+	}
+	if node.routes == nil {
+		node.routes = make(map[string]string)
+	}
+	node.routes[verb] = template
+}
 
-		// If we found a matching template, return it
-		if matches {
-			return path
+// templateSegments returns the segments of a path template, variables being replaced by their pattern,
+// * for a single segment and ** for any number of them, along with its custom verb
+// e.g. /v1/{name=projects/*}/jobs/{id}:cancel is [v1 projects * jobs *] and cancel
+func templateSegments(template string) ([]string, string) {
+	var expanded strings.Builder
+	for len(template) > 0 {
+		start := strings.IndexByte(template, '{')
+		end := strings.IndexByte(template, '}')
+		if start < 0 || end < start {
+			expanded.WriteString(template)
+			break
+		}
+		expanded.WriteString(template[:start])
+		if _, pattern, ok := strings.Cut(template[start+1:end], "="); ok {
+			expanded.WriteString(pattern)
+		} else {
+			expanded.WriteString("*")
 		}
+		template = template[end+1:]
 	}
 
-	// No template found, return original path
-	return actualPath
+	segments := strings.Split(strings.Trim(expanded.String(), "/"), "/")
+	last := segments[len(segments)-1]
+	if i := strings.LastIndexByte(last, ':'); i >= 0 {
+		segments[len(segments)-1] = last[:i]
+		return segments, last[i+1:]
+	}
+	return segments, ""
+}
+
+// match returns the template matching a request path and method
+// Literal segments win over parameters and parameters over **, /v1/users/me over /v1/users/{id}
+func (t routeTrie) match(path, method string) (string, bool) {
+	root := t[method]
+	if root == nil {
+		return "", false
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	// The last segment may end with a custom verb, e.g. /v1/jobs/123:cancel
+	last := len(segments) - 1
+	if i := strings.LastIndexByte(segments[last], ':'); i >= 0 {
+		withoutVerb := append(segments[:last:last], segments[last][:i])
+		if template, ok := root.match(withoutVerb, segments[last][i+1:]); ok {
+			return template, true
+		}
+	}
+	return root.match(segments, "")
+}
+
+// match returns the template matching the remaining segments of a path below the node
+func (n *routeNode) match(segments []string, verb string) (string, bool) {
+	if len(segments) == 0 {
+		if template, ok := n.routes[verb]; ok {
+			return template, true
+		}
+		template, ok := n.globs[verb]
+		return template, ok
+	}
+	if child := n.literals[segments[0]]; child != nil {
+		if template, ok := child.match(segments[1:], verb); ok {
+			return template, true
+		}
+	}
+	// Parameters accept any non-empty value
+	if n.param != nil && segments[0] != "" {
+		if template, ok := n.param.match(segments[1:], verb); ok {
+			return template, true
+		}
+	}
+	template, ok := n.globs[verb]
+	return template, ok
+}
+
+// pathValues returns the values of the variables of a path template in a request path it matches, in order
+// A variable spans as many segments as its pattern, e.g. projects/p1 for {name=projects/*}, and the remaining
+// ones with **
+func pathValues(template, path string) []string {
+	if _, verb := templateSegments(template); verb != "" {
+		template = strings.TrimSuffix(template, ":"+verb)
+		path = strings.TrimSuffix(path, ":"+verb)
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	var values []string
+	position := 0
+	for {
+		start := strings.IndexByte(template, '{')
+		end := strings.IndexByte(template, '}')
+		if start < 0 || end < start {
+			return values
+		}
+		// Skip the literal segments preceding the variable
+		for _, literal := range strings.Split(template[:start], "/") {
+			if literal != "" {
+				position++
+			}
+		}
+		pattern := "*"
+		if _, declared, ok := strings.Cut(template[start+1:end], "="); ok {
+			pattern = declared
+		}
+		next := min(position+strings.Count(pattern, "/")+1, len(segments))
+		if strings.Contains(pattern, "**") {
+			next = len(segments)
+		}
+		values = append(values, strings.Join(segments[min(position, next):next], "/"))
+		position = next
+		template = template[end+1:]
+	}
+}
+
+// normalizePathForAuthzWithMap converts a path with actual values to its template form
+// by matching against all known parameterized paths in the provided authz map for the given method
+// e.g., "/v1/foo/123" -> "/v1/foo/{foo_id}"
+// When several templates match, literal segments win over parameters, /v1/users/me over /v1/users/{id}
+// The route trie of the map is built on every call, see NewAuthorizer
+func normalizePathForAuthzWithMap(authzMap map[string]AuthzRule, actualPath, method string) string {
+	return normalizePathWithTrie(newRouteTrie(authzMap), actualPath, method)
 }
 
 // normalizePathForAuthz converts a path with actual values to its template form using the default authz map
 func normalizePathForAuthz(actualPath, method string) string {
-	return normalizePathForAuthzWithMap(generatedAuthzMap, actualPath, method)
+	return normalizePathWithTrie(generatedRouteTrie, actualPath, method)
+}
+
+// normalizePathWithTrie converts a path with actual values to its template form using a route trie
+func normalizePathWithTrie(trie routeTrie, actualPath, method string) string {
+	if template, ok := trie.match(actualPath, method); ok {
+		return template
+	}
+
+	// No template found, return original path
+	return actualPath
+}
+
+// lookupRule returns the rule of a path and method in an authz map, trie being the route trie of the map
+// The path is always matched against the templates, never looked up as is: a request path spelling a template,
+// e.g. /v1/users/{id}, must not select its rule
+func lookupRule(authzMap map[string]AuthzRule, trie routeTrie, path, method string) (AuthzRule, bool) {
+	template, ok := trie.match(path, method)
+	if !ok {
+		return AuthzRule{}, false
+	}
+	rule, exists := authzMap[template+"|"+method]
+	return rule, exists
+}
+
+// Authorizer checks requests against an authz map other than the default one, its route trie being built once
+// Use it rather than the WithMap functions, which build the route trie of the map on every call
+type Authorizer struct {
+	authzMap map[string]AuthzRule
+	trie     routeTrie
+}
+
+// NewAuthorizer returns the Authorizer of an authz map, which must not be modified afterwards
+func NewAuthorizer(authzMap map[string]AuthzRule) *Authorizer {
+	return &Authorizer{authzMap: authzMap, trie: newRouteTrie(authzMap)}
+}
+
+// IsAuthRequired returns whether authentication is required for a given path and method
+func (a *Authorizer) IsAuthRequired(path, method string) bool {
+	return isAuthRequired(a.authzMap, a.trie, path, method)
+}
+
+// HasPermission checks if any of the user permissions is allowed for a given path and method
+func (a *Authorizer) HasPermission(path, method string, userPermissions []string) bool {
+	return hasPermission(a.authzMap, a.trie, path, method, userPermissions)
+}
+
+// RoutePathParams returns the path variables of the rule of a path and method in order
+func (a *Authorizer) RoutePathParams(path, method string) []string {
+	rule, _ := lookupRule(a.authzMap, a.trie, path, method)
+	return rule.PathParams
+}
+
+// NormalizePath converts a path with actual values to its template form, e.g. "/v1/foo/123" -> "/v1/foo/{foo_id}"
+func (a *Authorizer) NormalizePath(actualPath, method string) string {
+	return normalizePathWithTrie(a.trie, actualPath, method)
 }
 
 // IsAuthRequiredWithMap returns whether authentication is required for a given path and method using provided authz map
+// The route trie of the map is built on every call, see NewAuthorizer
 func IsAuthRequiredWithMap(authzMap map[string]AuthzRule, path, method string) bool {
+	return isAuthRequired(authzMap, newRouteTrie(authzMap), path, method)
+}
+
+// IsAuthRequired returns whether authentication is required for a given path and method
+func IsAuthRequired(path, method string) bool {
+	return isAuthRequired(generatedAuthzMap, generatedRouteTrie, path, method)
+}
+
+// isAuthRequired returns whether authentication is required for a given path and method using an authz map and its route trie
+func isAuthRequired(authzMap map[string]AuthzRule, trie routeTrie, path, method string) bool {
 	// Health check endpoints do not require authentication
 	if path == "/v1/health" && method == "GET" {
 		return false
 	}
 
-	// First try exact match
-	key := path + "|" + method
-	rule, exists := authzMap[key]
-
-	// If not found, try with normalized path for parameterized routes
-	if !exists {
-		normalizedPath := normalizePathForAuthzWithMap(authzMap, path, method)
-		key = normalizedPath + "|" + method
-		rule, exists = authzMap[key]
-	}
-
+	rule, exists := lookupRule(authzMap, trie, path, method)
 	if !exists {
 		return true // Default to requiring auth for undefined paths
 	}
 	return !rule.NoAuthRequired
 }
 
-// IsAuthRequired returns whether authentication is required for a given path and method
-func IsAuthRequired(path, method string) bool {
-	return IsAuthRequiredWithMap(generatedAuthzMap, path, method)
-}
-
 // HasPermissionWithMap checks if any of the user permissions is allowed for a given path and method using provided authz map
+// The route trie of the map is built on every call, see NewAuthorizer
 func HasPermissionWithMap(authzMap map[string]AuthzRule, path, method string, userPermissions []string) bool {
+	return hasPermission(authzMap, newRouteTrie(authzMap), path, method, userPermissions)
+}
+
+// HasPermission checks if any of the user permissions is allowed for a given path and method
+// Templated permissions are resolved from the path variables, the rules referencing request fields outside of the
+// path being refused, as only the request resolves them
+func HasPermission(path, method string, userPermissions []string) bool {
+	return hasPermission(generatedAuthzMap, generatedRouteTrie, path, method, userPermissions)
+}
+
+// hasPermission checks if any of the user permissions is allowed for a given path and method using an authz map and its route trie
+func hasPermission(authzMap map[string]AuthzRule, trie routeTrie, path, method string, userPermissions []string) bool {
 	// Health check endpoints do not require authentication
 	if path == "/v1/health" && method == "GET" {
 		return true
 	}
 
-	// First try exact match
-	key := path + "|" + method
-	rule, exists := authzMap[key]
-
-	// If not found, try with normalized path for parameterized routes
-	if !exists {
-		normalizedPath := normalizePathForAuthzWithMap(authzMap, path, method)
-		key = normalizedPath + "|" + method
-		rule, exists = authzMap[key]
-	}
-
+	rule, exists := lookupRule(authzMap, trie, path, method)
 	if !exists {
 		return false
 	}
 
-	// If no auth is required, always allow
-	if rule.NoAuthRequired {
-		return true
+	// Templated permissions are resolved from the path, e.g. project:{project_id}:read for /v1/projects/p1
+	if rule.TemplatedPermissions {
+		permissions, ok := resolveRoutePermissions(rule, trie, path, method)
+		if !ok {
+			return false
+		}
+		rule.Permissions = permissions
 	}
+	return rule.Allows(userPermissions)
+}
 
-	// Check if user has any of the required permissions
-	requiredPermissionMap := make(map[string]bool, len(rule.Permissions))
-	for _, permission := range rule.Permissions {
-		requiredPermissionMap[strings.ToLower(permission)] = true
-	}
-	for _, userPermission := range userPermissions {
-		if requiredPermissionMap[strings.ToLower(userPermission)] {
-			return true
+// resolveRoutePermissions returns the templated permissions of the rule of a path and method resolved from the
+// values of its path variables, nested ones being referenced by field path as well, e.g. {item.id} for item_id
+// It returns false when a placeholder references a request field outside of the path, which the path cannot resolve,
+// or a value ResolvePermissions refuses
+func resolveRoutePermissions(rule AuthzRule, trie routeTrie, path, method string) ([]string, bool) {
+	template, _ := trie.match(path, method)
+	values := pathValues(template, path)
+	pathParams := make(map[string]string, len(values))
+	for i, name := range rule.PathParams {
+		if i >= len(values) {
+			break
+		}
+		pathParams[name] = values[i]
+		if field, ok := rule.PathParamFields[name]; ok {
+			pathParams[field] = values[i]
 		}
 	}
-	return false
+	return ResolvePermissions(rule, pathParams)
 }
 
-// HasPermission checks if any of the user permissions is allowed for a given path and method
-func HasPermission(path, method string, userPermissions []string) bool {
-	return HasPermissionWithMap(generatedAuthzMap, path, method, userPermissions)
+// RoutePathParamsWithMap returns the path variables of the rule of a path and method in order using provided authz map
+// e.g. ["foo_id"] for "/v1/foo/123" matching "/v1/foo/{foo_id}", nil when no rule matches
+// The route trie of the map is built on every call, see NewAuthorizer
+func RoutePathParamsWithMap(authzMap map[string]AuthzRule, path, method string) []string {
+	rule, _ := lookupRule(authzMap, newRouteTrie(authzMap), path, method)
+	return rule.PathParams
+}
+
+// RoutePathParams returns the path variables of the rule of a path and method in order
+func RoutePathParams(path, method string) []string {
+	rule, _ := lookupRule(generatedAuthzMap, generatedRouteTrie, path, method)
+	return rule.PathParams
 }
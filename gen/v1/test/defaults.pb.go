@@ -0,0 +1,196 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        (unknown)
+// source: proto/v1/defaults.proto
+
+package test
+
+import (
+	_ "google.golang.org/genproto/googleapis/api/annotations"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type TestDefaultsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FooId         string                 `protobuf:"bytes,1,opt,name=foo_id,json=fooId,proto3" json:"foo_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TestDefaultsRequest) Reset() {
+	*x = TestDefaultsRequest{}
+	mi := &file_proto_v1_defaults_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TestDefaultsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TestDefaultsRequest) ProtoMessage() {}
+
+func (x *TestDefaultsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_defaults_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TestDefaultsRequest.ProtoReflect.Descriptor instead.
+func (*TestDefaultsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_v1_defaults_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *TestDefaultsRequest) GetFooId() string {
+	if x != nil {
+		return x.FooId
+	}
+	return ""
+}
+
+type TestDefaultsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TestDefaultsResponse) Reset() {
+	*x = TestDefaultsResponse{}
+	mi := &file_proto_v1_defaults_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TestDefaultsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TestDefaultsResponse) ProtoMessage() {}
+
+func (x *TestDefaultsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_defaults_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TestDefaultsResponse.ProtoReflect.Descriptor instead.
+func (*TestDefaultsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_v1_defaults_proto_rawDescGZIP(), []int{1}
+}
+
+var File_proto_v1_defaults_proto protoreflect.FileDescriptor
+
+const file_proto_v1_defaults_proto_rawDesc = "" +
+	"\n" +
+	"\x17proto/v1/defaults.proto\x12\bproto.v1\x1a\x1cgoogle/api/annotations.proto\x1a\x15proto/v1/option.proto\",\n" +
+	"\x13TestDefaultsRequest\x12\x15\n" +
+	"\x06foo_id\x18\x01 \x01(\tR\x05fooId\"\x16\n" +
+	"\x14TestDefaultsResponse2\xa7\x03\n" +
+	"\x13TestDefaultsService\x12o\n" +
+	"\x0fTestDefaultOnly\x12\x1d.proto.v1.TestDefaultsRequest\x1a\x1e.proto.v1.TestDefaultsResponse\"\x1d\x82\xd3\xe4\x93\x02\x17\x12\x15/v1/defaults/{foo_id}\x12\x84\x01\n" +
+	"\x13TestDefaultOverride\x12\x1d.proto.v1.TestDefaultsRequest\x1a\x1e.proto.v1.TestDefaultsResponse\".\x8a\xb5\x18\n" +
+	"\n" +
+	"\bread:all\x82\xd3\xe4\x93\x02\x1a:\x01*\"\x15/v1/defaults/{foo_id}\x12\x86\x01\n" +
+	"\x19TestDefaultOverrideNoAuth\x12\x1d.proto.v1.TestDefaultsRequest\x1a\x1e.proto.v1.TestDefaultsResponse\"*\x8a\xb5\x18\x02\x10\x01\x82\xd3\xe4\x93\x02\x1e\x12\x1c/v1/defaults/{foo_id}/public\x1a\x0f\x8a\xb5\x18\v\n" +
+	"\tadmin:all2\xc0\x02\n" +
+	"\x18TestMergeDefaultsService\x12\x84\x01\n" +
+	"\x10TestMergeDefault\x12\x1d.proto.v1.TestDefaultsRequest\x1a\x1e.proto.v1.TestDefaultsResponse\"1\x8a\xb5\x18\n" +
+	"\n" +
+	"\bread:all\x82\xd3\xe4\x93\x02\x1d\x12\x1b/v1/merge-defaults/{foo_id}\x12\x89\x01\n" +
+	"\x16TestMergeDefaultNoAuth\x12\x1d.proto.v1.TestDefaultsRequest\x1a\x1e.proto.v1.TestDefaultsResponse\"0\x8a\xb5\x18\x02\x10\x01\x82\xd3\xe4\x93\x02$\x12\"/v1/merge-defaults/{foo_id}/public\x1a\x11\x8a\xb5\x18\r\n" +
+	"\tadmin:all\x18\x022\xbe\x02\n" +
+	"\x1aTestWithoutDefaultsService\x12z\n" +
+	"\x12TestWithoutDefault\x12\x1d.proto.v1.TestDefaultsRequest\x1a\x1e.proto.v1.TestDefaultsResponse\"%\x82\xd3\xe4\x93\x02\x1f\x12\x1d/v1/without-defaults/{foo_id}\x12\xa3\x01\n" +
+	"!TestWithoutDefaultWithPermissions\x12\x1d.proto.v1.TestDefaultsRequest\x1a\x1e.proto.v1.TestDefaultsResponse\"?\x8a\xb5\x18\n" +
+	"\n" +
+	"\bread:all\x82\xd3\xe4\x93\x02+\x12)/v1/without-defaults/{foo_id}/permissionsBy\x8a\xb5\x18\x0e\n" +
+	"\finternal:all\n" +
+	"\fcom.proto.v1B\rDefaultsProtoP\x01Z\av1/test\xa2\x02\x03PXX\xaa\x02\bProto.V1\xca\x02\bProto\\V1\xe2\x02\x14Proto\\V1\\GPBMetadata\xea\x02\tProto::V1b\x06proto3"
+
+var (
+	file_proto_v1_defaults_proto_rawDescOnce sync.Once
+	file_proto_v1_defaults_proto_rawDescData []byte
+)
+
+func file_proto_v1_defaults_proto_rawDescGZIP() []byte {
+	file_proto_v1_defaults_proto_rawDescOnce.Do(func() {
+		file_proto_v1_defaults_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_v1_defaults_proto_rawDesc), len(file_proto_v1_defaults_proto_rawDesc)))
+	})
+	return file_proto_v1_defaults_proto_rawDescData
+}
+
+var file_proto_v1_defaults_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_proto_v1_defaults_proto_goTypes = []any{
+	(*TestDefaultsRequest)(nil),  // 0: proto.v1.TestDefaultsRequest
+	(*TestDefaultsResponse)(nil), // 1: proto.v1.TestDefaultsResponse
+}
+var file_proto_v1_defaults_proto_depIdxs = []int32{
+	0, // 0: proto.v1.TestDefaultsService.TestDefaultOnly:input_type -> proto.v1.TestDefaultsRequest
+	0, // 1: proto.v1.TestDefaultsService.TestDefaultOverride:input_type -> proto.v1.TestDefaultsRequest
+	0, // 2: proto.v1.TestDefaultsService.TestDefaultOverrideNoAuth:input_type -> proto.v1.TestDefaultsRequest
+	0, // 3: proto.v1.TestMergeDefaultsService.TestMergeDefault:input_type -> proto.v1.TestDefaultsRequest
+	0, // 4: proto.v1.TestMergeDefaultsService.TestMergeDefaultNoAuth:input_type -> proto.v1.TestDefaultsRequest
+	0, // 5: proto.v1.TestWithoutDefaultsService.TestWithoutDefault:input_type -> proto.v1.TestDefaultsRequest
+	0, // 6: proto.v1.TestWithoutDefaultsService.TestWithoutDefaultWithPermissions:input_type -> proto.v1.TestDefaultsRequest
+	1, // 7: proto.v1.TestDefaultsService.TestDefaultOnly:output_type -> proto.v1.TestDefaultsResponse
+	1, // 8: proto.v1.TestDefaultsService.TestDefaultOverride:output_type -> proto.v1.TestDefaultsResponse
+	1, // 9: proto.v1.TestDefaultsService.TestDefaultOverrideNoAuth:output_type -> proto.v1.TestDefaultsResponse
+	1, // 10: proto.v1.TestMergeDefaultsService.TestMergeDefault:output_type -> proto.v1.TestDefaultsResponse
+	1, // 11: proto.v1.TestMergeDefaultsService.TestMergeDefaultNoAuth:output_type -> proto.v1.TestDefaultsResponse
+	1, // 12: proto.v1.TestWithoutDefaultsService.TestWithoutDefault:output_type -> proto.v1.TestDefaultsResponse
+	1, // 13: proto.v1.TestWithoutDefaultsService.TestWithoutDefaultWithPermissions:output_type -> proto.v1.TestDefaultsResponse
+	7, // [7:14] is the sub-list for method output_type
+	0, // [0:7] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_proto_v1_defaults_proto_init() }
+func file_proto_v1_defaults_proto_init() {
+	if File_proto_v1_defaults_proto != nil {
+		return
+	}
+	file_proto_v1_option_proto_init()
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_v1_defaults_proto_rawDesc), len(file_proto_v1_defaults_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   3,
+		},
+		GoTypes:           file_proto_v1_defaults_proto_goTypes,
+		DependencyIndexes: file_proto_v1_defaults_proto_depIdxs,
+		MessageInfos:      file_proto_v1_defaults_proto_msgTypes,
+	}.Build()
+	File_proto_v1_defaults_proto = out.File
+	file_proto_v1_defaults_proto_goTypes = nil
+	file_proto_v1_defaults_proto_depIdxs = nil
+}
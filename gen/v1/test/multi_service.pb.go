@@ -0,0 +1,166 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        (unknown)
+// source: proto/v1/multi_service.proto
+
+package test
+
+import (
+	_ "google.golang.org/genproto/googleapis/api/annotations"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type TestListRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TestListRequest) Reset() {
+	*x = TestListRequest{}
+	mi := &file_proto_v1_multi_service_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TestListRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TestListRequest) ProtoMessage() {}
+
+func (x *TestListRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_multi_service_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TestListRequest.ProtoReflect.Descriptor instead.
+func (*TestListRequest) Descriptor() ([]byte, []int) {
+	return file_proto_v1_multi_service_proto_rawDescGZIP(), []int{0}
+}
+
+type TestListResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TestListResponse) Reset() {
+	*x = TestListResponse{}
+	mi := &file_proto_v1_multi_service_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TestListResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TestListResponse) ProtoMessage() {}
+
+func (x *TestListResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_multi_service_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TestListResponse.ProtoReflect.Descriptor instead.
+func (*TestListResponse) Descriptor() ([]byte, []int) {
+	return file_proto_v1_multi_service_proto_rawDescGZIP(), []int{1}
+}
+
+var File_proto_v1_multi_service_proto protoreflect.FileDescriptor
+
+const file_proto_v1_multi_service_proto_rawDesc = "" +
+	"\n" +
+	"\x1cproto/v1/multi_service.proto\x12\bproto.v1\x1a\x1cgoogle/api/annotations.proto\x1a\x15proto/v1/option.proto\"\x11\n" +
+	"\x0fTestListRequest\"\x12\n" +
+	"\x10TestListResponse2t\n" +
+	"\x10TestUsersService\x12`\n" +
+	"\x04List\x12\x19.proto.v1.TestListRequest\x1a\x1a.proto.v1.TestListResponse\"!\x8a\xb5\x18\f\n" +
+	"\n" +
+	"users:list\x82\xd3\xe4\x93\x02\v\x12\t/v1/users2w\n" +
+	"\x11TestGroupsService\x12b\n" +
+	"\x04List\x12\x19.proto.v1.TestListRequest\x1a\x1a.proto.v1.TestListResponse\"#\x8a\xb5\x18\r\n" +
+	"\vgroups:list\x82\xd3\xe4\x93\x02\f\x12\n" +
+	"/v1/groupsBl\n" +
+	"\fcom.proto.v1B\x12Multi_serviceProtoP\x01Z\av1/test\xa2\x02\x03PXX\xaa\x02\bProto.V1\xca\x02\bProto\\V1\xe2\x02\x14Proto\\V1\\GPBMetadata\xea\x02\tProto::V1b\x06proto3"
+
+var (
+	file_proto_v1_multi_service_proto_rawDescOnce sync.Once
+	file_proto_v1_multi_service_proto_rawDescData []byte
+)
+
+func file_proto_v1_multi_service_proto_rawDescGZIP() []byte {
+	file_proto_v1_multi_service_proto_rawDescOnce.Do(func() {
+		file_proto_v1_multi_service_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_v1_multi_service_proto_rawDesc), len(file_proto_v1_multi_service_proto_rawDesc)))
+	})
+	return file_proto_v1_multi_service_proto_rawDescData
+}
+
+var file_proto_v1_multi_service_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_proto_v1_multi_service_proto_goTypes = []any{
+	(*TestListRequest)(nil),  // 0: proto.v1.TestListRequest
+	(*TestListResponse)(nil), // 1: proto.v1.TestListResponse
+}
+var file_proto_v1_multi_service_proto_depIdxs = []int32{
+	0, // 0: proto.v1.TestUsersService.List:input_type -> proto.v1.TestListRequest
+	0, // 1: proto.v1.TestGroupsService.List:input_type -> proto.v1.TestListRequest
+	1, // 2: proto.v1.TestUsersService.List:output_type -> proto.v1.TestListResponse
+	1, // 3: proto.v1.TestGroupsService.List:output_type -> proto.v1.TestListResponse
+	2, // [2:4] is the sub-list for method output_type
+	0, // [0:2] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_proto_v1_multi_service_proto_init() }
+func file_proto_v1_multi_service_proto_init() {
+	if File_proto_v1_multi_service_proto != nil {
+		return
+	}
+	file_proto_v1_option_proto_init()
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_v1_multi_service_proto_rawDesc), len(file_proto_v1_multi_service_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   2,
+		},
+		GoTypes:           file_proto_v1_multi_service_proto_goTypes,
+		DependencyIndexes: file_proto_v1_multi_service_proto_depIdxs,
+		MessageInfos:      file_proto_v1_multi_service_proto_msgTypes,
+	}.Build()
+	File_proto_v1_multi_service_proto = out.File
+	file_proto_v1_multi_service_proto_goTypes = nil
+	file_proto_v1_multi_service_proto_depIdxs = nil
+}
@@ -22,12 +22,134 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type Effect int32
+
+const (
+	// Same as EFFECT_ALLOW.
+	Effect_EFFECT_UNSPECIFIED Effect = 0
+	// The permission grants access.
+	Effect_EFFECT_ALLOW Effect = 1
+	// The permission rejects the caller, whatever the other permissions it holds.
+	Effect_EFFECT_DENY Effect = 2
+)
+
+// Enum value maps for Effect.
+var (
+	Effect_name = map[int32]string{
+		0: "EFFECT_UNSPECIFIED",
+		1: "EFFECT_ALLOW",
+		2: "EFFECT_DENY",
+	}
+	Effect_value = map[string]int32{
+		"EFFECT_UNSPECIFIED": 0,
+		"EFFECT_ALLOW":       1,
+		"EFFECT_DENY":        2,
+	}
+)
+
+func (x Effect) Enum() *Effect {
+	p := new(Effect)
+	*p = x
+	return p
+}
+
+func (x Effect) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Effect) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_v1_option_proto_enumTypes[0].Descriptor()
+}
+
+func (Effect) Type() protoreflect.EnumType {
+	return &file_proto_v1_option_proto_enumTypes[0]
+}
+
+func (x Effect) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Effect.Descriptor instead.
+func (Effect) EnumDescriptor() ([]byte, []int) {
+	return file_proto_v1_option_proto_rawDescGZIP(), []int{0}
+}
+
+type DefaultsStrategy int32
+
+const (
+	// Same as DEFAULTS_STRATEGY_REPLACE.
+	DefaultsStrategy_DEFAULTS_STRATEGY_UNSPECIFIED DefaultsStrategy = 0
+	// Permissions of a more specific option override the default ones.
+	DefaultsStrategy_DEFAULTS_STRATEGY_REPLACE DefaultsStrategy = 1
+	// Permissions of a more specific option are appended to the default ones.
+	DefaultsStrategy_DEFAULTS_STRATEGY_MERGE DefaultsStrategy = 2
+)
+
+// Enum value maps for DefaultsStrategy.
+var (
+	DefaultsStrategy_name = map[int32]string{
+		0: "DEFAULTS_STRATEGY_UNSPECIFIED",
+		1: "DEFAULTS_STRATEGY_REPLACE",
+		2: "DEFAULTS_STRATEGY_MERGE",
+	}
+	DefaultsStrategy_value = map[string]int32{
+		"DEFAULTS_STRATEGY_UNSPECIFIED": 0,
+		"DEFAULTS_STRATEGY_REPLACE":     1,
+		"DEFAULTS_STRATEGY_MERGE":       2,
+	}
+)
+
+func (x DefaultsStrategy) Enum() *DefaultsStrategy {
+	p := new(DefaultsStrategy)
+	*p = x
+	return p
+}
+
+func (x DefaultsStrategy) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (DefaultsStrategy) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_v1_option_proto_enumTypes[1].Descriptor()
+}
+
+func (DefaultsStrategy) Type() protoreflect.EnumType {
+	return &file_proto_v1_option_proto_enumTypes[1]
+}
+
+func (x DefaultsStrategy) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use DefaultsStrategy.Descriptor instead.
+func (DefaultsStrategy) EnumDescriptor() ([]byte, []int) {
+	return file_proto_v1_option_proto_rawDescGZIP(), []int{1}
+}
+
 type Authz struct {
-	state          protoimpl.MessageState `protogen:"open.v1"`
-	Permissions    []string               `protobuf:"bytes,1,rep,name=permissions,proto3" json:"permissions,omitempty"`
-	NoAuthRequired bool                   `protobuf:"varint,2,opt,name=no_auth_required,json=noAuthRequired,proto3" json:"no_auth_required,omitempty"`
-	unknownFields  protoimpl.UnknownFields
-	sizeCache      protoimpl.SizeCache
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Permissions []string               `protobuf:"bytes,1,rep,name=permissions,proto3" json:"permissions,omitempty"`
+	// Optional so that an explicit false, meaning authenticated callers without further permission, is kept.
+	// The Go field is then a *bool, read with GetNoAuthRequired.
+	NoAuthRequired *bool `protobuf:"varint,2,opt,name=no_auth_required,json=noAuthRequired,proto3,oneof" json:"no_auth_required,omitempty"`
+	// How methods with their own permissions combine with this option when it is used as a
+	// service or file default. Ignored on methods.
+	DefaultsStrategy DefaultsStrategy `protobuf:"varint,3,opt,name=defaults_strategy,json=defaultsStrategy,proto3,enum=proto.v1.DefaultsStrategy" json:"defaults_strategy,omitempty"`
+	// Boolean permission requirement. When set it supersedes the any-of semantics of permissions.
+	Require *Requirement `protobuf:"bytes,4,opt,name=require,proto3" json:"require,omitempty"`
+	// Permissions along with their effect. Allowed ones add to permissions, a caller holding a denied
+	// one is rejected whatever the other permissions it holds.
+	PermissionEffects []*Permission `protobuf:"bytes,5,rep,name=permission_effects,json=permissionEffects,proto3" json:"permission_effects,omitempty"`
+	// Roles the caller must hold one of. Along with permissions, both must be satisfied.
+	Roles []string `protobuf:"bytes,6,rep,name=roles,proto3" json:"roles,omitempty"`
+	// OAuth scopes, granting access like permissions. They are kept apart from the permissions, for checkers
+	// telling OAuth scopes and internal permissions apart.
+	Scopes []string `protobuf:"bytes,7,rep,name=scopes,proto3" json:"scopes,omitempty"`
+	// Options replacing this one in an environment, e.g. staging, selected when constructing the generated
+	// middleware. Environments without override use this option. Overrides cannot be nested.
+	EnvOverrides  map[string]*Authz `protobuf:"bytes,8,rep,name=env_overrides,json=envOverrides,proto3" json:"env_overrides,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *Authz) Reset() {
@@ -68,12 +190,180 @@ func (x *Authz) GetPermissions() []string {
 }
 
 func (x *Authz) GetNoAuthRequired() bool {
-	if x != nil {
-		return x.NoAuthRequired
+	if x != nil && x.NoAuthRequired != nil {
+		return *x.NoAuthRequired
 	}
 	return false
 }
 
+func (x *Authz) GetDefaultsStrategy() DefaultsStrategy {
+	if x != nil {
+		return x.DefaultsStrategy
+	}
+	return DefaultsStrategy_DEFAULTS_STRATEGY_UNSPECIFIED
+}
+
+func (x *Authz) GetRequire() *Requirement {
+	if x != nil {
+		return x.Require
+	}
+	return nil
+}
+
+func (x *Authz) GetPermissionEffects() []*Permission {
+	if x != nil {
+		return x.PermissionEffects
+	}
+	return nil
+}
+
+func (x *Authz) GetRoles() []string {
+	if x != nil {
+		return x.Roles
+	}
+	return nil
+}
+
+func (x *Authz) GetScopes() []string {
+	if x != nil {
+		return x.Scopes
+	}
+	return nil
+}
+
+func (x *Authz) GetEnvOverrides() map[string]*Authz {
+	if x != nil {
+		return x.EnvOverrides
+	}
+	return nil
+}
+
+// Permission is a permission along with its effect.
+type Permission struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Effect        Effect                 `protobuf:"varint,2,opt,name=effect,proto3,enum=proto.v1.Effect" json:"effect,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Permission) Reset() {
+	*x = Permission{}
+	mi := &file_proto_v1_option_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Permission) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Permission) ProtoMessage() {}
+
+func (x *Permission) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_option_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Permission.ProtoReflect.Descriptor instead.
+func (*Permission) Descriptor() ([]byte, []int) {
+	return file_proto_v1_option_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Permission) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Permission) GetEffect() Effect {
+	if x != nil {
+		return x.Effect
+	}
+	return Effect_EFFECT_UNSPECIFIED
+}
+
+// Requirement is satisfied when every non-empty clause is satisfied.
+type Requirement struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// At least one of these permissions.
+	AnyOf []string `protobuf:"bytes,1,rep,name=any_of,json=anyOf,proto3" json:"any_of,omitempty"`
+	// Every one of these permissions.
+	AllOf []string `protobuf:"bytes,2,rep,name=all_of,json=allOf,proto3" json:"all_of,omitempty"`
+	// Every nested requirement.
+	All []*Requirement `protobuf:"bytes,3,rep,name=all,proto3" json:"all,omitempty"`
+	// At least one nested requirement.
+	Any           []*Requirement `protobuf:"bytes,4,rep,name=any,proto3" json:"any,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Requirement) Reset() {
+	*x = Requirement{}
+	mi := &file_proto_v1_option_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Requirement) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Requirement) ProtoMessage() {}
+
+func (x *Requirement) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_option_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Requirement.ProtoReflect.Descriptor instead.
+func (*Requirement) Descriptor() ([]byte, []int) {
+	return file_proto_v1_option_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Requirement) GetAnyOf() []string {
+	if x != nil {
+		return x.AnyOf
+	}
+	return nil
+}
+
+func (x *Requirement) GetAllOf() []string {
+	if x != nil {
+		return x.AllOf
+	}
+	return nil
+}
+
+func (x *Requirement) GetAll() []*Requirement {
+	if x != nil {
+		return x.All
+	}
+	return nil
+}
+
+func (x *Requirement) GetAny() []*Requirement {
+	if x != nil {
+		return x.Any
+	}
+	return nil
+}
+
 var file_proto_v1_option_proto_extTypes = []protoimpl.ExtensionInfo{
 	{
 		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
@@ -83,6 +373,22 @@ var file_proto_v1_option_proto_extTypes = []protoimpl.ExtensionInfo{
 		Tag:           "bytes,50001,opt,name=authz",
 		Filename:      "proto/v1/option.proto",
 	},
+	{
+		ExtendedType:  (*descriptorpb.ServiceOptions)(nil),
+		ExtensionType: (*Authz)(nil),
+		Field:         50001,
+		Name:          "proto.v1.service_authz",
+		Tag:           "bytes,50001,opt,name=service_authz",
+		Filename:      "proto/v1/option.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.FileOptions)(nil),
+		ExtensionType: (*Authz)(nil),
+		Field:         50001,
+		Name:          "proto.v1.file_authz",
+		Tag:           "bytes,50001,opt,name=file_authz",
+		Filename:      "proto/v1/option.proto",
+	},
 }
 
 // Extension fields to descriptorpb.MethodOptions.
@@ -91,15 +397,57 @@ var (
 	E_Authz = &file_proto_v1_option_proto_extTypes[0]
 )
 
+// Extension fields to descriptorpb.ServiceOptions.
+var (
+	// optional proto.v1.Authz service_authz = 50001;
+	E_ServiceAuthz = &file_proto_v1_option_proto_extTypes[1]
+)
+
+// Extension fields to descriptorpb.FileOptions.
+var (
+	// optional proto.v1.Authz file_authz = 50001;
+	E_FileAuthz = &file_proto_v1_option_proto_extTypes[2]
+)
+
 var File_proto_v1_option_proto protoreflect.FileDescriptor
 
 const file_proto_v1_option_proto_rawDesc = "" +
 	"\n" +
-	"\x15proto/v1/option.proto\x12\bproto.v1\x1a google/protobuf/descriptor.proto\"S\n" +
+	"\x15proto/v1/option.proto\x12\bproto.v1\x1a google/protobuf/descriptor.proto\"\xf4\x03\n" +
 	"\x05Authz\x12 \n" +
-	"\vpermissions\x18\x01 \x03(\tR\vpermissions\x12(\n" +
-	"\x10no_auth_required\x18\x02 \x01(\bR\x0enoAuthRequired:G\n" +
-	"\x05authz\x12\x1e.google.protobuf.MethodOptions\x18ц\x03 \x01(\v2\x0f.proto.v1.AuthzR\x05authzBe\n" +
+	"\vpermissions\x18\x01 \x03(\tR\vpermissions\x12-\n" +
+	"\x10no_auth_required\x18\x02 \x01(\bH\x00R\x0enoAuthRequired\x88\x01\x01\x12G\n" +
+	"\x11defaults_strategy\x18\x03 \x01(\x0e2\x1a.proto.v1.DefaultsStrategyR\x10defaultsStrategy\x12/\n" +
+	"\arequire\x18\x04 \x01(\v2\x15.proto.v1.RequirementR\arequire\x12C\n" +
+	"\x12permission_effects\x18\x05 \x03(\v2\x14.proto.v1.PermissionR\x11permissionEffects\x12\x14\n" +
+	"\x05roles\x18\x06 \x03(\tR\x05roles\x12\x16\n" +
+	"\x06scopes\x18\a \x03(\tR\x06scopes\x12F\n" +
+	"\renv_overrides\x18\b \x03(\v2!.proto.v1.Authz.EnvOverridesEntryR\fenvOverrides\x1aP\n" +
+	"\x11EnvOverridesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12%\n" +
+	"\x05value\x18\x02 \x01(\v2\x0f.proto.v1.AuthzR\x05value:\x028\x01B\x13\n" +
+	"\x11_no_auth_required\"J\n" +
+	"\n" +
+	"Permission\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12(\n" +
+	"\x06effect\x18\x02 \x01(\x0e2\x10.proto.v1.EffectR\x06effect\"\x8d\x01\n" +
+	"\vRequirement\x12\x15\n" +
+	"\x06any_of\x18\x01 \x03(\tR\x05anyOf\x12\x15\n" +
+	"\x06all_of\x18\x02 \x03(\tR\x05allOf\x12'\n" +
+	"\x03all\x18\x03 \x03(\v2\x15.proto.v1.RequirementR\x03all\x12'\n" +
+	"\x03any\x18\x04 \x03(\v2\x15.proto.v1.RequirementR\x03any*C\n" +
+	"\x06Effect\x12\x16\n" +
+	"\x12EFFECT_UNSPECIFIED\x10\x00\x12\x10\n" +
+	"\fEFFECT_ALLOW\x10\x01\x12\x0f\n" +
+	"\vEFFECT_DENY\x10\x02*q\n" +
+	"\x10DefaultsStrategy\x12!\n" +
+	"\x1dDEFAULTS_STRATEGY_UNSPECIFIED\x10\x00\x12\x1d\n" +
+	"\x19DEFAULTS_STRATEGY_REPLACE\x10\x01\x12\x1b\n" +
+	"\x17DEFAULTS_STRATEGY_MERGE\x10\x02:G\n" +
+	"\x05authz\x12\x1e.google.protobuf.MethodOptions\x18ц\x03 \x01(\v2\x0f.proto.v1.AuthzR\x05authz:W\n" +
+	"\rservice_authz\x12\x1f.google.protobuf.ServiceOptions\x18ц\x03 \x01(\v2\x0f.proto.v1.AuthzR\fserviceAuthz:N\n" +
+	"\n" +
+	"file_authz\x12\x1c.google.protobuf.FileOptions\x18ц\x03 \x01(\v2\x0f.proto.v1.AuthzR\tfileAuthzBe\n" +
 	"\fcom.proto.v1B\vOptionProtoP\x01Z\av1/test\xa2\x02\x03PXX\xaa\x02\bProto.V1\xca\x02\bProto\\V1\xe2\x02\x14Proto\\V1\\GPBMetadata\xea\x02\tProto::V1b\x06proto3"
 
 var (
@@ -114,19 +462,39 @@ func file_proto_v1_option_proto_rawDescGZIP() []byte {
 	return file_proto_v1_option_proto_rawDescData
 }
 
-var file_proto_v1_option_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_proto_v1_option_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_proto_v1_option_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
 var file_proto_v1_option_proto_goTypes = []any{
-	(*Authz)(nil),                      // 0: proto.v1.Authz
-	(*descriptorpb.MethodOptions)(nil), // 1: google.protobuf.MethodOptions
+	(Effect)(0),                         // 0: proto.v1.Effect
+	(DefaultsStrategy)(0),               // 1: proto.v1.DefaultsStrategy
+	(*Authz)(nil),                       // 2: proto.v1.Authz
+	(*Permission)(nil),                  // 3: proto.v1.Permission
+	(*Requirement)(nil),                 // 4: proto.v1.Requirement
+	nil,                                 // 5: proto.v1.Authz.EnvOverridesEntry
+	(*descriptorpb.MethodOptions)(nil),  // 6: google.protobuf.MethodOptions
+	(*descriptorpb.ServiceOptions)(nil), // 7: google.protobuf.ServiceOptions
+	(*descriptorpb.FileOptions)(nil),    // 8: google.protobuf.FileOptions
 }
 var file_proto_v1_option_proto_depIdxs = []int32{
-	1, // 0: proto.v1.authz:extendee -> google.protobuf.MethodOptions
-	0, // 1: proto.v1.authz:type_name -> proto.v1.Authz
-	2, // [2:2] is the sub-list for method output_type
-	2, // [2:2] is the sub-list for method input_type
-	1, // [1:2] is the sub-list for extension type_name
-	0, // [0:1] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	1,  // 0: proto.v1.Authz.defaults_strategy:type_name -> proto.v1.DefaultsStrategy
+	4,  // 1: proto.v1.Authz.require:type_name -> proto.v1.Requirement
+	3,  // 2: proto.v1.Authz.permission_effects:type_name -> proto.v1.Permission
+	5,  // 3: proto.v1.Authz.env_overrides:type_name -> proto.v1.Authz.EnvOverridesEntry
+	0,  // 4: proto.v1.Permission.effect:type_name -> proto.v1.Effect
+	4,  // 5: proto.v1.Requirement.all:type_name -> proto.v1.Requirement
+	4,  // 6: proto.v1.Requirement.any:type_name -> proto.v1.Requirement
+	2,  // 7: proto.v1.Authz.EnvOverridesEntry.value:type_name -> proto.v1.Authz
+	6,  // 8: proto.v1.authz:extendee -> google.protobuf.MethodOptions
+	7,  // 9: proto.v1.service_authz:extendee -> google.protobuf.ServiceOptions
+	8,  // 10: proto.v1.file_authz:extendee -> google.protobuf.FileOptions
+	2,  // 11: proto.v1.authz:type_name -> proto.v1.Authz
+	2,  // 12: proto.v1.service_authz:type_name -> proto.v1.Authz
+	2,  // 13: proto.v1.file_authz:type_name -> proto.v1.Authz
+	14, // [14:14] is the sub-list for method output_type
+	14, // [14:14] is the sub-list for method input_type
+	11, // [11:14] is the sub-list for extension type_name
+	8,  // [8:11] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
 }
 
 func init() { file_proto_v1_option_proto_init() }
@@ -134,18 +502,20 @@ func file_proto_v1_option_proto_init() {
 	if File_proto_v1_option_proto != nil {
 		return
 	}
+	file_proto_v1_option_proto_msgTypes[0].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_v1_option_proto_rawDesc), len(file_proto_v1_option_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   1,
-			NumExtensions: 1,
+			NumEnums:      2,
+			NumMessages:   4,
+			NumExtensions: 3,
 			NumServices:   0,
 		},
 		GoTypes:           file_proto_v1_option_proto_goTypes,
 		DependencyIndexes: file_proto_v1_option_proto_depIdxs,
+		EnumInfos:         file_proto_v1_option_proto_enumTypes,
 		MessageInfos:      file_proto_v1_option_proto_msgTypes,
 		ExtensionInfos:    file_proto_v1_option_proto_extTypes,
 	}.Build()
@@ -0,0 +1,172 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        (unknown)
+// source: proto/v1/streaming.proto
+
+package test
+
+import (
+	_ "google.golang.org/genproto/googleapis/api/annotations"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type TestStreamingRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FooId         string                 `protobuf:"bytes,1,opt,name=foo_id,json=fooId,proto3" json:"foo_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TestStreamingRequest) Reset() {
+	*x = TestStreamingRequest{}
+	mi := &file_proto_v1_streaming_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TestStreamingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TestStreamingRequest) ProtoMessage() {}
+
+func (x *TestStreamingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_streaming_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TestStreamingRequest.ProtoReflect.Descriptor instead.
+func (*TestStreamingRequest) Descriptor() ([]byte, []int) {
+	return file_proto_v1_streaming_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *TestStreamingRequest) GetFooId() string {
+	if x != nil {
+		return x.FooId
+	}
+	return ""
+}
+
+type TestStreamingResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TestStreamingResponse) Reset() {
+	*x = TestStreamingResponse{}
+	mi := &file_proto_v1_streaming_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TestStreamingResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TestStreamingResponse) ProtoMessage() {}
+
+func (x *TestStreamingResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_streaming_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TestStreamingResponse.ProtoReflect.Descriptor instead.
+func (*TestStreamingResponse) Descriptor() ([]byte, []int) {
+	return file_proto_v1_streaming_proto_rawDescGZIP(), []int{1}
+}
+
+var File_proto_v1_streaming_proto protoreflect.FileDescriptor
+
+const file_proto_v1_streaming_proto_rawDesc = "" +
+	"\n" +
+	"\x18proto/v1/streaming.proto\x12\bproto.v1\x1a\x1cgoogle/api/annotations.proto\x1a\x15proto/v1/option.proto\"-\n" +
+	"\x14TestStreamingRequest\x12\x15\n" +
+	"\x06foo_id\x18\x01 \x01(\tR\x05fooId\"\x17\n" +
+	"\x15TestStreamingResponse2\xa4\x02\n" +
+	"\x14TestStreamingService\x12\x8b\x01\n" +
+	"\x11TestBidiStreaming\x12\x1e.proto.v1.TestStreamingRequest\x1a\x1f.proto.v1.TestStreamingResponse\"1\x8a\xb5\x18\f\n" +
+	"\n" +
+	"stream:all\x82\xd3\xe4\x93\x02\x1b:\x01*\"\x16/v1/streaming/{foo_id}(\x010\x01\x12~\n" +
+	"\x13TestServerStreaming\x12\x1e.proto.v1.TestStreamingRequest\x1a\x1f.proto.v1.TestStreamingResponse\"$\x8a\xb5\x18\x02\x10\x01\x82\xd3\xe4\x93\x02\x18\x12\x16/v1/streaming/{foo_id}0\x01Bh\n" +
+	"\fcom.proto.v1B\x0eStreamingProtoP\x01Z\av1/test\xa2\x02\x03PXX\xaa\x02\bProto.V1\xca\x02\bProto\\V1\xe2\x02\x14Proto\\V1\\GPBMetadata\xea\x02\tProto::V1b\x06proto3"
+
+var (
+	file_proto_v1_streaming_proto_rawDescOnce sync.Once
+	file_proto_v1_streaming_proto_rawDescData []byte
+)
+
+func file_proto_v1_streaming_proto_rawDescGZIP() []byte {
+	file_proto_v1_streaming_proto_rawDescOnce.Do(func() {
+		file_proto_v1_streaming_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_v1_streaming_proto_rawDesc), len(file_proto_v1_streaming_proto_rawDesc)))
+	})
+	return file_proto_v1_streaming_proto_rawDescData
+}
+
+var file_proto_v1_streaming_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_proto_v1_streaming_proto_goTypes = []any{
+	(*TestStreamingRequest)(nil),  // 0: proto.v1.TestStreamingRequest
+	(*TestStreamingResponse)(nil), // 1: proto.v1.TestStreamingResponse
+}
+var file_proto_v1_streaming_proto_depIdxs = []int32{
+	0, // 0: proto.v1.TestStreamingService.TestBidiStreaming:input_type -> proto.v1.TestStreamingRequest
+	0, // 1: proto.v1.TestStreamingService.TestServerStreaming:input_type -> proto.v1.TestStreamingRequest
+	1, // 2: proto.v1.TestStreamingService.TestBidiStreaming:output_type -> proto.v1.TestStreamingResponse
+	1, // 3: proto.v1.TestStreamingService.TestServerStreaming:output_type -> proto.v1.TestStreamingResponse
+	2, // [2:4] is the sub-list for method output_type
+	0, // [0:2] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_proto_v1_streaming_proto_init() }
+func file_proto_v1_streaming_proto_init() {
+	if File_proto_v1_streaming_proto != nil {
+		return
+	}
+	file_proto_v1_option_proto_init()
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_v1_streaming_proto_rawDesc), len(file_proto_v1_streaming_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_proto_v1_streaming_proto_goTypes,
+		DependencyIndexes: file_proto_v1_streaming_proto_depIdxs,
+		MessageInfos:      file_proto_v1_streaming_proto_msgTypes,
+	}.Build()
+	File_proto_v1_streaming_proto = out.File
+	file_proto_v1_streaming_proto_goTypes = nil
+	file_proto_v1_streaming_proto_depIdxs = nil
+}
@@ -26,6 +26,7 @@ const (
 type TestNoPermissionsRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Email         string                 `protobuf:"bytes,1,opt,name=email,proto3" json:"email,omitempty"`
+	FooId         string                 `protobuf:"bytes,2,opt,name=foo_id,json=fooId,proto3" json:"foo_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -67,6 +68,13 @@ func (x *TestNoPermissionsRequest) GetEmail() string {
 	return ""
 }
 
+func (x *TestNoPermissionsRequest) GetFooId() string {
+	if x != nil {
+		return x.FooId
+	}
+	return ""
+}
+
 type TestNoPermissionsResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
@@ -106,6 +114,8 @@ func (*TestNoPermissionsResponse) Descriptor() ([]byte, []int) {
 type TestWithPermissionsRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Email         string                 `protobuf:"bytes,1,opt,name=email,proto3" json:"email,omitempty"`
+	FooId         string                 `protobuf:"bytes,2,opt,name=foo_id,json=fooId,proto3" json:"foo_id,omitempty"`
+	Path          string                 `protobuf:"bytes,3,opt,name=path,proto3" json:"path,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -147,6 +157,20 @@ func (x *TestWithPermissionsRequest) GetEmail() string {
 	return ""
 }
 
+func (x *TestWithPermissionsRequest) GetFooId() string {
+	if x != nil {
+		return x.FooId
+	}
+	return ""
+}
+
+func (x *TestWithPermissionsRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
 type TestWithPermissionsResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
@@ -183,25 +207,207 @@ func (*TestWithPermissionsResponse) Descriptor() ([]byte, []int) {
 	return file_proto_v1_test_proto_rawDescGZIP(), []int{3}
 }
 
+type TestNestedFieldRequest struct {
+	state         protoimpl.MessageState       `protogen:"open.v1"`
+	Item          *TestNestedFieldRequest_Item `protobuf:"bytes,1,opt,name=item,proto3" json:"item,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TestNestedFieldRequest) Reset() {
+	*x = TestNestedFieldRequest{}
+	mi := &file_proto_v1_test_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TestNestedFieldRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TestNestedFieldRequest) ProtoMessage() {}
+
+func (x *TestNestedFieldRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_test_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TestNestedFieldRequest.ProtoReflect.Descriptor instead.
+func (*TestNestedFieldRequest) Descriptor() ([]byte, []int) {
+	return file_proto_v1_test_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *TestNestedFieldRequest) GetItem() *TestNestedFieldRequest_Item {
+	if x != nil {
+		return x.Item
+	}
+	return nil
+}
+
+type TestNestedFieldRequest_Item struct {
+	state         protoimpl.MessageState             `protogen:"open.v1"`
+	Owner         *TestNestedFieldRequest_Item_Owner `protobuf:"bytes,1,opt,name=owner,proto3" json:"owner,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TestNestedFieldRequest_Item) Reset() {
+	*x = TestNestedFieldRequest_Item{}
+	mi := &file_proto_v1_test_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TestNestedFieldRequest_Item) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TestNestedFieldRequest_Item) ProtoMessage() {}
+
+func (x *TestNestedFieldRequest_Item) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_test_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TestNestedFieldRequest_Item.ProtoReflect.Descriptor instead.
+func (*TestNestedFieldRequest_Item) Descriptor() ([]byte, []int) {
+	return file_proto_v1_test_proto_rawDescGZIP(), []int{4, 0}
+}
+
+func (x *TestNestedFieldRequest_Item) GetOwner() *TestNestedFieldRequest_Item_Owner {
+	if x != nil {
+		return x.Owner
+	}
+	return nil
+}
+
+type TestNestedFieldRequest_Item_Owner struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TestNestedFieldRequest_Item_Owner) Reset() {
+	*x = TestNestedFieldRequest_Item_Owner{}
+	mi := &file_proto_v1_test_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TestNestedFieldRequest_Item_Owner) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TestNestedFieldRequest_Item_Owner) ProtoMessage() {}
+
+func (x *TestNestedFieldRequest_Item_Owner) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_v1_test_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TestNestedFieldRequest_Item_Owner.ProtoReflect.Descriptor instead.
+func (*TestNestedFieldRequest_Item_Owner) Descriptor() ([]byte, []int) {
+	return file_proto_v1_test_proto_rawDescGZIP(), []int{4, 0, 0}
+}
+
+func (x *TestNestedFieldRequest_Item_Owner) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
 var File_proto_v1_test_proto protoreflect.FileDescriptor
 
 const file_proto_v1_test_proto_rawDesc = "" +
 	"\n" +
-	"\x13proto/v1/test.proto\x12\bproto.v1\x1a\x1bbuf/validate/validate.proto\x1a\x1cgoogle/api/annotations.proto\x1a\x15proto/v1/option.proto\"\xb8\x01\n" +
+	"\x13proto/v1/test.proto\x12\bproto.v1\x1a\x1bbuf/validate/validate.proto\x1a\x1cgoogle/api/annotations.proto\x1a\x15proto/v1/option.proto\"\xcf\x01\n" +
 	"\x18TestNoPermissionsRequest\x12\x9b\x01\n" +
 	"\x05email\x18\x01 \x01(\tB\x84\x01\xbaH\x80\x01\xba\x01}\n" +
-	"\ttest.test\x12-メールアドレスの形式が不正です\x1aAthis.matches(r'^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\\.[a-zA-Z]{2,}$')R\x05email\"\x1b\n" +
-	"\x19TestNoPermissionsResponse\"\xba\x01\n" +
+	"\ttest.test\x12-メールアドレスの形式が不正です\x1aAthis.matches(r'^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\\.[a-zA-Z]{2,}$')R\x05email\x12\x15\n" +
+	"\x06foo_id\x18\x02 \x01(\tR\x05fooId\"\x1b\n" +
+	"\x19TestNoPermissionsResponse\"\xe5\x01\n" +
 	"\x1aTestWithPermissionsRequest\x12\x9b\x01\n" +
 	"\x05email\x18\x01 \x01(\tB\x84\x01\xbaH\x80\x01\xba\x01}\n" +
-	"\ttest.test\x12-メールアドレスの形式が不正です\x1aAthis.matches(r'^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\\.[a-zA-Z]{2,}$')R\x05email\"\x1d\n" +
-	"\x1bTestWithPermissionsResponse2\xa1\x03\n" +
+	"\ttest.test\x12-メールアドレスの形式が不正です\x1aAthis.matches(r'^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\\.[a-zA-Z]{2,}$')R\x05email\x12\x15\n" +
+	"\x06foo_id\x18\x02 \x01(\tR\x05fooId\x12\x12\n" +
+	"\x04path\x18\x03 \x01(\tR\x04path\"\x1d\n" +
+	"\x1bTestWithPermissionsResponse\"\xb7\x01\n" +
+	"\x16TestNestedFieldRequest\x129\n" +
+	"\x04item\x18\x01 \x01(\v2%.proto.v1.TestNestedFieldRequest.ItemR\x04item\x1ab\n" +
+	"\x04Item\x12A\n" +
+	"\x05owner\x18\x01 \x01(\v2+.proto.v1.TestNestedFieldRequest.Item.OwnerR\x05owner\x1a\x17\n" +
+	"\x05Owner\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id2\xc8\x13\n" +
 	"\vTestService\x12\x80\x01\n" +
-	"\x11TestNoPermissions\x12\".proto.v1.TestNoPermissionsRequest\x1a#.proto.v1.TestNoPermissionsResponse\"\"\x8a\xb5\x18\x02\x10\x01\x82\xd3\xe4\x93\x02\x16:\x01*\"\x11/v1/test/{foo_id}\x12\x8f\x01\n" +
-	"\x13TestWithPermissions\x12$.proto.v1.TestWithPermissionsRequest\x1a%.proto.v1.TestWithPermissionsResponse\"+\x8a\xb5\x18\n" +
+	"\x11TestNoPermissions\x12\".proto.v1.TestNoPermissionsRequest\x1a#.proto.v1.TestNoPermissionsResponse\"\"\x8a\xb5\x18\x02\x10\x01\x82\xd3\xe4\x93\x02\x16:\x01*\"\x11/v1/test/{foo_id}\x12\x9e\x01\n" +
+	"\x13TestWithPermissions\x12$.proto.v1.TestWithPermissionsRequest\x1a%.proto.v1.TestWithPermissionsResponse\":\x8a\xb5\x18\x19\n" +
+	"\bread:allB\r\n" +
+	"\astaging\x12\x02\x10\x01\x82\xd3\xe4\x93\x02\x17:\x01*\"\x12/v1/test2/{foo_id}\x12\xae\x01\n" +
+	"\x1aTestWithAdditionalBindings\x12$.proto.v1.TestWithPermissionsRequest\x1a%.proto.v1.TestWithPermissionsResponse\"C\x8a\xb5\x18\n" +
+	"\n" +
+	"\bread:all\x82\xd3\xe4\x93\x02/Z\x19\x12\x17/v1/foos/{foo_id}/test3\x12\x12/v1/test3/{foo_id}\x12\x8e\x01\n" +
+	"\x12TestWithCustomVerb\x12$.proto.v1.TestWithPermissionsRequest\x1a%.proto.v1.TestWithPermissionsResponse\"+\x8a\xb5\x18\x02\x10\x01\x82\xd3\xe4\x93\x02\x1fB\x1d\n" +
+	"\aoptions\x12\x12/v1/test4/{foo_id}\x12\xc3\x01\n" +
+	"\x13TestWithRequirement\x12$.proto.v1.TestWithPermissionsRequest\x1a%.proto.v1.TestWithPermissionsResponse\"_\x8a\xb5\x18>\"<\n" +
+	"\bread:all\n" +
+	"\tread:test\x12\n" +
+	"write:test\"\v\x12\tadmin:all\"\f\x12\n" +
+	"owner:test\x82\xd3\xe4\x93\x02\x17:\x01*\"\x12/v1/test5/{foo_id}\x12\x9a\x01\n" +
+	"\x13TestWithFieldSyntax\x12$.proto.v1.TestWithPermissionsRequest\x1a%.proto.v1.TestWithPermissionsResponse\"6\x8a\xb5\x18\x15\n" +
+	"\bread:all\n" +
+	"\tread:test\x82\xd3\xe4\x93\x02\x14\x12\x12/v1/test6/{foo_id}\x88\x02\x01\x12\x87\x01\n" +
+	"\x10TestWithWildcard\x12$.proto.v1.TestWithPermissionsRequest\x1a%.proto.v1.TestWithPermissionsResponse\"&\x8a\xb5\x18\b\n" +
+	"\x06read:*\x82\xd3\xe4\x93\x02\x14\x12\x12/v1/test7/{foo_id}\x12\x9b\x01\n" +
+	"\x18TestWithCustomReportVerb\x12$.proto.v1.TestWithPermissionsRequest\x1a%.proto.v1.TestWithPermissionsResponse\"2\x8a\xb5\x18\n" +
+	"\n" +
+	"\bread:all\x82\xd3\xe4\x93\x02\x1eB\x1c\n" +
+	"\x06REPORT\x12\x12/v1/metrics:report\x12\xa4\x01\n" +
+	"\x18TestWithDeniedPermission\x12$.proto.v1.TestWithPermissionsRequest\x1a%.proto.v1.TestWithPermissionsResponse\";\x8a\xb5\x18\x1a\n" +
+	"\bread:all*\x0e\n" +
+	"\n" +
+	"banned:all\x10\x02\x82\xd3\xe4\x93\x02\x17:\x01*\"\x12/v1/test8/{foo_id}\x12\x8c\x01\n" +
+	"\rTestWithRoles\x12$.proto.v1.TestWithPermissionsRequest\x1a%.proto.v1.TestWithPermissionsResponse\".\x8a\xb5\x18\x102\x05admin2\asupport\x82\xd3\xe4\x93\x02\x14\x12\x12/v1/test9/{foo_id}\x12\x9f\x01\n" +
+	"\x1bTestWithRolesAndPermissions\x12$.proto.v1.TestWithPermissionsRequest\x1a%.proto.v1.TestWithPermissionsResponse\"3\x8a\xb5\x18\x12\n" +
+	"\twrite:all2\x05admin\x82\xd3\xe4\x93\x02\x17:\x01*\"\x12/v1/test9/{foo_id}\x12\x9a\x01\n" +
+	"\x10TestWithGlobPath\x12$.proto.v1.TestWithPermissionsRequest\x1a%.proto.v1.TestWithPermissionsResponse\"9\x8a\xb5\x18\n" +
+	"\n" +
+	"\bread:all\x82\xd3\xe4\x93\x02%\x12#/v1/test10/{foo_id}/{path=files/**}\x12\x91\x01\n" +
+	"\x13TestWithNestedField\x12 .proto.v1.TestNestedFieldRequest\x1a%.proto.v1.TestWithPermissionsResponse\"1\x8a\xb5\x18\v\n" +
+	"\twrite:all\x82\xd3\xe4\x93\x02\x1c2\x1a/v1/test11/{item.owner.id}\x12\x93\x01\n" +
+	"\x0eTestWithScopes\x12$.proto.v1.TestWithPermissionsRequest\x1a%.proto.v1.TestWithPermissionsResponse\"4\x8a\xb5\x18\x15\n" +
+	"\bread:all:\tread:test\x82\xd3\xe4\x93\x02\x15\x12\x13/v1/test12/{foo_id}\x12\xa9\x01\n" +
+	"\x1cTestWithTemplatedPermissions\x12$.proto.v1.TestWithPermissionsRequest\x1a%.proto.v1.TestWithPermissionsResponse\"<\x8a\xb5\x18\x1d\n" +
+	"\bread:all\n" +
+	"\x11foo:{foo_id}:read\x82\xd3\xe4\x93\x02\x15\x12\x13/v1/test13/{foo_id}\x12}\n" +
+	"\x0fTestWithNothing\x12$.proto.v1.TestWithPermissionsRequest\x1a%.proto.v1.TestWithPermissionsResponse\"\x1d\x82\xd3\xe4\x93\x02\x17:\x01*\"\x12/v1/test2/{foo_id}2\xf3\x01\n" +
+	"\x0fTestGRPCService\x12v\n" +
+	"\x17TestGRPCWithPermissions\x12$.proto.v1.TestWithPermissionsRequest\x1a%.proto.v1.TestWithPermissionsResponse\"\x0e\x8a\xb5\x18\n" +
 	"\n" +
-	"\bread:all\x82\xd3\xe4\x93\x02\x17:\x01*\"\x12/v1/test2/{foo_id}\x12}\n" +
-	"\x0fTestWithNothing\x12$.proto.v1.TestWithPermissionsRequest\x1a%.proto.v1.TestWithPermissionsResponse\"\x1d\x82\xd3\xe4\x93\x02\x17:\x01*\"\x12/v1/test2/{foo_id}Bc\n" +
+	"\bread:all\x12h\n" +
+	"\x15TestGRPCNoPermissions\x12\".proto.v1.TestNoPermissionsRequest\x1a#.proto.v1.TestNoPermissionsResponse\"\x06\x8a\xb5\x18\x02\x10\x01Bc\n" +
 	"\fcom.proto.v1B\tTestProtoP\x01Z\av1/test\xa2\x02\x03PXX\xaa\x02\bProto.V1\xca\x02\bProto\\V1\xe2\x02\x14Proto\\V1\\GPBMetadata\xea\x02\tProto::V1b\x06proto3"
 
 var (
@@ -216,25 +422,60 @@ func file_proto_v1_test_proto_rawDescGZIP() []byte {
 	return file_proto_v1_test_proto_rawDescData
 }
 
-var file_proto_v1_test_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_proto_v1_test_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
 var file_proto_v1_test_proto_goTypes = []any{
-	(*TestNoPermissionsRequest)(nil),    // 0: proto.v1.TestNoPermissionsRequest
-	(*TestNoPermissionsResponse)(nil),   // 1: proto.v1.TestNoPermissionsResponse
-	(*TestWithPermissionsRequest)(nil),  // 2: proto.v1.TestWithPermissionsRequest
-	(*TestWithPermissionsResponse)(nil), // 3: proto.v1.TestWithPermissionsResponse
+	(*TestNoPermissionsRequest)(nil),          // 0: proto.v1.TestNoPermissionsRequest
+	(*TestNoPermissionsResponse)(nil),         // 1: proto.v1.TestNoPermissionsResponse
+	(*TestWithPermissionsRequest)(nil),        // 2: proto.v1.TestWithPermissionsRequest
+	(*TestWithPermissionsResponse)(nil),       // 3: proto.v1.TestWithPermissionsResponse
+	(*TestNestedFieldRequest)(nil),            // 4: proto.v1.TestNestedFieldRequest
+	(*TestNestedFieldRequest_Item)(nil),       // 5: proto.v1.TestNestedFieldRequest.Item
+	(*TestNestedFieldRequest_Item_Owner)(nil), // 6: proto.v1.TestNestedFieldRequest.Item.Owner
 }
 var file_proto_v1_test_proto_depIdxs = []int32{
-	0, // 0: proto.v1.TestService.TestNoPermissions:input_type -> proto.v1.TestNoPermissionsRequest
-	2, // 1: proto.v1.TestService.TestWithPermissions:input_type -> proto.v1.TestWithPermissionsRequest
-	2, // 2: proto.v1.TestService.TestWithNothing:input_type -> proto.v1.TestWithPermissionsRequest
-	1, // 3: proto.v1.TestService.TestNoPermissions:output_type -> proto.v1.TestNoPermissionsResponse
-	3, // 4: proto.v1.TestService.TestWithPermissions:output_type -> proto.v1.TestWithPermissionsResponse
-	3, // 5: proto.v1.TestService.TestWithNothing:output_type -> proto.v1.TestWithPermissionsResponse
-	3, // [3:6] is the sub-list for method output_type
-	0, // [0:3] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	5,  // 0: proto.v1.TestNestedFieldRequest.item:type_name -> proto.v1.TestNestedFieldRequest.Item
+	6,  // 1: proto.v1.TestNestedFieldRequest.Item.owner:type_name -> proto.v1.TestNestedFieldRequest.Item.Owner
+	0,  // 2: proto.v1.TestService.TestNoPermissions:input_type -> proto.v1.TestNoPermissionsRequest
+	2,  // 3: proto.v1.TestService.TestWithPermissions:input_type -> proto.v1.TestWithPermissionsRequest
+	2,  // 4: proto.v1.TestService.TestWithAdditionalBindings:input_type -> proto.v1.TestWithPermissionsRequest
+	2,  // 5: proto.v1.TestService.TestWithCustomVerb:input_type -> proto.v1.TestWithPermissionsRequest
+	2,  // 6: proto.v1.TestService.TestWithRequirement:input_type -> proto.v1.TestWithPermissionsRequest
+	2,  // 7: proto.v1.TestService.TestWithFieldSyntax:input_type -> proto.v1.TestWithPermissionsRequest
+	2,  // 8: proto.v1.TestService.TestWithWildcard:input_type -> proto.v1.TestWithPermissionsRequest
+	2,  // 9: proto.v1.TestService.TestWithCustomReportVerb:input_type -> proto.v1.TestWithPermissionsRequest
+	2,  // 10: proto.v1.TestService.TestWithDeniedPermission:input_type -> proto.v1.TestWithPermissionsRequest
+	2,  // 11: proto.v1.TestService.TestWithRoles:input_type -> proto.v1.TestWithPermissionsRequest
+	2,  // 12: proto.v1.TestService.TestWithRolesAndPermissions:input_type -> proto.v1.TestWithPermissionsRequest
+	2,  // 13: proto.v1.TestService.TestWithGlobPath:input_type -> proto.v1.TestWithPermissionsRequest
+	4,  // 14: proto.v1.TestService.TestWithNestedField:input_type -> proto.v1.TestNestedFieldRequest
+	2,  // 15: proto.v1.TestService.TestWithScopes:input_type -> proto.v1.TestWithPermissionsRequest
+	2,  // 16: proto.v1.TestService.TestWithTemplatedPermissions:input_type -> proto.v1.TestWithPermissionsRequest
+	2,  // 17: proto.v1.TestService.TestWithNothing:input_type -> proto.v1.TestWithPermissionsRequest
+	2,  // 18: proto.v1.TestGRPCService.TestGRPCWithPermissions:input_type -> proto.v1.TestWithPermissionsRequest
+	0,  // 19: proto.v1.TestGRPCService.TestGRPCNoPermissions:input_type -> proto.v1.TestNoPermissionsRequest
+	1,  // 20: proto.v1.TestService.TestNoPermissions:output_type -> proto.v1.TestNoPermissionsResponse
+	3,  // 21: proto.v1.TestService.TestWithPermissions:output_type -> proto.v1.TestWithPermissionsResponse
+	3,  // 22: proto.v1.TestService.TestWithAdditionalBindings:output_type -> proto.v1.TestWithPermissionsResponse
+	3,  // 23: proto.v1.TestService.TestWithCustomVerb:output_type -> proto.v1.TestWithPermissionsResponse
+	3,  // 24: proto.v1.TestService.TestWithRequirement:output_type -> proto.v1.TestWithPermissionsResponse
+	3,  // 25: proto.v1.TestService.TestWithFieldSyntax:output_type -> proto.v1.TestWithPermissionsResponse
+	3,  // 26: proto.v1.TestService.TestWithWildcard:output_type -> proto.v1.TestWithPermissionsResponse
+	3,  // 27: proto.v1.TestService.TestWithCustomReportVerb:output_type -> proto.v1.TestWithPermissionsResponse
+	3,  // 28: proto.v1.TestService.TestWithDeniedPermission:output_type -> proto.v1.TestWithPermissionsResponse
+	3,  // 29: proto.v1.TestService.TestWithRoles:output_type -> proto.v1.TestWithPermissionsResponse
+	3,  // 30: proto.v1.TestService.TestWithRolesAndPermissions:output_type -> proto.v1.TestWithPermissionsResponse
+	3,  // 31: proto.v1.TestService.TestWithGlobPath:output_type -> proto.v1.TestWithPermissionsResponse
+	3,  // 32: proto.v1.TestService.TestWithNestedField:output_type -> proto.v1.TestWithPermissionsResponse
+	3,  // 33: proto.v1.TestService.TestWithScopes:output_type -> proto.v1.TestWithPermissionsResponse
+	3,  // 34: proto.v1.TestService.TestWithTemplatedPermissions:output_type -> proto.v1.TestWithPermissionsResponse
+	3,  // 35: proto.v1.TestService.TestWithNothing:output_type -> proto.v1.TestWithPermissionsResponse
+	3,  // 36: proto.v1.TestGRPCService.TestGRPCWithPermissions:output_type -> proto.v1.TestWithPermissionsResponse
+	1,  // 37: proto.v1.TestGRPCService.TestGRPCNoPermissions:output_type -> proto.v1.TestNoPermissionsResponse
+	20, // [20:38] is the sub-list for method output_type
+	2,  // [2:20] is the sub-list for method input_type
+	2,  // [2:2] is the sub-list for extension type_name
+	2,  // [2:2] is the sub-list for extension extendee
+	0,  // [0:2] is the sub-list for field type_name
 }
 
 func init() { file_proto_v1_test_proto_init() }
@@ -249,9 +490,9 @@ func file_proto_v1_test_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_v1_test_proto_rawDesc), len(file_proto_v1_test_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   4,
+			NumMessages:   7,
 			NumExtensions: 0,
-			NumServices:   1,
+			NumServices:   2,
 		},
 		GoTypes:           file_proto_v1_test_proto_goTypes,
 		DependencyIndexes: file_proto_v1_test_proto_depIdxs,
@@ -0,0 +1,327 @@
+// Code generated by protoc-gen-go-authz. DO NOT EDIT.
+
+package test
+
+import (
+	authzmap "github.com/aymenworks/public-medium-protocgen/gen/authzmap"
+)
+
+// TestGRPCServiceAuthzRules are the authorization rules of the proto.v1.TestGRPCService service
+var TestGRPCServiceAuthzRules = []AuthzRule{
+	{
+		AuthzRule: authzmap.AuthzRule{
+			Permissions:    []string{},
+			NoAuthRequired: true,
+			Level:          "method",
+			StreamingType:  "none",
+			Transport:      "grpc",
+			GRPCMethod:     "/proto.v1.TestGRPCService/TestGRPCNoPermissions",
+			ProtoPackage:   "proto.v1",
+			ServiceName:    "TestGRPCService",
+			MethodName:     "TestGRPCNoPermissions",
+		},
+	},
+	{
+		AuthzRule: authzmap.AuthzRule{
+			Permissions:    []string{"read:all"},
+			NoAuthRequired: false,
+			Level:          "method",
+			StreamingType:  "none",
+			Transport:      "grpc",
+			GRPCMethod:     "/proto.v1.TestGRPCService/TestGRPCWithPermissions",
+			ProtoPackage:   "proto.v1",
+			ServiceName:    "TestGRPCService",
+			MethodName:     "TestGRPCWithPermissions",
+		},
+	},
+}
+
+// TestServiceAuthzRules are the authorization rules of the proto.v1.TestService service
+var TestServiceAuthzRules = []AuthzRule{
+	{
+		HTTPPath:   "/v1/foos/{foo_id}/test3",
+		HTTPMethod: "GET",
+		AuthzRule: authzmap.AuthzRule{
+			Permissions:    []string{"read:all"},
+			NoAuthRequired: false,
+			Level:          "method",
+			StreamingType:  "none",
+			Transport:      "http",
+			GRPCMethod:     "/proto.v1.TestService/TestWithAdditionalBindings",
+			PathParams:     []string{"foo_id"},
+			ProtoPackage:   "proto.v1",
+			ServiceName:    "TestService",
+			MethodName:     "TestWithAdditionalBindings",
+		},
+	},
+	{
+		HTTPPath:   "/v1/metrics:report",
+		HTTPMethod: "REPORT",
+		AuthzRule: authzmap.AuthzRule{
+			Permissions:    []string{"read:all"},
+			NoAuthRequired: false,
+			Level:          "method",
+			StreamingType:  "none",
+			Transport:      "http",
+			GRPCMethod:     "/proto.v1.TestService/TestWithCustomReportVerb",
+			ProtoPackage:   "proto.v1",
+			ServiceName:    "TestService",
+			MethodName:     "TestWithCustomReportVerb",
+		},
+	},
+	{
+		HTTPPath:   "/v1/test/{foo_id}",
+		HTTPMethod: "POST",
+		AuthzRule: authzmap.AuthzRule{
+			Permissions:    []string{},
+			NoAuthRequired: true,
+			Level:          "method",
+			StreamingType:  "none",
+			Transport:      "http",
+			GRPCMethod:     "/proto.v1.TestService/TestNoPermissions",
+			PathParams:     []string{"foo_id"},
+			Body:           "*",
+			ProtoPackage:   "proto.v1",
+			ServiceName:    "TestService",
+			MethodName:     "TestNoPermissions",
+		},
+	},
+	{
+		HTTPPath:   "/v1/test10/{foo_id}/{path=files/**}",
+		HTTPMethod: "GET",
+		AuthzRule: authzmap.AuthzRule{
+			Permissions:       []string{"read:all"},
+			NoAuthRequired:    false,
+			Level:             "method",
+			StreamingType:     "none",
+			Transport:         "http",
+			GRPCMethod:        "/proto.v1.TestService/TestWithGlobPath",
+			PathParams:        []string{"foo_id", "path"},
+			PathParamPatterns: map[string]string{"path": "files/**"},
+			ProtoPackage:      "proto.v1",
+			ServiceName:       "TestService",
+			MethodName:        "TestWithGlobPath",
+		},
+	},
+	{
+		HTTPPath:   "/v1/test11/{item.owner.id}",
+		HTTPMethod: "PATCH",
+		AuthzRule: authzmap.AuthzRule{
+			Permissions:     []string{"write:all"},
+			NoAuthRequired:  false,
+			Level:           "method",
+			StreamingType:   "none",
+			Transport:       "http",
+			GRPCMethod:      "/proto.v1.TestService/TestWithNestedField",
+			PathParams:      []string{"item_owner_id"},
+			PathParamFields: map[string]string{"item_owner_id": "item.owner.id"},
+			ProtoPackage:    "proto.v1",
+			ServiceName:     "TestService",
+			MethodName:      "TestWithNestedField",
+		},
+	},
+	{
+		HTTPPath:   "/v1/test12/{foo_id}",
+		HTTPMethod: "GET",
+		AuthzRule: authzmap.AuthzRule{
+			Permissions:    []string{"read:all"},
+			AllowedScopes:  []string{"read:test"},
+			NoAuthRequired: false,
+			Level:          "method",
+			StreamingType:  "none",
+			Transport:      "http",
+			GRPCMethod:     "/proto.v1.TestService/TestWithScopes",
+			PathParams:     []string{"foo_id"},
+			ProtoPackage:   "proto.v1",
+			ServiceName:    "TestService",
+			MethodName:     "TestWithScopes",
+		},
+	},
+	{
+		HTTPPath:   "/v1/test13/{foo_id}",
+		HTTPMethod: "GET",
+		AuthzRule: authzmap.AuthzRule{
+			Permissions:          []string{"read:all", "foo:{foo_id}:read"},
+			NoAuthRequired:       false,
+			TemplatedPermissions: true,
+			PermissionParams:     []string{"foo_id"},
+			Level:                "method",
+			StreamingType:        "none",
+			Transport:            "http",
+			GRPCMethod:           "/proto.v1.TestService/TestWithTemplatedPermissions",
+			PathParams:           []string{"foo_id"},
+			ProtoPackage:         "proto.v1",
+			ServiceName:          "TestService",
+			MethodName:           "TestWithTemplatedPermissions",
+		},
+	},
+	{
+		HTTPPath:   "/v1/test2/{foo_id}",
+		HTTPMethod: "POST",
+		AuthzRule: authzmap.AuthzRule{
+			Permissions:    []string{"read:all"},
+			NoAuthRequired: false,
+			EnvOverrides: map[string]authzmap.AuthzRule{
+				"staging": {
+					Permissions:    []string{},
+					NoAuthRequired: true,
+					Level:          "method",
+					StreamingType:  "none",
+					Transport:      "http",
+					GRPCMethod:     "/proto.v1.TestService/TestWithPermissions",
+					PathParams:     []string{"foo_id"},
+					Body:           "*",
+					ProtoPackage:   "proto.v1",
+					ServiceName:    "TestService",
+					MethodName:     "TestWithPermissions",
+				},
+			},
+			Level:         "method",
+			StreamingType: "none",
+			Transport:     "http",
+			GRPCMethod:    "/proto.v1.TestService/TestWithPermissions",
+			PathParams:    []string{"foo_id"},
+			Body:          "*",
+			ProtoPackage:  "proto.v1",
+			ServiceName:   "TestService",
+			MethodName:    "TestWithPermissions",
+		},
+	},
+	{
+		HTTPPath:   "/v1/test3/{foo_id}",
+		HTTPMethod: "GET",
+		AuthzRule: authzmap.AuthzRule{
+			Permissions:    []string{"read:all"},
+			NoAuthRequired: false,
+			Level:          "method",
+			StreamingType:  "none",
+			Transport:      "http",
+			GRPCMethod:     "/proto.v1.TestService/TestWithAdditionalBindings",
+			PathParams:     []string{"foo_id"},
+			ProtoPackage:   "proto.v1",
+			ServiceName:    "TestService",
+			MethodName:     "TestWithAdditionalBindings",
+		},
+	},
+	{
+		HTTPPath:   "/v1/test4/{foo_id}",
+		HTTPMethod: "OPTIONS",
+		AuthzRule: authzmap.AuthzRule{
+			Permissions:    []string{},
+			NoAuthRequired: true,
+			Level:          "method",
+			StreamingType:  "none",
+			Transport:      "http",
+			GRPCMethod:     "/proto.v1.TestService/TestWithCustomVerb",
+			PathParams:     []string{"foo_id"},
+			ProtoPackage:   "proto.v1",
+			ServiceName:    "TestService",
+			MethodName:     "TestWithCustomVerb",
+		},
+	},
+	{
+		HTTPPath:   "/v1/test5/{foo_id}",
+		HTTPMethod: "POST",
+		AuthzRule: authzmap.AuthzRule{
+			Permissions:    []string{"read:all", "read:test", "write:test", "admin:all", "owner:test"},
+			Require:        &authzmap.PermissionExpr{AnyOf: []string{"read:all", "read:test"}, AllOf: []string{"write:test"}, Any: []authzmap.PermissionExpr{{AllOf: []string{"admin:all"}}, {AllOf: []string{"owner:test"}}}},
+			NoAuthRequired: false,
+			Level:          "method",
+			StreamingType:  "none",
+			Transport:      "http",
+			GRPCMethod:     "/proto.v1.TestService/TestWithRequirement",
+			PathParams:     []string{"foo_id"},
+			Body:           "*",
+			ProtoPackage:   "proto.v1",
+			ServiceName:    "TestService",
+			MethodName:     "TestWithRequirement",
+		},
+	},
+	{
+		HTTPPath:   "/v1/test6/{foo_id}",
+		HTTPMethod: "GET",
+		AuthzRule: authzmap.AuthzRule{
+			Permissions:    []string{"read:all", "read:test"},
+			NoAuthRequired: false,
+			Deprecated:     true,
+			Level:          "method",
+			StreamingType:  "none",
+			Transport:      "http",
+			GRPCMethod:     "/proto.v1.TestService/TestWithFieldSyntax",
+			PathParams:     []string{"foo_id"},
+			ProtoPackage:   "proto.v1",
+			ServiceName:    "TestService",
+			MethodName:     "TestWithFieldSyntax",
+		},
+	},
+	{
+		HTTPPath:   "/v1/test7/{foo_id}",
+		HTTPMethod: "GET",
+		AuthzRule: authzmap.AuthzRule{
+			Permissions:    []string{"read:all", "read:test"},
+			RawPermissions: []string{"read:*"},
+			NoAuthRequired: false,
+			Level:          "method",
+			StreamingType:  "none",
+			Transport:      "http",
+			GRPCMethod:     "/proto.v1.TestService/TestWithWildcard",
+			PathParams:     []string{"foo_id"},
+			ProtoPackage:   "proto.v1",
+			ServiceName:    "TestService",
+			MethodName:     "TestWithWildcard",
+		},
+	},
+	{
+		HTTPPath:   "/v1/test8/{foo_id}",
+		HTTPMethod: "POST",
+		AuthzRule: authzmap.AuthzRule{
+			Permissions:       []string{"read:all"},
+			DeniedPermissions: []string{"banned:all"},
+			NoAuthRequired:    false,
+			Level:             "method",
+			StreamingType:     "none",
+			Transport:         "http",
+			GRPCMethod:        "/proto.v1.TestService/TestWithDeniedPermission",
+			PathParams:        []string{"foo_id"},
+			Body:              "*",
+			ProtoPackage:      "proto.v1",
+			ServiceName:       "TestService",
+			MethodName:        "TestWithDeniedPermission",
+		},
+	},
+	{
+		HTTPPath:   "/v1/test9/{foo_id}",
+		HTTPMethod: "GET",
+		AuthzRule: authzmap.AuthzRule{
+			Permissions:    []string{},
+			Roles:          []string{"admin", "support"},
+			NoAuthRequired: false,
+			Level:          "method",
+			StreamingType:  "none",
+			Transport:      "http",
+			GRPCMethod:     "/proto.v1.TestService/TestWithRoles",
+			PathParams:     []string{"foo_id"},
+			ProtoPackage:   "proto.v1",
+			ServiceName:    "TestService",
+			MethodName:     "TestWithRoles",
+		},
+	},
+	{
+		HTTPPath:   "/v1/test9/{foo_id}",
+		HTTPMethod: "POST",
+		AuthzRule: authzmap.AuthzRule{
+			Permissions:    []string{"write:all"},
+			Roles:          []string{"admin"},
+			NoAuthRequired: false,
+			Level:          "method",
+			StreamingType:  "none",
+			Transport:      "http",
+			GRPCMethod:     "/proto.v1.TestService/TestWithRolesAndPermissions",
+			PathParams:     []string{"foo_id"},
+			Body:           "*",
+			ProtoPackage:   "proto.v1",
+			ServiceName:    "TestService",
+			MethodName:     "TestWithRolesAndPermissions",
+		},
+	},
+}
@@ -0,0 +1,622 @@
+package authzgen
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+// generateAuthzMapFile generates the Go file containing the authorization map.
+func generateAuthzMapFile(plugin *protogen.Plugin, out outputPackage, rules []Rule) {
+	// Generate in a separate package to avoid circular imports
+	gen := out.newGoFile(plugin, "generated_authz_map.go")
+
+	gen.P("import (")
+	gen.P("	\"context\"")
+	gen.P("	\"strings\"")
+	gen.P(")")
+	gen.P()
+
+	// Generate the AuthzRule struct
+	gen.P("// AuthzRule represents authorization rules for a method")
+	gen.P("type AuthzRule struct {")
+	gen.P("	Permissions       []string")
+	gen.P("	RawPermissions    []string        // permissions as declared, set when wildcards were expanded")
+	gen.P("	DeniedPermissions []string        // permissions rejecting the caller, whatever the other permissions it holds")
+	gen.P("	Roles             []string        // roles the caller must hold one of, on top of satisfying the permissions")
+	gen.P("	AllowedScopes     []string        // OAuth scopes granting access like Permissions, kept apart from them")
+	gen.P("	Require           *PermissionExpr // boolean requirement, when set it supersedes the any-of semantics of Permissions and AllowedScopes")
+	gen.P("	NoAuthRequired    bool")
+	gen.P("	Deprecated        bool // whether the method is marked with option deprecated = true")
+	gen.P("	// EnvOverrides is the rule in effect per environment, e.g. staging, for the environments overriding it")
+	gen.P("	EnvOverrides map[string]AuthzRule")
+	gen.P("	// TemplatedPermissions is set when Permissions reference path variables, e.g. project:{project_id}:read")
+	gen.P("	TemplatedPermissions bool")
+	gen.P("	// PermissionParams are the path variables or request fields referenced by the placeholders of Permissions in order")
+	gen.P("	PermissionParams []string")
+	gen.P("	// Level is the proto level the rule was declared at: file, service or method")
+	gen.P("	Level string")
+	gen.P("	// StreamingType is the streaming kind of the method: none, client, server or bidi")
+	gen.P("	StreamingType string")
+	gen.P("	// Transport is http, or grpc for rules of methods without HTTP annotation keyed by their gRPC full method name")
+	gen.P("	Transport string")
+	gen.P("	// GRPCMethod is the gRPC full method name, e.g. /package.Service/Method")
+	gen.P("	GRPCMethod string")
+	gen.P("	// PathParams are the flat names of the variables of the HTTP path template in order, e.g. item_id for /v1/{item.id}")
+	gen.P("	PathParams []string")
+	gen.P("	// PathParamPatterns is the pattern of the path variables declaring one, e.g. ** for {name=**}")
+	gen.P("	PathParamPatterns map[string]string")
+	gen.P("	// PathParamFields is the request field path of the nested path variables, e.g. item.id for item_id")
+	gen.P("	PathParamFields map[string]string")
+	gen.P("	// Body is the request field mapped to the HTTP body, * for the whole request")
+	gen.P("	Body string")
+	gen.P("	// ResponseBody is the response field mapped to the HTTP body, empty for the whole response")
+	gen.P("	ResponseBody string")
+	gen.P("	// ProtoPackage, ServiceName and MethodName identify the method the rule was extracted from")
+	gen.P("	ProtoPackage string")
+	gen.P("	ServiceName  string")
+	gen.P("	MethodName   string")
+	gen.P("}")
+	gen.P()
+
+	// Generate the PermissionChecker interface
+	gen.P("// PermissionChecker resolves the permissions of the caller of a request")
+	gen.P("type PermissionChecker interface {")
+	gen.P("	// Permissions returns the permissions granted to the caller, an error means the caller is not authenticated")
+	gen.P("	Permissions(ctx context.Context) ([]string, error)")
+	gen.P("}")
+	gen.P()
+	gen.P("// RoleChecker is optionally implemented by a PermissionChecker to resolve the roles of the caller as well")
+	gen.P("// Callers of a PermissionChecker not implementing it hold no role")
+	gen.P("type RoleChecker interface {")
+	gen.P("	// Roles returns the roles granted to the caller, an error means the caller is not authenticated")
+	gen.P("	Roles(ctx context.Context) ([]string, error)")
+	gen.P("}")
+	gen.P()
+	gen.P("// ScopeChecker is optionally implemented by a PermissionChecker to resolve the OAuth scopes of the caller apart")
+	gen.P("// from its permissions. The AllowedScopes of the rules are checked against the permissions of callers of a")
+	gen.P("// PermissionChecker not implementing it")
+	gen.P("type ScopeChecker interface {")
+	gen.P("	// Scopes returns the OAuth scopes granted to the caller, an error means the caller is not authenticated")
+	gen.P("	Scopes(ctx context.Context) ([]string, error)")
+	gen.P("}")
+	gen.P()
+
+	// Generate the Checker interface
+	gen.P("// Checker tells whether the caller holds a permission or a role")
+	gen.P("type Checker interface {")
+	gen.P("	HasPermission(permission string) bool")
+	gen.P("	HasRole(role string) bool")
+	gen.P("}")
+	gen.P()
+	gen.P("// ScopedChecker is a Checker telling the OAuth scopes of the caller apart from its permissions")
+	gen.P("// The AllowedScopes of the rules are checked with HasPermission on the Checkers not implementing it")
+	gen.P("type ScopedChecker interface {")
+	gen.P("	Checker")
+	gen.P("	HasScope(scope string) bool")
+	gen.P("}")
+	gen.P()
+	gen.P("// grants is a ScopedChecker over the permissions, roles and scopes of a caller, compared case-insensitively")
+	gen.P("type grants struct {")
+	gen.P("	permissions map[string]bool")
+	gen.P("	roles       map[string]bool")
+	gen.P("	scopes      map[string]bool // nil when the scopes are not told apart from the permissions")
+	gen.P("}")
+	gen.P()
+	gen.P("// newGrants returns the Checker of a caller holding permissions and roles")
+	gen.P("func newGrants(permissions, roles []string) grants {")
+	gen.P("	g := grants{permissions: make(map[string]bool, len(permissions)), roles: make(map[string]bool, len(roles))}")
+	gen.P("	for _, permission := range permissions {")
+	gen.P("		g.permissions[strings.ToLower(permission)] = true")
+	gen.P("	}")
+	gen.P("	for _, role := range roles {")
+	gen.P("		g.roles[strings.ToLower(role)] = true")
+	gen.P("	}")
+	gen.P("	return g")
+	gen.P("}")
+	gen.P()
+	gen.P("func (g grants) HasPermission(permission string) bool {")
+	gen.P("	return g.permissions[strings.ToLower(permission)]")
+	gen.P("}")
+	gen.P()
+	gen.P("func (g grants) HasRole(role string) bool {")
+	gen.P("	return g.roles[strings.ToLower(role)]")
+	gen.P("}")
+	gen.P()
+	gen.P("func (g grants) HasScope(scope string) bool {")
+	gen.P("	if g.scopes == nil {")
+	gen.P("		return g.HasPermission(scope)")
+	gen.P("	}")
+	gen.P("	return g.scopes[strings.ToLower(scope)]")
+	gen.P("}")
+	gen.P()
+	gen.P("// callerGrants resolves the permissions of the caller, its roles when checker implements RoleChecker and its")
+	gen.P("// scopes when checker implements ScopeChecker")
+	gen.P("func callerGrants(ctx context.Context, checker PermissionChecker) (grants, error) {")
+	gen.P("	permissions, err := checker.Permissions(ctx)")
+	gen.P("	if err != nil {")
+	gen.P("		return grants{}, err")
+	gen.P("	}")
+	gen.P("	var roles []string")
+	gen.P("	if roleChecker, ok := checker.(RoleChecker); ok {")
+	gen.P("		if roles, err = roleChecker.Roles(ctx); err != nil {")
+	gen.P("			return grants{}, err")
+	gen.P("		}")
+	gen.P("	}")
+	gen.P("	g := newGrants(permissions, roles)")
+	gen.P("	if scopeChecker, ok := checker.(ScopeChecker); ok {")
+	gen.P("		scopes, err := scopeChecker.Scopes(ctx)")
+	gen.P("		if err != nil {")
+	gen.P("			return grants{}, err")
+	gen.P("		}")
+	gen.P("		g.scopes = make(map[string]bool, len(scopes))")
+	gen.P("		for _, scope := range scopes {")
+	gen.P("			g.scopes[strings.ToLower(scope)] = true")
+	gen.P("		}")
+	gen.P("	}")
+	gen.P("	return g, nil")
+	gen.P("}")
+	gen.P()
+
+	// Generate the PermissionExpr struct
+	gen.P("// PermissionExpr is a boolean combination of permissions")
+	gen.P("// It is satisfied when every non-empty clause is satisfied")
+	gen.P("type PermissionExpr struct {")
+	gen.P("	AnyOf []string         // at least one of these permissions")
+	gen.P("	AllOf []string         // every one of these permissions")
+	gen.P("	All   []PermissionExpr // every nested expression")
+	gen.P("	Any   []PermissionExpr // at least one nested expression")
+	gen.P("}")
+	gen.P()
+	gen.P("// Evaluate reports whether the expression is satisfied given a function telling whether a permission is granted")
+	gen.P("func (e PermissionExpr) Evaluate(hasPermission func(permission string) bool) bool {")
+	gen.P("	if len(e.AnyOf) > 0 {")
+	gen.P("		granted := false")
+	gen.P("		for _, permission := range e.AnyOf {")
+	gen.P("			if hasPermission(permission) {")
+	gen.P("				granted = true")
+	gen.P("				break")
+	gen.P("			}")
+	gen.P("		}")
+	gen.P("		if !granted {")
+	gen.P("			return false")
+	gen.P("		}")
+	gen.P("	}")
+	gen.P("	for _, permission := range e.AllOf {")
+	gen.P("		if !hasPermission(permission) {")
+	gen.P("			return false")
+	gen.P("		}")
+	gen.P("	}")
+	gen.P("	for _, nested := range e.All {")
+	gen.P("		if !nested.Evaluate(hasPermission) {")
+	gen.P("			return false")
+	gen.P("		}")
+	gen.P("	}")
+	gen.P("	if len(e.Any) > 0 {")
+	gen.P("		for _, nested := range e.Any {")
+	gen.P("			if nested.Evaluate(hasPermission) {")
+	gen.P("				return true")
+	gen.P("			}")
+	gen.P("		}")
+	gen.P("		return false")
+	gen.P("	}")
+	gen.P("	return true")
+	gen.P("}")
+	gen.P()
+
+	// Generate the AuthzRule checks
+	gen.P("// ForEnv returns the rule in effect in env, the rule itself when env does not override it")
+	gen.P("func (rule AuthzRule) ForEnv(env string) AuthzRule {")
+	gen.P("	if override, ok := rule.EnvOverrides[env]; ok {")
+	gen.P("		return override")
+	gen.P("	}")
+	gen.P("	return rule")
+	gen.P("}")
+	gen.P()
+	gen.P("// Allows reports whether a caller with the given permissions and no role satisfies the rule")
+	gen.P("func (rule AuthzRule) Allows(userPermissions []string) bool {")
+	gen.P("	return rule.Check(newGrants(userPermissions, nil))")
+	gen.P("}")
+	gen.P()
+	gen.P("// AllowsWithRoles reports whether a caller with the given permissions and roles satisfies the rule")
+	gen.P("func (rule AuthzRule) AllowsWithRoles(userPermissions, userRoles []string) bool {")
+	gen.P("	return rule.Check(newGrants(userPermissions, userRoles))")
+	gen.P("}")
+	gen.P()
+	gen.P("// Check reports whether the caller described by checker satisfies the rule")
+	gen.P("// A rule declaring both roles and permissions requires one of the roles and the permissions")
+	gen.P("// The allowed scopes grant access like the permissions, checked with HasScope when checker is a ScopedChecker")
+	gen.P("func (rule AuthzRule) Check(checker Checker) bool {")
+	gen.P("	// If no auth is required, always allow")
+	gen.P("	if rule.NoAuthRequired {")
+	gen.P("		return true")
+	gen.P("	}")
+	gen.P("	")
+	gen.P("	// Explicitly reject callers holding a denied permission")
+	gen.P("	for _, permission := range rule.DeniedPermissions {")
+	gen.P("		if checker.HasPermission(permission) {")
+	gen.P("			return false")
+	gen.P("		}")
+	gen.P("	}")
+	gen.P("	")
+	gen.P("	// Check if user has any of the required roles, which is enough when no permission nor scope is declared")
+	gen.P("	if len(rule.Roles) > 0 {")
+	gen.P("		hasRole := false")
+	gen.P("		for _, role := range rule.Roles {")
+	gen.P("			if checker.HasRole(role) {")
+	gen.P("				hasRole = true")
+	gen.P("				break")
+	gen.P("			}")
+	gen.P("		}")
+	gen.P("		if !hasRole {")
+	gen.P("			return false")
+	gen.P("		}")
+	gen.P("		if rule.Require == nil && len(rule.Permissions) == 0 && len(rule.AllowedScopes) == 0 {")
+	gen.P("			return true")
+	gen.P("		}")
+	gen.P("	}")
+	gen.P("	")
+	gen.P("	// Evaluate the boolean requirement when declared")
+	gen.P("	if rule.Require != nil {")
+	gen.P("		return rule.Require.Evaluate(checker.HasPermission)")
+	gen.P("	}")
+	gen.P("	")
+	gen.P("	// Check if user has any of the required permissions")
+	gen.P("	for _, permission := range rule.Permissions {")
+	gen.P("		if checker.HasPermission(permission) {")
+	gen.P("			return true")
+	gen.P("		}")
+	gen.P("	}")
+	gen.P("	")
+	gen.P("	// Or any of the allowed OAuth scopes")
+	gen.P("	scopedChecker, scoped := checker.(ScopedChecker)")
+	gen.P("	for _, scope := range rule.AllowedScopes {")
+	gen.P("		if (scoped && scopedChecker.HasScope(scope)) || (!scoped && checker.HasPermission(scope)) {")
+	gen.P("			return true")
+	gen.P("		}")
+	gen.P("	}")
+	gen.P("	return false")
+	gen.P("}")
+	gen.P()
+
+	// Generate the authorization map
+	gen.P("// generatedAuthzMap contains authorization rules extracted from proto definitions")
+	gen.P("// This map is automatically generated during go tool buf generate")
+	gen.P("var generatedAuthzMap = map[string]AuthzRule{")
+
+	for _, rule := range rules {
+		gen.P("	" + strconv.Quote(rule.Key()) + ": {")
+		writeAuthzRuleFields(gen, rule, "		", out.importPath)
+		gen.P("	},")
+	}
+
+	gen.P("}")
+	gen.P()
+
+	gen.P("// ResolvePermissions returns the permissions of the rule, the placeholders of templated permissions such as")
+	gen.P("// project:{project_id}:read being replaced by the values of params, keyed by path variable or request field")
+	gen.P("// It returns false when a placeholder is left unresolved, or its value is empty or contains :, *, {, } or /,")
+	gen.P("// which would let a request name the permission of another resource or a wildcard one")
+	gen.P("// The permissions of rules without template are returned as is")
+	gen.P("func ResolvePermissions(rule AuthzRule, params map[string]string) ([]string, bool) {")
+	gen.P("	if !rule.TemplatedPermissions {")
+	gen.P("		return rule.Permissions, true")
+	gen.P("	}")
+	gen.P("	")
+	gen.P("	replacements := make([]string, 0, 2*len(rule.PermissionParams))")
+	gen.P("	for _, name := range rule.PermissionParams {")
+	gen.P("		value, ok := params[name]")
+	gen.P("		if !ok || value == \"\" || strings.ContainsAny(value, \":*{}/\") {")
+	gen.P("			return nil, false")
+	gen.P("		}")
+	gen.P("		replacements = append(replacements, \"{\"+name+\"}\", value)")
+	gen.P("	}")
+	gen.P("	replacer := strings.NewReplacer(replacements...)")
+	gen.P("	resolved := make([]string, len(rule.Permissions))")
+	gen.P("	for i, permission := range rule.Permissions {")
+	gen.P("		resolved[i] = replacer.Replace(permission)")
+	gen.P("		if strings.ContainsAny(resolved[i], \"{}\") {")
+	gen.P("			return nil, false")
+	gen.P("		}")
+	gen.P("	}")
+	gen.P("	return resolved, true")
+	gen.P("}")
+	gen.P()
+
+	generateRouteTrie(gen)
+
+	gen.P("// normalizePathForAuthzWithMap converts a path with actual values to its template form")
+	gen.P("// by matching against all known parameterized paths in the provided authz map for the given method")
+	gen.P("// e.g., \"/v1/foo/123\" -> \"/v1/foo/{foo_id}\"")
+	gen.P("// When several templates match, literal segments win over parameters, /v1/users/me over /v1/users/{id}")
+	gen.P("// The route trie of the map is built on every call, see NewAuthorizer")
+	gen.P("func normalizePathForAuthzWithMap(authzMap map[string]AuthzRule, actualPath, method string) string {")
+	gen.P("	return normalizePathWithTrie(newRouteTrie(authzMap), actualPath, method)")
+	gen.P("}")
+	gen.P()
+
+	gen.P("// normalizePathForAuthz converts a path with actual values to its template form using the default authz map")
+	gen.P("func normalizePathForAuthz(actualPath, method string) string {")
+	gen.P("	return normalizePathWithTrie(generatedRouteTrie, actualPath, method)")
+	gen.P("}")
+	gen.P()
+
+	gen.P("// normalizePathWithTrie converts a path with actual values to its template form using a route trie")
+	gen.P("func normalizePathWithTrie(trie routeTrie, actualPath, method string) string {")
+	gen.P("	if template, ok := trie.match(actualPath, method); ok {")
+	gen.P("		return template")
+	gen.P("	}")
+	gen.P("	")
+	gen.P("	// No template found, return original path")
+	gen.P("	return actualPath")
+	gen.P("}")
+	gen.P()
+
+	gen.P("// lookupRule returns the rule of a path and method in an authz map, trie being the route trie of the map")
+	gen.P("// The path is always matched against the templates, never looked up as is: a request path spelling a template,")
+	gen.P("// e.g. /v1/users/{id}, must not select its rule")
+	gen.P("func lookupRule(authzMap map[string]AuthzRule, trie routeTrie, path, method string) (AuthzRule, bool) {")
+	gen.P("	template, ok := trie.match(path, method)")
+	gen.P("	if !ok {")
+	gen.P("		return AuthzRule{}, false")
+	gen.P("	}")
+	gen.P("	rule, exists := authzMap[template+\"|\"+method]")
+	gen.P("	return rule, exists")
+	gen.P("}")
+	gen.P()
+
+	gen.P("// Authorizer checks requests against an authz map other than the default one, its route trie being built once")
+	gen.P("// Use it rather than the WithMap functions, which build the route trie of the map on every call")
+	gen.P("type Authorizer struct {")
+	gen.P("	authzMap map[string]AuthzRule")
+	gen.P("	trie     routeTrie")
+	gen.P("}")
+	gen.P()
+	gen.P("// NewAuthorizer returns the Authorizer of an authz map, which must not be modified afterwards")
+	gen.P("func NewAuthorizer(authzMap map[string]AuthzRule) *Authorizer {")
+	gen.P("	return &Authorizer{authzMap: authzMap, trie: newRouteTrie(authzMap)}")
+	gen.P("}")
+	gen.P()
+	gen.P("// IsAuthRequired returns whether authentication is required for a given path and method")
+	gen.P("func (a *Authorizer) IsAuthRequired(path, method string) bool {")
+	gen.P("	return isAuthRequired(a.authzMap, a.trie, path, method)")
+	gen.P("}")
+	gen.P()
+	gen.P("// HasPermission checks if any of the user permissions is allowed for a given path and method")
+	gen.P("func (a *Authorizer) HasPermission(path, method string, userPermissions []string) bool {")
+	gen.P("	return hasPermission(a.authzMap, a.trie, path, method, userPermissions)")
+	gen.P("}")
+	gen.P()
+	gen.P("// RoutePathParams returns the path variables of the rule of a path and method in order")
+	gen.P("func (a *Authorizer) RoutePathParams(path, method string) []string {")
+	gen.P("	rule, _ := lookupRule(a.authzMap, a.trie, path, method)")
+	gen.P("	return rule.PathParams")
+	gen.P("}")
+	gen.P()
+	gen.P("// NormalizePath converts a path with actual values to its template form, e.g. \"/v1/foo/123\" -> \"/v1/foo/{foo_id}\"")
+	gen.P("func (a *Authorizer) NormalizePath(actualPath, method string) string {")
+	gen.P("	return normalizePathWithTrie(a.trie, actualPath, method)")
+	gen.P("}")
+	gen.P()
+
+	gen.P("// IsAuthRequiredWithMap returns whether authentication is required for a given path and method using provided authz map")
+	gen.P("// The route trie of the map is built on every call, see NewAuthorizer")
+	gen.P("func IsAuthRequiredWithMap(authzMap map[string]AuthzRule, path, method string) bool {")
+	gen.P("	return isAuthRequired(authzMap, newRouteTrie(authzMap), path, method)")
+	gen.P("}")
+	gen.P()
+
+	gen.P("// IsAuthRequired returns whether authentication is required for a given path and method")
+	gen.P("func IsAuthRequired(path, method string) bool {")
+	gen.P("	return isAuthRequired(generatedAuthzMap, generatedRouteTrie, path, method)")
+	gen.P("}")
+	gen.P()
+
+	gen.P("// isAuthRequired returns whether authentication is required for a given path and method using an authz map and its route trie")
+	gen.P("func isAuthRequired(authzMap map[string]AuthzRule, trie routeTrie, path, method string) bool {")
+	gen.P("	// Health check endpoints do not require authentication")
+	gen.P("	if path == \"/v1/health\" && method == \"GET\" {")
+	gen.P("		return false")
+	gen.P("	}")
+	gen.P("	")
+	gen.P("	rule, exists := lookupRule(authzMap, trie, path, method)")
+	gen.P("	if !exists {")
+	gen.P("		return true // Default to requiring auth for undefined paths")
+	gen.P("	}")
+	gen.P("	return !rule.NoAuthRequired")
+	gen.P("}")
+	gen.P()
+
+	gen.P("// HasPermissionWithMap checks if any of the user permissions is allowed for a given path and method using provided authz map")
+	gen.P("// The route trie of the map is built on every call, see NewAuthorizer")
+	gen.P("func HasPermissionWithMap(authzMap map[string]AuthzRule, path, method string, userPermissions []string) bool {")
+	gen.P("	return hasPermission(authzMap, newRouteTrie(authzMap), path, method, userPermissions)")
+	gen.P("}")
+	gen.P()
+
+	gen.P("// HasPermission checks if any of the user permissions is allowed for a given path and method")
+	gen.P("// Templated permissions are resolved from the path variables, the rules referencing request fields outside of the")
+	gen.P("// path being refused, as only the request resolves them")
+	gen.P("func HasPermission(path, method string, userPermissions []string) bool {")
+	gen.P("	return hasPermission(generatedAuthzMap, generatedRouteTrie, path, method, userPermissions)")
+	gen.P("}")
+	gen.P()
+
+	gen.P("// hasPermission checks if any of the user permissions is allowed for a given path and method using an authz map and its route trie")
+	gen.P("func hasPermission(authzMap map[string]AuthzRule, trie routeTrie, path, method string, userPermissions []string) bool {")
+	gen.P("	// Health check endpoints do not require authentication")
+	gen.P("	if path == \"/v1/health\" && method == \"GET\" {")
+	gen.P("		return true")
+	gen.P("	}")
+	gen.P("	")
+	gen.P("	rule, exists := lookupRule(authzMap, trie, path, method)")
+	gen.P("	if !exists {")
+	gen.P("		return false")
+	gen.P("	}")
+	gen.P("	")
+	gen.P("	// Templated permissions are resolved from the path, e.g. project:{project_id}:read for /v1/projects/p1")
+	gen.P("	if rule.TemplatedPermissions {")
+	gen.P("		permissions, ok := resolveRoutePermissions(rule, trie, path, method)")
+	gen.P("		if !ok {")
+	gen.P("			return false")
+	gen.P("		}")
+	gen.P("		rule.Permissions = permissions")
+	gen.P("	}")
+	gen.P("	return rule.Allows(userPermissions)")
+	gen.P("}")
+	gen.P()
+
+	gen.P("// resolveRoutePermissions returns the templated permissions of the rule of a path and method resolved from the")
+	gen.P("// values of its path variables, nested ones being referenced by field path as well, e.g. {item.id} for item_id")
+	gen.P("// It returns false when a placeholder references a request field outside of the path, which the path cannot resolve,")
+	gen.P("// or a value ResolvePermissions refuses")
+	gen.P("func resolveRoutePermissions(rule AuthzRule, trie routeTrie, path, method string) ([]string, bool) {")
+	gen.P("	template, _ := trie.match(path, method)")
+	gen.P("	values := pathValues(template, path)")
+	gen.P("	pathParams := make(map[string]string, len(values))")
+	gen.P("	for i, name := range rule.PathParams {")
+	gen.P("		if i >= len(values) {")
+	gen.P("			break")
+	gen.P("		}")
+	gen.P("		pathParams[name] = values[i]")
+	gen.P("		if field, ok := rule.PathParamFields[name]; ok {")
+	gen.P("			pathParams[field] = values[i]")
+	gen.P("		}")
+	gen.P("	}")
+	gen.P("	return ResolvePermissions(rule, pathParams)")
+	gen.P("}")
+	gen.P()
+
+	gen.P("// RoutePathParamsWithMap returns the path variables of the rule of a path and method in order using provided authz map")
+	gen.P("// e.g. [\"foo_id\"] for \"/v1/foo/123\" matching \"/v1/foo/{foo_id}\", nil when no rule matches")
+	gen.P("// The route trie of the map is built on every call, see NewAuthorizer")
+	gen.P("func RoutePathParamsWithMap(authzMap map[string]AuthzRule, path, method string) []string {")
+	gen.P("	rule, _ := lookupRule(authzMap, newRouteTrie(authzMap), path, method)")
+	gen.P("	return rule.PathParams")
+	gen.P("}")
+	gen.P()
+
+	gen.P("// RoutePathParams returns the path variables of the rule of a path and method in order")
+	gen.P("func RoutePathParams(path, method string) []string {")
+	gen.P("	rule, _ := lookupRule(generatedAuthzMap, generatedRouteTrie, path, method)")
+	gen.P("	return rule.PathParams")
+	gen.P("}")
+}
+
+// writeAuthzRuleFields writes the fields of the AuthzRule literal of rule, every line starting with indent.
+// The types of the authz map, declared in authzPackage, are qualified when gen belongs to another package.
+func writeAuthzRuleFields(gen *protogen.GeneratedFile, rule Rule, indent string, authzPackage protogen.GoImportPath) {
+	ruleType := gen.QualifiedGoIdent(protogen.GoIdent{GoName: "AuthzRule", GoImportPath: authzPackage})
+	exprType := gen.QualifiedGoIdent(protogen.GoIdent{GoName: "PermissionExpr", GoImportPath: authzPackage})
+	gen.P(indent + "Permissions:    " + goStringSlice(rule.Permissions) + ",")
+	if rule.RawPermissions != nil {
+		gen.P(indent + "RawPermissions: " + goStringSlice(rule.RawPermissions) + ",")
+	}
+	if denied := rule.DeniedPermissions(); denied != nil {
+		gen.P(indent + "DeniedPermissions: " + goStringSlice(denied) + ",")
+	}
+	if len(rule.Roles) > 0 {
+		gen.P(indent + "Roles:          " + goStringSlice(rule.Roles) + ",")
+	}
+	if len(rule.AllowedScopes) > 0 {
+		gen.P(indent + "AllowedScopes:  " + goStringSlice(rule.AllowedScopes) + ",")
+	}
+	if rule.Require != nil {
+		gen.P(indent + "Require:        &" + goPermissionExpr(*rule.Require, exprType) + ",")
+	}
+	gen.P(indent + "NoAuthRequired: " + fmt.Sprintf("%t", rule.NoAuthRequired) + ",")
+	if len(rule.EnvOverrides) > 0 {
+		// Overrides are written as the whole rule in effect in their environment
+		gen.P(indent + "EnvOverrides: map[string]" + ruleType + "{")
+		for _, env := range rule.Envs() {
+			gen.P(indent + "	" + strconv.Quote(env) + ": {")
+			writeAuthzRuleFields(gen, rule.ForEnv(env), indent+"		", authzPackage)
+			gen.P(indent + "	},")
+		}
+		gen.P(indent + "},")
+	}
+	if rule.Deprecated {
+		gen.P(indent + "Deprecated:     true,")
+	}
+	if rule.HasTemplatedPermissions() {
+		gen.P(indent + "TemplatedPermissions: true,")
+		gen.P(indent + "PermissionParams: " + goStringSlice(rule.PermissionParams()) + ",")
+	}
+	gen.P(indent + "Level:          " + `"` + string(rule.Level) + `"` + ",")
+	gen.P(indent + "StreamingType:  " + `"` + string(rule.StreamingType) + `"` + ",")
+	gen.P(indent + "Transport:      " + strconv.Quote(rule.Transport) + ",")
+	gen.P(indent + "GRPCMethod:     " + strconv.Quote(rule.GRPCMethod) + ",")
+	if len(rule.PathParams) > 0 {
+		gen.P(indent + "PathParams:     " + goStringSlice(rule.PathParams) + ",")
+	}
+	if len(rule.PathParamPatterns) > 0 {
+		gen.P(indent + "PathParamPatterns: " + goStringMap(rule.PathParamPatterns) + ",")
+	}
+	if len(rule.PathParamFields) > 0 {
+		gen.P(indent + "PathParamFields: " + goStringMap(rule.PathParamFields) + ",")
+	}
+	if rule.Body != "" {
+		gen.P(indent + "Body:           " + strconv.Quote(rule.Body) + ",")
+	}
+	if rule.ResponseBody != "" {
+		gen.P(indent + "ResponseBody:   " + strconv.Quote(rule.ResponseBody) + ",")
+	}
+	gen.P(indent + "ProtoPackage:   " + strconv.Quote(string(rule.ProtoPackage)) + ",")
+	gen.P(indent + "ServiceName:    " + strconv.Quote(string(rule.ServiceName)) + ",")
+	gen.P(indent + "MethodName:     " + strconv.Quote(string(rule.MethodName)) + ",")
+}
+
+// goStringSlice returns the Go literal of a string slice.
+func goStringSlice(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, value := range values {
+		quoted = append(quoted, strconv.Quote(value))
+	}
+	return "[]string{" + strings.Join(quoted, ", ") + "}"
+}
+
+// goStringMap returns the Go literal of a string map, sorted by key.
+func goStringMap(values map[string]string) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	entries := make([]string, 0, len(keys))
+	for _, key := range keys {
+		entries = append(entries, strconv.Quote(key)+": "+strconv.Quote(values[key]))
+	}
+	return "map[string]string{" + strings.Join(entries, ", ") + "}"
+}
+
+// goPermissionExpr returns the Go literal of the generated PermissionExpr matching expr, of type exprType, e.g.
+// authzmap.PermissionExpr outside of the authz map package.
+func goPermissionExpr(expr PermissionExpr, exprType string) string {
+	var fields []string
+	if len(expr.AnyOf) > 0 {
+		fields = append(fields, "AnyOf: "+goStringSlice(expr.AnyOf))
+	}
+	if len(expr.AllOf) > 0 {
+		fields = append(fields, "AllOf: "+goStringSlice(expr.AllOf))
+	}
+	if len(expr.All) > 0 {
+		fields = append(fields, "All: "+goPermissionExprSlice(expr.All, exprType))
+	}
+	if len(expr.Any) > 0 {
+		fields = append(fields, "Any: "+goPermissionExprSlice(expr.Any, exprType))
+	}
+	return exprType + "{" + strings.Join(fields, ", ") + "}"
+}
+
+// goPermissionExprSlice returns the Go literal of a PermissionExpr slice, eliding the element type.
+func goPermissionExprSlice(exprs []PermissionExpr, exprType string) string {
+	literals := make([]string, 0, len(exprs))
+	for _, expr := range exprs {
+		literals = append(literals, strings.TrimPrefix(goPermissionExpr(expr, exprType), exprType))
+	}
+	return "[]" + exprType + "{" + strings.Join(literals, ", ") + "}"
+}
@@ -0,0 +1,183 @@
+package authzgen
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+// Subjects of the Casbin policy lines not granted to a permission.
+const (
+	casbinAnonymous     = "role:anonymous"     // anyone, authenticated or not
+	casbinAuthenticated = "role:authenticated" // any authenticated caller
+)
+
+// casbinModel is the Casbin model of the policy: subjects are permissions, users being granted them as roles, and
+// objects are keyMatch2 path patterns. role:anonymous lines apply to every caller.
+const casbinModel = `[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = (p.sub == "` + casbinAnonymous + `" || g(r.sub, p.sub)) && keyMatch2(r.obj, p.obj) && r.act == p.act
+`
+
+// generateCasbinFiles writes the authorization rules as a Casbin model and policy, one policy line per permission,
+// path and method. Requirements a Casbin policy cannot express, combinations of permissions, roles, denied and
+// templated permissions, are skipped with a warning, leaving the routes denied.
+func generateCasbinFiles(plugin *protogen.Plugin, out outputPackage, rules []Rule, logger *slog.Logger) {
+	model := out.newFile(plugin, "casbin/model.conf")
+	model.P("# Code generated by protoc-gen-go-authz. DO NOT EDIT.")
+	model.P()
+	model.P(strings.TrimSuffix(casbinModel, "\n"))
+
+	policy := out.newFile(plugin, "casbin/policy.csv")
+	policy.P("# Code generated by protoc-gen-go-authz. DO NOT EDIT.")
+	seen := make(map[string]bool)
+	line := func(subject, object, action string) {
+		if l := "p, " + subject + ", " + object + ", " + action; !seen[l] {
+			seen[l] = true
+			policy.P(l)
+		}
+	}
+
+	hasHealthCheck := false
+	for _, rule := range rules {
+		object, action := rule.GRPCMethod, "POST"
+		if rule.Transport == TransportHTTP {
+			pattern, ok := casbinPath(rule)
+			if !ok {
+				logger.Warn(fmt.Sprintf("skipping Casbin policy of %s %s: path template not supported by keyMatch2", rule.HTTPMethod, rule.HTTPPath))
+				continue
+			}
+			object, action = pattern, rule.HTTPMethod
+			if action == "GET" && rule.HTTPPath == "/v1/health" {
+				hasHealthCheck = true
+			}
+		}
+
+		subjects, ok := casbinSubjects(rule)
+		if !ok {
+			logger.Warn(fmt.Sprintf("skipping Casbin policy of %s: requirement not expressible as a permission subject", rule.FullMethodName()))
+			continue
+		}
+		for _, subject := range subjects {
+			line(subject, object, action)
+		}
+	}
+	// Health check endpoints do not require authentication, as with the middleware
+	if !hasHealthCheck {
+		line(casbinAnonymous, "/v1/health", "GET")
+	}
+}
+
+// casbinSubjects returns the subjects granted access by a rule, any of which is enough, or false when the rule
+// requires more than holding a single permission. Templated permissions are left out, their placeholders cannot be
+// bound to the path.
+func casbinSubjects(rule Rule) ([]string, bool) {
+	if rule.NoAuthRequired {
+		return []string{casbinAnonymous}, true
+	}
+	if len(rule.Roles) > 0 || len(rule.DeniedPermissions()) > 0 {
+		return nil, false
+	}
+
+	var subjects []string
+	for _, set := range securityAlternatives(rule) {
+		switch {
+		case len(set) == 0:
+			subjects = append(subjects, casbinAuthenticated)
+		case len(set) > 1:
+			return nil, false
+		case !IsTemplatedPermission(set[0]):
+			subjects = append(subjects, set[0])
+		}
+	}
+	return subjects, len(subjects) > 0
+}
+
+// routePattern converts a rule to a route pattern of wildcards spanning whole segments, e.g. GET /v1/users/{id}, which
+// the casbin and istio targets rewrite to their own syntax. Variables matching several segments become one wildcard
+// per segment, {name=**} becomes {name...}. It returns false when the path template cannot be expressed that way.
+func routePattern(rule Rule) (string, bool) {
+	supported := true
+	path := templateVariableRegex.ReplaceAllStringFunc(rule.HTTPPath, func(variable string) string {
+		match := templateVariableRegex.FindStringSubmatch(variable)
+		name := PathParamName(match[1])
+		if match[2] == "" || match[2] == "*" {
+			return "{" + name + "}"
+		}
+
+		segments := strings.Split(match[2], "/")
+		wildcards := 0
+		for i, segment := range segments {
+			switch segment {
+			case "*":
+				wildcards++
+				segments[i] = "{" + name + "}"
+				if wildcards > 1 {
+					segments[i] = "{" + name + "_" + strconv.Itoa(wildcards) + "}"
+				}
+			case "**":
+				segments[i] = "{" + name + "...}"
+			default:
+				if strings.Contains(segment, "*") {
+					supported = false
+				}
+			}
+		}
+		return strings.Join(segments, "/")
+	})
+
+	// Wildcards must span whole segments, which rules out custom verbs following a variable,
+	// and a multi-segment wildcard must be the last segment
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.ContainsAny(segment, "{}") && !(strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")) {
+			supported = false
+		}
+		if strings.HasSuffix(segment, "...}") && i != len(segments)-1 {
+			supported = false
+		}
+	}
+	if !supported {
+		return "", false
+	}
+
+	return strings.ToUpper(rule.HTTPMethod) + " " + path, true
+}
+
+// casbinPath converts the path template of a rule to a keyMatch2 pattern, e.g. /v1/users/:id for /v1/users/{id}
+// and /v1/files/* for /v1/files/{path=**}. It returns false for custom verbs and the templates routePattern cannot
+// express either.
+func casbinPath(rule Rule) (string, bool) {
+	pattern, ok := routePattern(rule)
+	if !ok {
+		return "", false
+	}
+	_, path, _ := strings.Cut(pattern, " ")
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		switch {
+		case strings.HasSuffix(segment, "...}"):
+			segments[i] = "*"
+		case strings.HasPrefix(segment, "{"):
+			segments[i] = ":" + strings.Trim(segment, "{}")
+		case strings.Contains(segment, ":"):
+			// keyMatch2 would take a custom verb for a variable
+			return "", false
+		}
+	}
+	return strings.Join(segments, "/"), true
+}
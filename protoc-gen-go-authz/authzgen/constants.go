@@ -0,0 +1,155 @@
+package authzgen
+
+import (
+	"fmt"
+	"go/token"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+// permissionConstName derives the Go constant name of a permission by title-casing its segments
+// split on : and _, e.g. user:read_all becomes PermissionUserReadAll. The placeholders of templated permissions
+// are left out, e.g. project:{project_id}:read becomes PermissionProjectRead.
+func permissionConstName(permission string) (string, error) {
+	var name strings.Builder
+	name.WriteString("Permission")
+	for _, placeholder := range PermissionPlaceholders(permission) {
+		permission = strings.ReplaceAll(permission, "{"+placeholder+"}", "")
+	}
+	for _, segment := range strings.FieldsFunc(permission, func(r rune) bool { return r == ':' || r == '_' }) {
+		name.WriteString(strings.ToUpper(segment[:1]) + segment[1:])
+	}
+	if !token.IsIdentifier(name.String()) {
+		return "", fmt.Errorf("permission %q does not map to a valid Go identifier", permission)
+	}
+	return name.String(), nil
+}
+
+// placeholderParamName derives the parameter name of a placeholder in the builder of a templated permission by
+// camel-casing it, e.g. projectId for project_id and itemId for item.id.
+func placeholderParamName(placeholder string) string {
+	var name strings.Builder
+	for i, segment := range strings.FieldsFunc(placeholder, func(r rune) bool { return r == '.' || r == '_' }) {
+		if i == 0 {
+			name.WriteString(strings.ToLower(segment[:1]) + segment[1:])
+			continue
+		}
+		name.WriteString(strings.ToUpper(segment[:1]) + segment[1:])
+	}
+	// Placeholders named after a Go keyword, e.g. {type}
+	if !token.IsIdentifier(name.String()) {
+		name.WriteString("_")
+	}
+	return name.String()
+}
+
+// generateConstantsFile generates a Go file declaring every permission of the rules as an exported constant, listed
+// in AllPermissions. Templated permissions get a format string constant, suffixed with Format, and a function
+// building the permission from the values of its placeholders. Unexpanded wildcards are left out, and two
+// permissions mapping to the same name are an error.
+func generateConstantsFile(plugin *protogen.Plugin, out outputPackage, rules []Rule) error {
+	names := make(map[string]string)
+	templated := make(map[string]bool)
+	for _, rule := range rules {
+		for _, permission := range slices.Concat(rule.Permissions, rule.DeniedPermissions()) {
+			if strings.HasSuffix(permission, ":*") {
+				continue
+			}
+			name, err := permissionConstName(permission)
+			if err != nil {
+				return err
+			}
+			if existing, ok := names[name]; ok && existing != permission {
+				return fmt.Errorf("permissions %q and %q both map to the constant %s", existing, permission, name)
+			}
+			names[name] = permission
+			if IsTemplatedPermission(permission) {
+				templated[name] = true
+			}
+		}
+	}
+	// The format string constants share the namespace of the other constants
+	for name := range templated {
+		if existing, ok := names[name+"Format"]; ok {
+			return fmt.Errorf("permissions %q and %q both map to the constant %sFormat", existing, names[name], name)
+		}
+	}
+
+	constNames := make([]string, 0, len(names))
+	for name := range names {
+		constNames = append(constNames, name)
+	}
+	sort.Strings(constNames)
+
+	gen := out.newGoFile(plugin, "generated_authz_permissions.go")
+
+	if len(templated) > 0 {
+		gen.P("import \"fmt\"")
+		gen.P()
+	}
+
+	// Generate the constants, the file only declaring an empty AllPermissions when no rule references a permission
+	if len(constNames) > len(templated) {
+		gen.P("// Permissions referenced by the authorization map")
+		gen.P("const (")
+		for _, name := range constNames {
+			if !templated[name] {
+				gen.P("	" + name + " = " + strconv.Quote(names[name]))
+			}
+		}
+		gen.P(")")
+		gen.P()
+	}
+
+	// Generate the templated permission builders
+	if len(templated) > 0 {
+		gen.P("// Format strings of the templated permissions referenced by the authorization map, one verb per placeholder")
+		gen.P("const (")
+		for _, name := range constNames {
+			if templated[name] {
+				gen.P("	" + name + "Format = " + strconv.Quote(permissionFormat(names[name])))
+			}
+		}
+		gen.P(")")
+		gen.P()
+	}
+	for _, name := range constNames {
+		if !templated[name] {
+			continue
+		}
+		permission := names[name]
+		var params []string
+		for _, placeholder := range PermissionPlaceholders(permission) {
+			params = append(params, placeholderParamName(placeholder))
+		}
+		gen.P("// ", name, " returns the ", permission, " permission for the given placeholder values")
+		gen.P("func ", name, "(", strings.Join(params, ", "), " string) string {")
+		gen.P("	return fmt.Sprintf(", name, "Format, ", strings.Join(params, ", "), ")")
+		gen.P("}")
+		gen.P()
+	}
+
+	gen.P("// AllPermissions lists the permissions referenced by the authorization map, templated ones excluded")
+	gen.P("var AllPermissions = []string{")
+	for _, name := range constNames {
+		if !templated[name] {
+			gen.P("	", name, ",")
+		}
+	}
+	gen.P("}")
+	return nil
+}
+
+// permissionFormat returns the fmt format string of a templated permission, its placeholders replaced by indexed
+// verbs in order of appearance, e.g. project:%[1]s:read for project:{project_id}:read.
+func permissionFormat(permission string) string {
+	format := strings.ReplaceAll(permission, "%", "%%")
+	for i, placeholder := range PermissionPlaceholders(permission) {
+		format = strings.ReplaceAll(format, "{"+placeholder+"}", "%["+strconv.Itoa(i+1)+"]s")
+	}
+	return format
+}
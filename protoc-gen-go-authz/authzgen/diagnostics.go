@@ -0,0 +1,54 @@
+package authzgen
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Warning is a diagnostic that does not fail the generation, e.g. a skipped method or a wildcard matching nothing.
+type Warning struct {
+	File    string // proto file the warning relates to, empty when unknown
+	Line    int    // 1-based line in File, 0 when unknown
+	Message string
+}
+
+// String returns the warning prefixed with its location, e.g. proto/v1/test.proto:42: message.
+func (w Warning) String() string {
+	switch {
+	case w.File == "":
+		return w.Message
+	case w.Line == 0:
+		return w.File + ": " + w.Message
+	default:
+		return fmt.Sprintf("%s:%d: %s", w.File, w.Line, w.Message)
+	}
+}
+
+// warningAt returns a warning located at desc, or without location when desc is nil or carries no source info.
+func warningAt(desc protoreflect.Descriptor, format string, args ...any) Warning {
+	warning := Warning{Message: fmt.Sprintf(format, args...)}
+	if desc != nil {
+		warning.File, warning.Line = sourceLocation(desc)
+	}
+	return warning
+}
+
+// Warnings returns the warnings recorded since the parser was created.
+func (p *Parser) Warnings() []Warning {
+	return p.warnings
+}
+
+// warn records a warning.
+func (p *Parser) warn(warning Warning) {
+	p.warnings = append(p.warnings, warning)
+}
+
+// debugf logs a diagnostic at debug level, formatting it only when the logger enables that level.
+func (p *Parser) debugf(format string, args ...any) {
+	if p.Logger.Enabled(context.Background(), slog.LevelDebug) {
+		p.Logger.Debug(fmt.Sprintf(format, args...))
+	}
+}
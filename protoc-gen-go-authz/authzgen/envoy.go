@@ -0,0 +1,274 @@
+package authzgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+// envoyJWTFilter and envoyJWTPayloadKey locate the JWT payload in the dynamic metadata, as written by the
+// jwt_authn filter configured with payload_in_metadata: jwt_payload.
+const (
+	envoyJWTFilter     = "envoy.filters.http.jwt_authn"
+	envoyJWTPayloadKey = "jwt_payload"
+)
+
+// envoyGrantSource locates a list of grants of the caller, permissions or roles, in a claim of the JWT payload or,
+// when header is set, in a request header listing them separated by commas, e.g. set by an authenticating proxy.
+type envoyGrantSource struct {
+	claim  string
+	header string
+}
+
+// envoyFilter is an envoy.filters.http.rbac filter of an HTTP connection manager.
+type envoyFilter struct {
+	Name        string `json:"name"`
+	TypedConfig struct {
+		Type  string `json:"@type"`
+		Rules struct {
+			Action   string                 `json:"action"`
+			Policies map[string]envoyPolicy `json:"policies"`
+		} `json:"rules"`
+	} `json:"typed_config"`
+}
+
+// envoyPolicy grants the routes of permissions to the callers matching one of principals.
+type envoyPolicy struct {
+	Permissions []envoyObject `json:"permissions"`
+	Principals  []envoyObject `json:"principals"`
+}
+
+// envoyObject is a message of the RBAC filter configuration, e.g. a permission or a principal.
+type envoyObject = map[string]any
+
+// generateEnvoyRBACFile writes the authorization rules as an Envoy RBAC filter configuration, to enforce them at
+// the edge. Routes are grouped into one ALLOW policy per requirement, principals matching the permissions and roles
+// of the caller, and public routes get a policy of their own. Requests matching no policy are denied.
+func generateEnvoyRBACFile(plugin *protogen.Plugin, out outputPackage, rules []Rule, permissions, roles envoyGrantSource) error {
+	filter := envoyFilter{Name: "envoy.filters.http.rbac"}
+	filter.TypedConfig.Type = "type.googleapis.com/envoy.extensions.filters.http.rbac.v3.RBAC"
+	filter.TypedConfig.Rules.Action = "ALLOW"
+	policies := make(map[string]envoyPolicy)
+
+	hasHealthCheck := false
+	for _, rule := range rules {
+		name, principal := "public", envoyObject{"any": true}
+		if !rule.NoAuthRequired {
+			name, principal = envoyPrincipal(rule, permissions, roles)
+		}
+		policy := policies[name]
+		policy.Principals = []envoyObject{principal}
+		policy.Permissions = append(policy.Permissions, envoyRoute(rule))
+		policies[name] = policy
+
+		if rule.Transport == TransportHTTP && rule.HTTPMethod == "GET" && rule.HTTPPath == "/v1/health" {
+			hasHealthCheck = true
+		}
+	}
+	// Health check endpoints do not require authentication, as with the middleware
+	if !hasHealthCheck {
+		policy := policies["public"]
+		policy.Principals = []envoyObject{{"any": true}}
+		policy.Permissions = append(policy.Permissions, envoyRoute(Rule{Transport: TransportHTTP, HTTPMethod: "GET", HTTPPath: "/v1/health"}))
+		policies["public"] = policy
+	}
+	filter.TypedConfig.Rules.Policies = policies
+
+	// The configuration goes through JSON so that it is written with the same YAML conventions as the manifest
+	content, err := json.Marshal(filter)
+	if err != nil {
+		return fmt.Errorf("failed to marshal Envoy RBAC filter: %w", err)
+	}
+	node, err := decodeYAMLNode(json.NewDecoder(bytes.NewReader(content)))
+	if err != nil {
+		return fmt.Errorf("failed to convert Envoy RBAC filter to YAML: %w", err)
+	}
+	var document strings.Builder
+	document.WriteString("# Code generated by protoc-gen-go-authz. DO NOT EDIT.\n")
+	node.write(&document, "", "")
+
+	gen := out.newFile(plugin, "envoy_rbac.yaml")
+	_, err = gen.Write([]byte(document.String()))
+	return err
+}
+
+// envoyRoute returns the RBAC permission matching the method and path template of a rule, or the gRPC full
+// method name of the rules without HTTP annotation.
+func envoyRoute(rule Rule) envoyObject {
+	method, path := rule.HTTPMethod, envoyPathMatcher(rule.HTTPPath)
+	if rule.Transport != TransportHTTP {
+		method, path = "POST", envoyObject{"exact": rule.GRPCMethod}
+	}
+	return envoyObject{"and_rules": envoyObject{"rules": []envoyObject{
+		{"header": envoyObject{"name": ":method", "string_match": envoyObject{"exact": method}}},
+		{"url_path": envoyObject{"path": path}},
+	}}}
+}
+
+// envoyPathMatcher converts a path template to an Envoy string matcher, exact for literal paths and a regular
+// expression otherwise, e.g. /v1/users/([^/]+) for /v1/users/{id}.
+func envoyPathMatcher(template string) envoyObject {
+	regex, variables := pathTemplateRegex(template)
+	if len(variables) == 0 && !strings.Contains(template, "*") {
+		return envoyObject{"exact": template}
+	}
+	return envoyObject{"safe_regex": envoyObject{"regex": regex}}
+}
+
+// envoyPrincipal returns the RBAC principal of the callers satisfying a rule, along with the name of the policy
+// grouping the rules with the same requirement, e.g. "any of read:all, read:test".
+func envoyPrincipal(rule Rule, permissions, roles envoyGrantSource) (string, envoyObject) {
+	var names []string
+	var ids []envoyObject
+	switch {
+	case rule.Require != nil:
+		names = append(names, "require "+envoyExprName(*rule.Require))
+		ids = append(ids, envoyExprPrincipal(*rule.Require, permissions))
+	case len(rule.Permissions) > 0 || len(rule.AllowedScopes) > 0:
+		// The scopes are looked up in the permissions of the caller, the policies not telling them apart
+		grants := slices.Concat(rule.Permissions, rule.AllowedScopes)
+		names = append(names, "any of "+strings.Join(grants, ", "))
+		ids = append(ids, envoyGrantsPrincipal("or_ids", grants, permissions))
+	default:
+		// Without permissions, any authenticated caller is granted access, holding a role implying authentication
+		names = append(names, "authenticated")
+		if len(rule.Roles) == 0 {
+			ids = append(ids, permissions.authenticated())
+		}
+	}
+	if len(rule.Roles) > 0 {
+		names = append(names, "role "+strings.Join(rule.Roles, ", "))
+		ids = append(ids, envoyGrantsPrincipal("or_ids", rule.Roles, roles))
+	}
+	if denied := rule.DeniedPermissions(); len(denied) > 0 {
+		names = append(names, "denied "+strings.Join(denied, ", "))
+		ids = append(ids, envoyObject{"not_id": envoyGrantsPrincipal("or_ids", denied, permissions)})
+	}
+
+	if len(ids) == 1 {
+		return strings.Join(names, "; "), ids[0]
+	}
+	return strings.Join(names, "; "), envoyObject{"and_ids": envoyObject{"ids": ids}}
+}
+
+// envoyExprName describes a permission requirement in a policy name, e.g. (read:all | read:test) & write:test.
+func envoyExprName(expr PermissionExpr) string {
+	var clauses []string
+	if len(expr.AnyOf) > 0 {
+		clauses = append(clauses, envoyGroup(expr.AnyOf, " | "))
+	}
+	if len(expr.AllOf) > 0 {
+		clauses = append(clauses, envoyGroup(expr.AllOf, " & "))
+	}
+	for _, nested := range expr.All {
+		clauses = append(clauses, envoyExprName(nested))
+	}
+	if len(expr.Any) > 0 {
+		alternatives := make([]string, 0, len(expr.Any))
+		for _, nested := range expr.Any {
+			alternatives = append(alternatives, envoyExprName(nested))
+		}
+		clauses = append(clauses, envoyGroup(alternatives, " | "))
+	}
+	return strings.Join(clauses, " & ")
+}
+
+// envoyGroup joins terms with an operator, parenthesized when there are several of them.
+func envoyGroup(terms []string, operator string) string {
+	if len(terms) == 1 {
+		return terms[0]
+	}
+	return "(" + strings.Join(terms, operator) + ")"
+}
+
+// envoyExprPrincipal returns the RBAC principal of the callers satisfying a permission requirement.
+func envoyExprPrincipal(expr PermissionExpr, permissions envoyGrantSource) envoyObject {
+	var ids []envoyObject
+	if len(expr.AnyOf) > 0 {
+		ids = append(ids, envoyGrantsPrincipal("or_ids", expr.AnyOf, permissions))
+	}
+	if len(expr.AllOf) > 0 {
+		ids = append(ids, envoyGrantsPrincipal("and_ids", expr.AllOf, permissions))
+	}
+	for _, nested := range expr.All {
+		ids = append(ids, envoyExprPrincipal(nested, permissions))
+	}
+	if len(expr.Any) > 0 {
+		alternatives := make([]envoyObject, 0, len(expr.Any))
+		for _, nested := range expr.Any {
+			alternatives = append(alternatives, envoyExprPrincipal(nested, permissions))
+		}
+		ids = append(ids, envoyObject{"or_ids": envoyObject{"ids": alternatives}})
+	}
+	if len(ids) == 1 {
+		return ids[0]
+	}
+	return envoyObject{"and_ids": envoyObject{"ids": ids}}
+}
+
+// envoyGrantsPrincipal returns the RBAC principal of the callers whose grants in source list values, any of them
+// with or_ids or every one of them with and_ids.
+func envoyGrantsPrincipal(set string, values []string, source envoyGrantSource) envoyObject {
+	if len(values) == 1 {
+		return source.principal(values[0])
+	}
+	ids := make([]envoyObject, 0, len(values))
+	for _, value := range values {
+		ids = append(ids, source.principal(value))
+	}
+	return envoyObject{set: envoyObject{"ids": ids}}
+}
+
+// authenticated returns the RBAC principal of the authenticated callers, carrying a JWT payload or the header.
+func (s envoyGrantSource) authenticated() envoyObject {
+	if s.header != "" {
+		return envoyObject{"header": envoyObject{"name": s.header, "present_match": true}}
+	}
+	return envoyObject{"metadata": envoyObject{
+		"filter": envoyJWTFilter,
+		"path":   []envoyObject{{"key": envoyJWTPayloadKey}},
+		"value":  envoyObject{"present_match": true},
+	}}
+}
+
+// principal returns the RBAC principal of the callers whose grants list value.
+// Templated permissions cannot be resolved at the edge, their placeholders match any value, e.g. a caller holding
+// project:123:read holds project:{project_id}:read whatever the project of the request.
+func (s envoyGrantSource) principal(value string) envoyObject {
+	if s.header != "" {
+		// The values of a header are matched as a whole, the grant being one of its comma separated elements
+		return envoyObject{"header": envoyObject{
+			"name":         s.header,
+			"string_match": envoyObject{"safe_regex": envoyObject{"regex": `(.*,)?\s*` + envoyGrantRegex(value, "[^,]+") + `\s*(,.*)?`}},
+		}}
+	}
+
+	match := envoyObject{"exact": value}
+	if IsTemplatedPermission(value) {
+		match = envoyObject{"safe_regex": envoyObject{"regex": envoyGrantRegex(value, ".+")}}
+	}
+	return envoyObject{"metadata": envoyObject{
+		"filter": envoyJWTFilter,
+		"path":   []envoyObject{{"key": envoyJWTPayloadKey}, {"key": s.claim}},
+		"value":  envoyObject{"list_match": envoyObject{"one_of": envoyObject{"string_match": match}}},
+	}}
+}
+
+// envoyGrantRegex returns the regular expression matching a grant, the placeholders of templated permissions
+// matching placeholder.
+func envoyGrantRegex(value, placeholder string) string {
+	parts := strings.Split(value, "{")
+	for i, part := range parts {
+		if _, literal, ok := strings.Cut(part, "}"); ok && i > 0 {
+			parts[i] = placeholder + regexp.QuoteMeta(literal)
+		} else {
+			parts[i] = regexp.QuoteMeta(part)
+		}
+	}
+	return strings.Join(parts, "")
+}
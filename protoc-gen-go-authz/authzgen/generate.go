@@ -0,0 +1,358 @@
+package authzgen
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Target is an additional output generated next to the authz map.
+type Target string
+
+// Targets selected with Options.Targets.
+const (
+	TargetHTTPMiddleware  Target = "http-middleware"
+	TargetGRPCInterceptor Target = "grpc-interceptor"
+	TargetJSON            Target = "json"
+	TargetYAML            Target = "yaml"
+	TargetOpenAPI         Target = "openapi"
+	TargetOpenAPIOverlay  Target = "openapi-overlay"
+	TargetConstants       Target = "constants"
+	TargetRegistry        Target = "registry"
+	TargetTestHelper      Target = "test-helper"
+	TargetMarkdown        Target = "markdown"
+	TargetEnvoyRBAC       Target = "envoy-rbac"
+	TargetRego            Target = "rego"
+	TargetCasbin          Target = "casbin"
+	TargetGRPCAuthz       Target = "grpc-authz"
+	TargetIstio           Target = "istio"
+	TargetSpiceDB         Target = "spicedb"
+)
+
+// Targets lists every target.
+var Targets = []Target{
+	TargetHTTPMiddleware, TargetGRPCInterceptor, TargetJSON, TargetYAML, TargetOpenAPI, TargetOpenAPIOverlay,
+	TargetConstants, TargetRegistry, TargetTestHelper, TargetMarkdown, TargetEnvoyRBAC, TargetRego, TargetCasbin,
+	TargetGRPCAuthz, TargetIstio, TargetSpiceDB,
+}
+
+// Options configures ParseRules and Generate, each field matching a parameter of protoc-gen-go-authz. Start from
+// DefaultOptions, the zero value of some fields is not valid.
+type Options struct {
+	// ExtensionNames and ExtensionNumber identify the authz options, see NewParser.
+	ExtensionNames  ExtensionNames
+	ExtensionNumber protoreflect.FieldNumber
+
+	// GRPCFallback, PermissionPattern, Strict, AllowEmptyPermissions and NoAuthConflictWarning configure the
+	// parser, see Parser.
+	GRPCFallback          bool
+	PermissionPattern     *regexp.Regexp
+	Strict                bool
+	AllowEmptyPermissions bool
+	NoAuthConflictWarning bool
+
+	// StrictWellKnown applies the strict mode to the DefaultStrictExemptServices as well.
+	StrictWellKnown bool
+
+	// Check only validates the files, in strict mode, logging every violation and generating nothing.
+	Check bool
+
+	// Logger receives the diagnostics: the debug ones of the parser, the warnings, such as skipped methods and
+	// routes, and the violations of the check mode as errors. DefaultOptions and nil discard them.
+	Logger *slog.Logger
+
+	// Targets are the additional outputs generated next to the authz map.
+	Targets map[Target]bool
+
+	// OutputDir, relative to the output directory, and OutputGoPackage, an import path optionally followed by ;name,
+	// locate the authz map and the files of the targets.
+	OutputDir       string
+	OutputGoPackage string
+
+	// RegistrySuffix is appended to the name of the proto files of the registry target files.
+	RegistrySuffix string
+
+	// HTTPAllowUnmatched passes through the requests matching no rule in the http-middleware target.
+	HTTPAllowUnmatched bool
+
+	// OpenAPISecurityScheme is the security scheme listing the permissions in the openapi and openapi-overlay targets.
+	OpenAPISecurityScheme string
+
+	// The claims, or the headers when set, listing the permissions and roles of the caller in the envoy-rbac target.
+	EnvoyPermissionsClaim  string
+	EnvoyRolesClaim        string
+	EnvoyPermissionsHeader string
+	EnvoyRolesHeader       string
+
+	// GRPCAuthzMetadataPrefix prefixes the metadata carrying the permissions and roles in the grpc-authz target.
+	GRPCAuthzMetadataPrefix string
+
+	// The namespace, workload labels and claims of the policies in the istio target.
+	IstioNamespace        string
+	IstioSelector         map[string]string
+	IstioPermissionsClaim string
+	IstioRolesClaim       string
+
+	// The permission separator and subject definition of the spicedb target.
+	SpiceDBPermissionSeparator string
+	SpiceDBSubjectType         string
+}
+
+// DefaultOptions returns the options protoc-gen-go-authz runs with when no parameter is set.
+func DefaultOptions() Options {
+	return Options{
+		ExtensionNames:             DefaultExtensionNames,
+		ExtensionNumber:            DefaultExtensionNumber,
+		GRPCFallback:               true,
+		Logger:                     slog.New(slog.DiscardHandler),
+		PermissionPattern:          regexp.MustCompile(DefaultPermissionPattern),
+		Targets:                    make(map[Target]bool),
+		OutputDir:                  defaultOutputDir,
+		OutputGoPackage:            defaultOutputGoPackage,
+		RegistrySuffix:             "_authz.pb.go",
+		OpenAPISecurityScheme:      "bearerAuth",
+		EnvoyPermissionsClaim:      "permissions",
+		EnvoyRolesClaim:            "roles",
+		GRPCAuthzMetadataPrefix:    "x-authz-",
+		IstioSelector:              make(map[string]string),
+		IstioPermissionsClaim:      "permissions",
+		IstioRolesClaim:            "roles",
+		SpiceDBPermissionSeparator: ":",
+		SpiceDBSubjectType:         "user",
+	}
+}
+
+// ParseRules parses the files of plugin to generate with the parser settings of opts and returns their rules,
+// deduplicated, validated and sorted with SortRules. Warnings are logged with opts.Logger.
+func ParseRules(plugin *protogen.Plugin, opts Options) ([]Rule, error) {
+	logger := optionsLogger(opts)
+	rules, err := parseFiles(plugin, opts, logger)
+	if err != nil {
+		return nil, err
+	}
+	// Rules are emitted once, by package, service, method and route so that the generated files diff cleanly
+	rules = DedupeRules(rules)
+	if rules == nil {
+		// Files without annotated methods, or with empty services only, still get well-formed outputs, e.g. an empty
+		// rules list rather than null
+		rules = []Rule{}
+	}
+	if err := ValidateRules(rules); err != nil {
+		return nil, err
+	}
+	SortRules(rules)
+
+	// Overlapping routes requiring different permissions are resolved by specificity, which is easily overlooked
+	if err := reportOverlaps(rules, opts.Strict, logger); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// optionsLogger returns the logger of opts, discarding the diagnostics when unset.
+func optionsLogger(opts Options) *slog.Logger {
+	if opts.Logger == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+	return opts.Logger
+}
+
+// parseFiles returns the rules of the files of plugin to generate, along with the errors of all of them joined.
+func parseFiles(plugin *protogen.Plugin, opts Options, logger *slog.Logger) ([]Rule, error) {
+	parser := NewParser(plugin.Files, opts.ExtensionNames, opts.ExtensionNumber)
+	parser.GRPCFallback = opts.GRPCFallback
+	parser.PermissionPattern = opts.PermissionPattern
+	// Every method must declare its authz in check mode
+	parser.Strict = opts.Strict || opts.Check
+	parser.NoAuthConflictWarning = opts.NoAuthConflictWarning
+	parser.AllowEmptyPermissions = opts.AllowEmptyPermissions
+	if opts.StrictWellKnown {
+		parser.StrictExemptServices = nil
+	}
+	parser.Logger = logger
+	var allAuthzRules []Rule
+	var errs []error
+
+	// Process each proto file
+	for _, file := range plugin.Files {
+		if !file.Generate {
+			continue
+		}
+
+		rules, err := parser.ParseFile(file)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		allAuthzRules = append(allAuthzRules, rules...)
+	}
+	for _, warning := range parser.Warnings() {
+		logger.Warn(warning.String())
+	}
+	return allAuthzRules, errors.Join(errs...)
+}
+
+// Generate parses the files of plugin to generate and writes the authz map along with the outputs of the selected
+// targets. In check mode, the violations are logged with opts.Logger, like warnings, and nothing is written.
+func Generate(plugin *protogen.Plugin, opts Options) error {
+	logger := optionsLogger(opts)
+	out, err := newOutputPackage(opts.OutputDir, opts.OutputGoPackage)
+	if err != nil {
+		return err
+	}
+	// The suffix must not clash with the pb files nor escape their directory
+	if !strings.HasSuffix(opts.RegistrySuffix, ".go") || opts.RegistrySuffix == ".pb.go" || strings.Contains(opts.RegistrySuffix, "/") {
+		return fmt.Errorf("registry suffix %q must end with .go, differ from .pb.go and contain no /", opts.RegistrySuffix)
+	}
+
+	if opts.Check {
+		rules, err := parseFiles(plugin, opts, logger)
+		rules = DedupeRules(rules)
+		return reportViolations(logger, err, ValidateRules(rules), reportOverlaps(rules, true, logger))
+	}
+	allAuthzRules, err := ParseRules(plugin, opts)
+	if err != nil {
+		return err
+	}
+
+	// Always generate the authz map file, even if empty
+	// This ensures the package exists for imports
+	generateAuthzMapFile(plugin, out, allAuthzRules)
+	targets := opts.Targets
+	if targets[TargetHTTPMiddleware] {
+		generateHTTPMiddlewareFile(plugin, out, opts.HTTPAllowUnmatched)
+	}
+	if targets[TargetGRPCInterceptor] {
+		generateGRPCInterceptorFile(plugin, out, allAuthzRules)
+	}
+	if targets[TargetJSON] {
+		if err := generateJSONFile(plugin, out, allAuthzRules); err != nil {
+			return err
+		}
+	}
+	if targets[TargetYAML] {
+		if err := generateYAMLFile(plugin, out, allAuthzRules); err != nil {
+			return err
+		}
+	}
+	if targets[TargetOpenAPI] {
+		if err := generateOpenAPIFile(plugin, out, allAuthzRules, opts.OpenAPISecurityScheme, logger); err != nil {
+			return err
+		}
+	}
+	if targets[TargetOpenAPIOverlay] {
+		if err := generateOpenAPIOverlayFile(plugin, out, allAuthzRules, opts.OpenAPISecurityScheme, logger); err != nil {
+			return err
+		}
+	}
+	if targets[TargetConstants] {
+		if err := generateConstantsFile(plugin, out, allAuthzRules); err != nil {
+			return err
+		}
+	}
+	if targets[TargetRegistry] {
+		if err := generateRegistryFiles(plugin, out, allAuthzRules, opts.RegistrySuffix); err != nil {
+			return err
+		}
+	}
+	if targets[TargetTestHelper] {
+		generateTestHelperFile(plugin, out)
+	}
+	if targets[TargetMarkdown] {
+		generateMarkdownFiles(plugin, allAuthzRules)
+	}
+	if targets[TargetRego] {
+		if err := generateRegoFiles(plugin, out, allAuthzRules); err != nil {
+			return err
+		}
+	}
+	if targets[TargetCasbin] {
+		generateCasbinFiles(plugin, out, allAuthzRules, logger)
+	}
+	if targets[TargetGRPCAuthz] {
+		if err := generateGRPCAuthzFile(plugin, out, allAuthzRules, opts.GRPCAuthzMetadataPrefix, logger); err != nil {
+			return err
+		}
+	}
+	if targets[TargetIstio] {
+		if err := generateIstioFile(plugin, out, allAuthzRules, opts.IstioNamespace, opts.IstioSelector, opts.IstioPermissionsClaim, opts.IstioRolesClaim, logger); err != nil {
+			return err
+		}
+	}
+	if targets[TargetSpiceDB] {
+		if err := generateSpiceDBFiles(plugin, out, allAuthzRules, opts.SpiceDBPermissionSeparator, opts.SpiceDBSubjectType, logger); err != nil {
+			return err
+		}
+	}
+	if targets[TargetEnvoyRBAC] {
+		if err := generateEnvoyRBACFile(plugin, out, allAuthzRules,
+			envoyGrantSource{claim: opts.EnvoyPermissionsClaim, header: opts.EnvoyPermissionsHeader},
+			envoyGrantSource{claim: opts.EnvoyRolesClaim, header: opts.EnvoyRolesHeader},
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reportOverlaps warns about the overlapping routes requiring different permissions, or fails when strict is set.
+// Overlaps of routes with the same requirements are harmless, whichever route matches a request.
+func reportOverlaps(rules []Rule, strict bool, logger *slog.Logger) error {
+	var errs []error
+	for _, overlap := range FindOverlaps(rules) {
+		if overlap.SameRequirements() {
+			continue
+		}
+		winner := "the most specific one wins"
+		if overlap.Ambiguous() {
+			winner = "neither is more specific and the one with the leftmost literal segment wins"
+		}
+		message := fmt.Sprintf("routes %s %s of %s and %s %s of %s overlap with different permissions, %s",
+			overlap.A.HTTPMethod, overlap.A.HTTPPath, overlap.A.FullMethodName(),
+			overlap.B.HTTPMethod, overlap.B.HTTPPath, overlap.B.FullMethodName(), winner)
+		if strict {
+			errs = append(errs, errors.New(message))
+			continue
+		}
+		logger.Warn(Warning{File: overlap.A.SourceFile, Line: overlap.A.SourceLine, Message: message}.String())
+	}
+	return errors.Join(errs...)
+}
+
+// reportViolations logs every violation found in check mode as an error and returns an error when there is any.
+func reportViolations(logger *slog.Logger, errs ...error) error {
+	var violations []error
+	for _, err := range errs {
+		violations = append(violations, flattenErrors(err)...)
+	}
+	if len(violations) == 0 {
+		logger.Info("check passed with no violation")
+		return nil
+	}
+
+	for _, violation := range violations {
+		logger.Error(violation.Error())
+	}
+	return fmt.Errorf("check failed with %d violation(s)", len(violations))
+}
+
+// flattenErrors returns the errors joined with errors.Join in err, recursively.
+func flattenErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []error{err}
+	}
+	var errs []error
+	for _, err := range joined.Unwrap() {
+		errs = append(errs, flattenErrors(err)...)
+	}
+	return errs
+}
@@ -0,0 +1,248 @@
+package authzgen
+
+import (
+	"encoding/json"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+// testProtoFiles are the fixture protos declaring rules, imported from testProtoRoot.
+var testProtoFiles = []string{
+	"proto/v1/test.proto",
+	"proto/v1/defaults.proto",
+	"proto/v1/multi_service.proto",
+	"proto/v1/streaming.proto",
+}
+
+// generateTestFiles runs Generate on the proto files with every target and returns the content of the generated
+// files by name.
+func generateTestFiles(t testing.TB, files ...string) map[string]string {
+	t.Helper()
+	plugin := newTestPlugin(t, nil, files...)
+	opts := DefaultOptions()
+	opts.Targets = make(map[Target]bool, len(Targets))
+	for _, target := range Targets {
+		opts.Targets[target] = true
+	}
+	if err := Generate(plugin, opts); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	return generatedFiles(t, plugin)
+}
+
+// generatedFiles returns the content of the files generated by plugin by name.
+func generatedFiles(t testing.TB, plugin *protogen.Plugin) map[string]string {
+	t.Helper()
+	response := plugin.Response()
+	if response.Error != nil {
+		t.Fatalf("Generate() response error = %s", response.GetError())
+	}
+	generated := make(map[string]string, len(response.File))
+	for _, file := range response.File {
+		generated[file.GetName()] = file.GetContent()
+	}
+	return generated
+}
+
+func TestGenerateDeterministic(t *testing.T) {
+	want := generateTestFiles(t, testProtoFiles...)
+	random := rand.New(rand.NewPCG(1, 2))
+	for range 5 {
+		files := slices.Clone(testProtoFiles)
+		random.Shuffle(len(files), func(i, j int) { files[i], files[j] = files[j], files[i] })
+		got := generateTestFiles(t, files...)
+		for _, name := range slices.Sorted(maps.Keys(want)) {
+			if got[name] != want[name] {
+				t.Errorf("Generate(%v) wrote a different %s than Generate(%v)", files, name, testProtoFiles)
+			}
+		}
+		if len(got) != len(want) {
+			t.Errorf("Generate(%v) wrote %d files, want %d", files, len(got), len(want))
+		}
+	}
+}
+
+func TestGenerateRoles(t *testing.T) {
+	// The generated checker tells both the permissions and the roles of the caller
+	generated := generateTestFiles(t, "proto/v1/test.proto")["authzmap/generated_authz_map.go"]
+	for _, want := range []string{"HasPermission(permission string) bool", "HasRole(role string) bool"} {
+		if !strings.Contains(generated, want) {
+			t.Errorf("generated_authz_map.go does not declare %s", want)
+		}
+	}
+}
+
+func TestRoutePatternNestedPathParams(t *testing.T) {
+	rule := findRule(t, parseTestFiles(t, nil, "proto/v1/test.proto"), "proto.v1.TestService.TestWithNestedField")
+	// The route pattern uses the flat name of the nested variable
+	if pattern, ok := routePattern(rule); !ok || pattern != "PATCH /v1/test11/{item_owner_id}" {
+		t.Errorf("routePattern() = %q, %v, want PATCH /v1/test11/{item_owner_id}", pattern, ok)
+	}
+}
+
+func TestGenerateDeprecated(t *testing.T) {
+	sources := map[string]string{"items.proto": `
+syntax = "proto3";
+
+package items.v1;
+
+import "google/api/annotations.proto";
+import "proto/v1/option.proto";
+
+option go_package = "example.com/items/v1";
+
+service ItemService {
+  rpc GetLegacy(GetRequest) returns (GetResponse) {
+    option deprecated = true;
+    option (google.api.http) = {get: "/v1/legacy/items/{id}"};
+    option (proto.v1.authz) = {permissions: ["items:read"]};
+  }
+
+  rpc Get(GetRequest) returns (GetResponse) {
+    option (google.api.http) = {get: "/v1/items/{id}"};
+    option (proto.v1.authz) = {permissions: ["items:read"]};
+  }
+}
+
+message GetRequest {
+  string id = 1;
+}
+
+message GetResponse {}
+`}
+	rules := parseTestFiles(t, sources, "items.proto")
+	want := map[string]bool{"/v1/legacy/items/{id}": true, "/v1/items/{id}": false}
+	for _, rule := range rules {
+		if rule.Deprecated != want[rule.HTTPPath] {
+			t.Errorf("%s: Deprecated = %v, want %v", rule.HTTPPath, rule.Deprecated, want[rule.HTTPPath])
+		}
+	}
+
+	plugin := newTestPlugin(t, sources, "items.proto")
+	opts := DefaultOptions()
+	opts.Targets[TargetJSON] = true
+	opts.Targets[TargetOpenAPI] = true
+	if err := Generate(plugin, opts); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	generated := generatedFiles(t, plugin)
+
+	var document struct {
+		Rules []Rule `json:"rules"`
+	}
+	if err := json.Unmarshal([]byte(generated["authzmap/authz_rules.json"]), &document); err != nil {
+		t.Fatalf("failed to decode authz_rules.json: %v", err)
+	}
+	if len(document.Rules) != len(want) {
+		t.Fatalf("authz_rules.json has %d rules, want %d", len(document.Rules), len(want))
+	}
+	for _, rule := range document.Rules {
+		if rule.Deprecated != want[rule.HTTPPath] {
+			t.Errorf("authz_rules.json %s: deprecated = %v, want %v", rule.HTTPPath, rule.Deprecated, want[rule.HTTPPath])
+		}
+	}
+
+	var openAPI struct {
+		Paths map[string]map[string]struct {
+			Deprecated bool `json:"deprecated"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal([]byte(generated["authzmap/authz_openapi.json"]), &openAPI); err != nil {
+		t.Fatalf("failed to decode authz_openapi.json: %v", err)
+	}
+	for path, deprecated := range want {
+		if got := openAPI.Paths[path]["get"].Deprecated; got != deprecated {
+			t.Errorf("authz_openapi.json GET %s: deprecated = %v, want %v", path, got, deprecated)
+		}
+	}
+}
+
+func TestGenerateDescription(t *testing.T) {
+	sources := map[string]string{"users.proto": `
+syntax = "proto3";
+
+package users.v1;
+
+import "google/api/annotations.proto";
+import "proto/v1/option.proto";
+
+option go_package = "example.com/users/v1";
+
+service UsersService {
+  // Detached comments are not part of the description.
+
+  // Lists the users of the organization.
+  //
+  // Results are paginated, see page_token.
+  rpc List(ListRequest) returns (ListResponse) {
+    option (google.api.http) = {get: "/v1/users"};
+    option (proto.v1.authz) = {permissions: ["users:list"]};
+  }
+}
+
+message ListRequest {}
+
+message ListResponse {}
+`}
+	rules := parseTestFiles(t, sources, "users.proto")
+	want := "Lists the users of the organization.\n\nResults are paginated, see page_token."
+	if len(rules) != 1 || rules[0].Description != want {
+		t.Fatalf("rules = %+v, want the description %q", rules, want)
+	}
+
+	// The json target includes it, the openapi target uses it as the description of the operation
+	plugin := newTestPlugin(t, sources, "users.proto")
+	opts := DefaultOptions()
+	opts.Targets[TargetJSON] = true
+	opts.Targets[TargetOpenAPI] = true
+	if err := Generate(plugin, opts); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	generated := generatedFiles(t, plugin)
+	var document struct {
+		Rules []struct {
+			Description string `json:"description"`
+		} `json:"rules"`
+	}
+	if err := json.Unmarshal([]byte(generated["authzmap/authz_rules.json"]), &document); err != nil {
+		t.Fatalf("failed to decode authz_rules.json: %v", err)
+	}
+	if len(document.Rules) != 1 || document.Rules[0].Description != want {
+		t.Errorf("authz_rules.json rules = %+v, want the description %q", document.Rules, want)
+	}
+	var openAPI struct {
+		Paths map[string]map[string]struct {
+			Description string `json:"description"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal([]byte(generated["authzmap/authz_openapi.json"]), &openAPI); err != nil {
+		t.Fatalf("failed to decode authz_openapi.json: %v", err)
+	}
+	if got := openAPI.Paths["/v1/users"]["get"].Description; got != want {
+		t.Errorf("authz_openapi.json GET /v1/users: description = %q, want %q", got, want)
+	}
+}
+
+func TestGenerateIstioPolicyPerService(t *testing.T) {
+	// The routes of the services interleave once sorted, each service still gets a single ALLOW policy
+	generated := generateTestFiles(t, testProtoFiles...)["authzmap/istio_authorization_policies.yaml"]
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(generated, "\n") {
+		name, ok := strings.CutPrefix(strings.TrimSpace(line), "name: ")
+		if !ok {
+			continue
+		}
+		if seen[name] {
+			t.Errorf("istio_authorization_policies.yaml declares the policy %s twice", name)
+		}
+		seen[name] = true
+	}
+	if len(seen) == 0 {
+		t.Fatal("istio_authorization_policies.yaml declares no policy")
+	}
+}
@@ -0,0 +1,150 @@
+package authzgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+// grpcAuthzPolicy is a policy of the grpc-go authorization engine, loaded with authz.NewStatic.
+type grpcAuthzPolicy struct {
+	Name       string           `json:"name"`
+	DenyRules  []*grpcAuthzRule `json:"deny_rules,omitempty"`
+	AllowRules []*grpcAuthzRule `json:"allow_rules"`
+}
+
+// grpcAuthzRule matches the calls to paths whose metadata carries every one of headers.
+type grpcAuthzRule struct {
+	Name    string `json:"name"`
+	Request struct {
+		Paths   []string          `json:"paths"`
+		Headers []grpcAuthzHeader `json:"headers,omitempty"`
+	} `json:"request"`
+}
+
+// grpcAuthzHeader matches the calls whose metadata key has any of values, * matching any value.
+type grpcAuthzHeader struct {
+	Key    string   `json:"key"`
+	Values []string `json:"values"`
+}
+
+// grpcMetadataKeyRegex matches the valid gRPC metadata keys.
+var grpcMetadataKeyRegex = regexp.MustCompile(`^[0-9a-z_.-]+$`)
+
+// generateGRPCAuthzFile writes the authorization rules as a grpc-go authorization policy, for gRPC servers
+// enforcing them with authz.NewStatic rather than with the generated interceptors. The engine only sees metadata:
+// the permissions and roles of the caller are expected as one metadata entry each, prefixed with metadataPrefix,
+// e.g. x-authz-permission-read.all for read:all, set by the authentication in front of the engine.
+// Calls are allowed by one rule per requirement, holding the methods requiring it, and denied by default.
+func generateGRPCAuthzFile(plugin *protogen.Plugin, out outputPackage, rules []Rule, metadataPrefix string, logger *slog.Logger) error {
+	policy := grpcAuthzPolicy{Name: "authz", AllowRules: []*grpcAuthzRule{}}
+	allowRules := make(map[string]*grpcAuthzRule)
+	denyRules := make(map[string]*grpcAuthzRule)
+	// add appends path to the rule named name, creating it with headers when it does not exist
+	add := func(named map[string]*grpcAuthzRule, list *[]*grpcAuthzRule, name, path string, headers []grpcAuthzHeader) {
+		rule, ok := named[name]
+		if !ok {
+			rule = &grpcAuthzRule{Name: name}
+			rule.Request.Headers = headers
+			named[name] = rule
+			*list = append(*list, rule)
+		}
+		rule.Request.Paths = append(rule.Request.Paths, path)
+	}
+
+	// Every binding of a method shares the same rule so the first one is used
+	seen := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		if seen[rule.GRPCMethod] {
+			continue
+		}
+		seen[rule.GRPCMethod] = true
+		if rule.NoAuthRequired {
+			add(allowRules, &policy.AllowRules, "public", rule.GRPCMethod, nil)
+			continue
+		}
+
+		// Deny rules are evaluated first, the callers holding a denied permission being rejected whatever they hold
+		for _, permission := range rule.DeniedPermissions() {
+			key, ok := grpcMetadataKey(metadataPrefix+"permission-", permission)
+			if !ok {
+				return fmt.Errorf("denied permission %s of %s cannot be expressed as gRPC metadata", permission, rule.FullMethodName())
+			}
+			add(denyRules, &policy.DenyRules, "denied "+permission, rule.GRPCMethod, []grpcAuthzHeader{{Key: key, Values: []string{"*"}}})
+		}
+
+		// The caller must hold one of the roles along with one of the permission alternatives
+		roles := []string{""}
+		if len(rule.Roles) > 0 {
+			roles = rule.Roles
+		}
+		allowed := false
+		for _, set := range securityAlternatives(rule) {
+			headers, ok := grpcAuthzPermissionHeaders(set, metadataPrefix)
+			if !ok {
+				continue
+			}
+			for _, role := range roles {
+				name := strings.Join(set, " & ")
+				roleHeaders := headers
+				if role != "" {
+					key, ok := grpcMetadataKey(metadataPrefix+"role-", role)
+					if !ok {
+						continue
+					}
+					name = strings.TrimPrefix(name+"; role "+role, "; ")
+					// Holding the role is enough when no permission is required
+					roleHeaders = []grpcAuthzHeader{{Key: key, Values: []string{"*"}}}
+					if len(set) > 0 {
+						roleHeaders = append(append([]grpcAuthzHeader{}, headers...), roleHeaders...)
+					}
+				}
+				if name == "" {
+					name = "authenticated"
+				}
+				add(allowRules, &policy.AllowRules, name, rule.GRPCMethod, roleHeaders)
+				allowed = true
+			}
+		}
+		if !allowed {
+			logger.Warn(fmt.Sprintf("skipping gRPC authz policy of %s: no requirement expressible as metadata", rule.FullMethodName()))
+		}
+	}
+
+	content, err := json.MarshalIndent(policy, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal gRPC authz policy: %w", err)
+	}
+	gen := out.newFile(plugin, "grpc_authz_policy.json")
+	_, err = gen.Write(append(content, '\n'))
+	return err
+}
+
+// grpcAuthzPermissionHeaders returns the metadata the caller must carry to hold every permission of set, or false
+// when some cannot be expressed, e.g. templated permissions the engine cannot resolve. Authenticated callers without
+// permission carry the authenticated metadata.
+func grpcAuthzPermissionHeaders(set []string, metadataPrefix string) ([]grpcAuthzHeader, bool) {
+	if len(set) == 0 {
+		return []grpcAuthzHeader{{Key: metadataPrefix + "authenticated", Values: []string{"*"}}}, true
+	}
+	headers := make([]grpcAuthzHeader, 0, len(set))
+	for _, permission := range set {
+		key, ok := grpcMetadataKey(metadataPrefix+"permission-", permission)
+		if !ok || IsTemplatedPermission(permission) {
+			return nil, false
+		}
+		headers = append(headers, grpcAuthzHeader{Key: key, Values: []string{"*"}})
+	}
+	return headers, true
+}
+
+// grpcMetadataKey returns the metadata key of a permission or role, lower-cased with colons replaced by dots,
+// e.g. x-authz-permission-read.all for read:all. It returns false when the name is not a valid metadata key.
+func grpcMetadataKey(prefix, name string) (string, bool) {
+	key := prefix + strings.ReplaceAll(strings.ToLower(name), ":", ".")
+	return key, grpcMetadataKeyRegex.MatchString(key)
+}
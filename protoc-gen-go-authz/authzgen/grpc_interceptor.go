@@ -0,0 +1,177 @@
+package authzgen
+
+import (
+	"strconv"
+
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+// generateGRPCInterceptorFile generates the gRPC unary and stream server interceptors enforcing the authorization map.
+func generateGRPCInterceptorFile(plugin *protogen.Plugin, out outputPackage, rules []Rule) {
+	gen := out.newGoFile(plugin, "generated_authz_grpc.go")
+
+	gen.P("import (")
+	gen.P("	\"context\"")
+	gen.P("	\"fmt\"")
+	gen.P("	\"slices\"")
+	gen.P("	\"strings\"")
+	gen.P()
+	gen.P("	\"google.golang.org/grpc\"")
+	gen.P("	\"google.golang.org/grpc/codes\"")
+	gen.P("	\"google.golang.org/grpc/status\"")
+	gen.P("	\"google.golang.org/protobuf/reflect/protoreflect\"")
+	gen.P(")")
+	gen.P()
+
+	// Generate the lookup table, every binding of a method shares the same rule so the first one is used
+	gen.P("// grpcAuthzMap maps the gRPC full method names to their key in the authorization map")
+	gen.P("var grpcAuthzMap = map[string]string{")
+	seen := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		if seen[rule.GRPCMethod] {
+			continue
+		}
+		seen[rule.GRPCMethod] = true
+		gen.P("	" + strconv.Quote(rule.GRPCMethod) + ": " + strconv.Quote(rule.Key()) + ",")
+	}
+	gen.P("}")
+	gen.P()
+
+	// Generate the check shared by the interceptors
+	gen.P("// authorizeGRPC checks the caller of a gRPC full method name against its rule, the templated permissions being")
+	gen.P("// resolved from the request message req, nil for streaming calls which cannot declare any")
+	gen.P("// Calls to methods without rule are denied")
+	gen.P("func authorizeGRPC(ctx context.Context, checker PermissionChecker, fullMethod string, req any) error {")
+	gen.P("	key, exists := grpcAuthzMap[fullMethod]")
+	gen.P("	if !exists {")
+	gen.P("		return status.Errorf(codes.PermissionDenied, \"no authz rule for %s\", fullMethod)")
+	gen.P("	}")
+	gen.P("	rule := generatedAuthzMap[key]")
+	gen.P("	")
+	gen.P("	// If no auth is required, always allow")
+	gen.P("	if rule.NoAuthRequired {")
+	gen.P("		return nil")
+	gen.P("	}")
+	gen.P("	")
+	gen.P("	if rule.TemplatedPermissions {")
+	gen.P("		permissions, ok := resolveRequestPermissions(rule, req)")
+	gen.P("		if !ok {")
+	gen.P("			return status.Errorf(codes.PermissionDenied, \"unresolved permissions for %s\", fullMethod)")
+	gen.P("		}")
+	gen.P("		rule.Permissions = permissions")
+	gen.P("	}")
+	gen.P("	caller, err := callerGrants(ctx, checker)")
+	gen.P("	if err != nil {")
+	gen.P("		return status.Error(codes.Unauthenticated, err.Error())")
+	gen.P("	}")
+	gen.P("	if !rule.Check(caller) {")
+	gen.P("		return status.Errorf(codes.PermissionDenied, \"missing permissions for %s\", fullMethod)")
+	gen.P("	}")
+	gen.P("	return nil")
+	gen.P("}")
+	gen.P()
+
+	gen.P("// resolveRequestPermissions returns the templated permissions of rule resolved from the fields of the request")
+	gen.P("// message req, the path variables mapping to request fields as well, e.g. item_id to item.id")
+	gen.P("// It returns false when req is not a proto message, a placeholder references no singular scalar field or its")
+	gen.P("// value is one ResolvePermissions refuses")
+	gen.P("func resolveRequestPermissions(rule AuthzRule, req any) ([]string, bool) {")
+	gen.P("	message, ok := req.(protoreflect.ProtoMessage)")
+	gen.P("	if !ok {")
+	gen.P("		return nil, false")
+	gen.P("	}")
+	gen.P("	params := make(map[string]string, len(rule.PermissionParams))")
+	gen.P("	for _, param := range rule.PermissionParams {")
+	gen.P("		fieldPath := param")
+	gen.P("		if field, ok := rule.PathParamFields[param]; ok {")
+	gen.P("			fieldPath = field")
+	gen.P("		}")
+	gen.P("		value, ok := requestFieldValue(message.ProtoReflect(), fieldPath)")
+	gen.P("		if !ok {")
+	gen.P("			return nil, false")
+	gen.P("		}")
+	gen.P("		params[param] = value")
+	gen.P("	}")
+	gen.P("	return ResolvePermissions(rule, params)")
+	gen.P("}")
+	gen.P()
+	gen.P("// requestFieldValue returns the value of a singular scalar field of message by field path, e.g. item.id")
+	gen.P("func requestFieldValue(message protoreflect.Message, fieldPath string) (string, bool) {")
+	gen.P("	names := strings.Split(fieldPath, \".\")")
+	gen.P("	for _, name := range names[:len(names)-1] {")
+	gen.P("		field := message.Descriptor().Fields().ByName(protoreflect.Name(name))")
+	gen.P("		if field == nil || field.Message() == nil || field.IsList() || field.IsMap() {")
+	gen.P("			return \"\", false")
+	gen.P("		}")
+	gen.P("		message = message.Get(field).Message()")
+	gen.P("	}")
+	gen.P("	field := message.Descriptor().Fields().ByName(protoreflect.Name(names[len(names)-1]))")
+	gen.P("	if field == nil || field.Message() != nil || field.IsList() {")
+	gen.P("		return \"\", false")
+	gen.P("	}")
+	gen.P("	return message.Get(field).String(), true")
+	gen.P("}")
+	gen.P()
+
+	// Generate the interceptors
+	gen.P("// UnaryAuthzInterceptor enforces the authorization map on unary calls, resolving the templated permissions from")
+	gen.P("// the request message, e.g. project:{project_id}:read from its project_id field")
+	gen.P("// Calls to methods without rule are denied")
+	gen.P("func UnaryAuthzInterceptor(checker PermissionChecker) grpc.UnaryServerInterceptor {")
+	gen.P("	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {")
+	gen.P("		if err := authorizeGRPC(ctx, checker, info.FullMethod, req); err != nil {")
+	gen.P("			return nil, err")
+	gen.P("		}")
+	gen.P("		return handler(ctx, req)")
+	gen.P("	}")
+	gen.P("}")
+	gen.P()
+	gen.P("// StreamAuthzInterceptor enforces the authorization map on streaming calls, before the handler is invoked")
+	gen.P("// Calls to methods without rule are denied")
+	gen.P("func StreamAuthzInterceptor(checker PermissionChecker) grpc.StreamServerInterceptor {")
+	gen.P("	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {")
+	gen.P("		if err := authorizeGRPC(stream.Context(), checker, info.FullMethod, nil); err != nil {")
+	gen.P("			return err")
+	gen.P("		}")
+	gen.P("		return handler(srv, stream)")
+	gen.P("	}")
+	gen.P("}")
+	gen.P()
+
+	// Generate the coverage check of the registered services
+	gen.P("// grpcCoverageExemptServices are the well-known services ValidateServerCoverage ignores")
+	gen.P("var grpcCoverageExemptServices = []string{")
+	for _, service := range DefaultStrictExemptServices {
+		gen.P("	" + strconv.Quote(string(service)) + ",")
+	}
+	gen.P("}")
+	gen.P()
+	gen.P("// ServiceInfoProvider lists the services registered on a server, as implemented by *grpc.Server")
+	gen.P("type ServiceInfoProvider interface {")
+	gen.P("	GetServiceInfo() map[string]grpc.ServiceInfo")
+	gen.P("}")
+	gen.P()
+	gen.P("// ValidateServerCoverage returns an error listing the methods registered on srv without authz rule, which the")
+	gen.P("// interceptors would deny, e.g. a new service whose proto lacks authz options")
+	gen.P("// To be called at startup or in a test once every service is registered. The grpc.health and grpc.reflection")
+	gen.P("// services are ignored, they are expected to be served without the interceptors")
+	gen.P("func ValidateServerCoverage(srv ServiceInfoProvider) error {")
+	gen.P("	var missing []string")
+	gen.P("	for service, info := range srv.GetServiceInfo() {")
+	gen.P("		if slices.Contains(grpcCoverageExemptServices, service) {")
+	gen.P("			continue")
+	gen.P("		}")
+	gen.P("		for _, method := range info.Methods {")
+	gen.P("			fullMethod := \"/\" + service + \"/\" + method.Name")
+	gen.P("			if _, exists := grpcAuthzMap[fullMethod]; !exists {")
+	gen.P("				missing = append(missing, fullMethod)")
+	gen.P("			}")
+	gen.P("		}")
+	gen.P("	}")
+	gen.P("	if len(missing) == 0 {")
+	gen.P("		return nil")
+	gen.P("	}")
+	gen.P("	slices.Sort(missing)")
+	gen.P("	return fmt.Errorf(\"gRPC methods registered without authz rule: %s\", strings.Join(missing, \", \"))")
+	gen.P("}")
+}
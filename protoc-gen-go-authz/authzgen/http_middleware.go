@@ -0,0 +1,259 @@
+package authzgen
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+// pathTemplateRegex converts a path template to a regular expression matching the request paths, without anchors,
+// every variable being a capturing group, e.g. /v1/users/([^/]+) for /v1/users/{id}. As in the route trie, **
+// matches the remaining segments, none included. It also returns the flat names of the variables, in order.
+func pathTemplateRegex(template string) (string, []string) {
+	var regex strings.Builder
+	var names []string
+	last := 0
+	for _, loc := range templateVariableRegex.FindAllStringSubmatchIndex(template, -1) {
+		regex.WriteString(segmentsRegex(template[last:loc[0]]))
+		pattern := "*"
+		if loc[4] >= 0 && loc[5] > loc[4] {
+			pattern = template[loc[4]:loc[5]]
+		}
+		regex.WriteString("(" + segmentsRegex(pattern) + ")")
+		names = append(names, PathParamName(template[loc[2]:loc[3]]))
+		last = loc[1]
+	}
+	regex.WriteString(segmentsRegex(template[last:]))
+	return regex.String(), names
+}
+
+// segmentsRegex converts the segments of a path template, variables excluded, to a regular expression.
+func segmentsRegex(segments string) string {
+	parts := strings.Split(segments, "/")
+	for i, part := range parts {
+		// A wildcard may be followed by the custom verb, e.g. *:cancel
+		wildcard, verb, _ := strings.Cut(part, ":")
+		if verb != "" {
+			verb = regexp.QuoteMeta(":" + verb)
+		}
+		switch wildcard {
+		case "*":
+			parts[i] = "[^/]+" + verb
+		case "**":
+			parts[i] = ".*" + verb
+		default:
+			parts[i] = regexp.QuoteMeta(part)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// generateHTTPMiddlewareFile generates the net/http middleware enforcing the authorization map.
+// The requests matching no rule are denied by default, unless allowUnmatched is set in which case they are passed through.
+func generateHTTPMiddlewareFile(plugin *protogen.Plugin, out outputPackage, allowUnmatched bool) {
+	gen := out.newGoFile(plugin, "generated_authz_middleware.go")
+
+	gen.P("import (")
+	gen.P("	\"context\"")
+	gen.P("	\"encoding/json\"")
+	gen.P("	\"log/slog\"")
+	gen.P("	\"maps\"")
+	gen.P("	\"net/http\"")
+	gen.P("	\"slices\"")
+	gen.P(")")
+	gen.P()
+
+	// Generate the middleware
+	gen.P("// httpDefaultDeny is whether Middleware, ServiceMiddleware and EnvMiddleware deny the requests matching no rule,")
+	gen.P("// set with the http_allow_unmatched plugin parameter")
+	gen.P("const httpDefaultDeny = ", strconv.FormatBool(!allowUnmatched))
+	gen.P()
+	gen.P("// Middleware enforces the authorization map on the requests handled by next")
+	if allowUnmatched {
+		gen.P("// Requests are matched with the route trie of IsAuthRequired and HasPermission, the ones matching no rule are passed through")
+	} else {
+		gen.P("// Requests are matched with the route trie of IsAuthRequired and HasPermission, the ones matching no rule are denied")
+		gen.P("// except the health check")
+	}
+	gen.P("func Middleware(next http.Handler, checker PermissionChecker) http.Handler {")
+	gen.P("	return ServiceMiddleware(next, checker)")
+	gen.P("}")
+	gen.P()
+	gen.P("// ServiceMiddleware enforces the rules of the given services only, e.g. proto.v1.TestService, for servers")
+	gen.P("// serving a subset of the services, the routes of the other services being handled as matching no rule")
+	gen.P("// Without service, the rules of every service are enforced as with Middleware")
+	gen.P("func ServiceMiddleware(next http.Handler, checker PermissionChecker, services ...string) http.Handler {")
+	gen.P("	return NewMiddleware(next, checker, \"\", false, httpDefaultDeny, services...)")
+	gen.P("}")
+	gen.P()
+	gen.P("// EnvMiddleware is ServiceMiddleware enforcing the rules in effect in env, e.g. staging, the env_overrides of")
+	gen.P("// the authz options replacing the rules of the methods overridden in env")
+	gen.P("func EnvMiddleware(next http.Handler, checker PermissionChecker, env string, services ...string) http.Handler {")
+	gen.P("	return NewMiddleware(next, checker, env, false, httpDefaultDeny, services...)")
+	gen.P("}")
+	gen.P()
+	gen.P("// NewMiddleware is EnvMiddleware with a shadow mode, to roll out new rules: with auditOnly, the requests")
+	gen.P("// that would be denied are logged with slog, along with their route, the permissions required and the ones")
+	gen.P("// held by the caller, but passed through")
+	gen.P("// An empty env enforces the rules as declared, without override")
+	gen.P("// With defaultDeny, the requests matching no rule get a 403, except the health check. Without it they are passed")
+	gen.P("// through unchecked: a method added without authz option is then served to any caller, so it should only be")
+	gen.P("// disabled while the rules do not cover every route yet")
+	gen.P("func NewMiddleware(next http.Handler, checker PermissionChecker, env string, auditOnly, defaultDeny bool, services ...string) http.Handler {")
+	gen.P("	rules := make(map[string]AuthzRule)")
+	gen.P("	for key, rule := range generatedAuthzMap {")
+	gen.P("		// gRPC calls are not served by the middleware")
+	gen.P("		if rule.Transport != \"http\" {")
+	gen.P("			continue")
+	gen.P("		}")
+	gen.P("		rule = rule.ForEnv(env)")
+	gen.P("		if len(services) > 0 && !slices.Contains(services, rule.ProtoPackage+\".\"+rule.ServiceName) {")
+	gen.P("			continue")
+	gen.P("		}")
+	gen.P("		rules[key] = rule")
+	gen.P("	}")
+	gen.P("	")
+	gen.P("	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {")
+	gen.P("		// The route is matched as IsAuthRequired and HasPermission do, so that they agree with the middleware")
+	gen.P("		route, _ := generatedRouteTrie.match(r.URL.Path, r.Method)")
+	gen.P("		rule, matched := rules[route+\"|\"+r.Method]")
+	gen.P("		switch {")
+	gen.P("		case matched:")
+	gen.P("			// The path variables are set for the handlers, read with r.PathValue, and the templated permissions")
+	gen.P("			for i, value := range pathValues(route, r.URL.Path) {")
+	gen.P("				r.SetPathValue(rule.PathParams[i], value)")
+	gen.P("			}")
+	gen.P("			authorizeHTTP(w, r, next, checker, rule, r.Method+\" \"+route, auditOnly)")
+	gen.P("		case r.Method == http.MethodGet && r.URL.Path == \"/v1/health\":")
+	gen.P("			// Health check endpoints do not require authentication")
+	gen.P("			next.ServeHTTP(w, r)")
+	gen.P("		case !defaultDeny:")
+	gen.P("			// Pass through requests matching no rule, unprotected")
+	gen.P("			next.ServeHTTP(w, r)")
+	gen.P("		case auditOnly:")
+	gen.P("			auditDenial(r, http.StatusForbidden, r.Method+\" \"+r.URL.Path, \"reason\", \"no authz rule\")")
+	gen.P("			next.ServeHTTP(w, r)")
+	gen.P("		default:")
+	gen.P("			// Deny requests matching no rule")
+	gen.P("			writeAuthzError(w, http.StatusForbidden)")
+	gen.P("		}")
+	gen.P("	})")
+	gen.P("}")
+	gen.P()
+
+	gen.P("// AuthzMiddleware returns Middleware as a func(http.Handler) http.Handler, to be chained with other middlewares")
+	gen.P("func AuthzMiddleware(checker PermissionChecker) func(http.Handler) http.Handler {")
+	gen.P("	return func(next http.Handler) http.Handler {")
+	gen.P("		return Middleware(next, checker)")
+	gen.P("	}")
+	gen.P("}")
+	gen.P()
+
+	gen.P("// writeAuthzError writes an authorization failure with a JSON body, e.g. {\"error\":\"Forbidden\"}")
+	gen.P("func writeAuthzError(w http.ResponseWriter, code int) {")
+	gen.P("	w.Header().Set(\"Content-Type\", \"application/json\")")
+	gen.P("	w.Header().Set(\"X-Content-Type-Options\", \"nosniff\")")
+	gen.P("	w.WriteHeader(code)")
+	gen.P("	json.NewEncoder(w).Encode(map[string]string{\"error\": http.StatusText(code)})")
+	gen.P("}")
+	gen.P()
+
+	gen.P("// auditDenial logs a request that would have been denied with code, had the middleware not been in audit mode")
+	gen.P("// route is the method and path template the request matched, e.g. GET /v1/users/{id}, or its method and path")
+	gen.P("// when it matched no rule")
+	gen.P("func auditDenial(r *http.Request, code int, route string, attrs ...any) {")
+	gen.P("	attrs = append([]any{")
+	gen.P("		\"status\", code,")
+	gen.P("		\"method\", r.Method,")
+	gen.P("		\"path\", r.URL.Path,")
+	gen.P("		\"route\", route,")
+	gen.P("	}, attrs...)")
+	gen.P("	slog.WarnContext(r.Context(), \"authz: request would be denied\", attrs...)")
+	gen.P("}")
+	gen.P()
+
+	gen.P("// ruleAttrs prepends the gRPC method and the permissions required by rule to the audit attributes attrs")
+	gen.P("func ruleAttrs(rule AuthzRule, attrs ...any) []any {")
+	gen.P("	return append([]any{\"grpc_method\", rule.GRPCMethod, \"required_permissions\", rule.Permissions}, attrs...)")
+	gen.P("}")
+	gen.P()
+
+	gen.P("// authorizeHTTP checks the permissions and roles of the caller against rule before serving r with next,")
+	gen.P("// the rule being passed to next in the request context")
+	gen.P("// In audit mode, requests failing the check are logged as denied under route, but passed through")
+	gen.P("func authorizeHTTP(w http.ResponseWriter, r *http.Request, next http.Handler, checker PermissionChecker, rule AuthzRule, route string, auditOnly bool) {")
+	gen.P("	// If no auth is required, always allow")
+	gen.P("	if rule.NoAuthRequired {")
+	gen.P("		next.ServeHTTP(w, r.WithContext(withMatchedRule(r.Context(), rule)))")
+	gen.P("		return")
+	gen.P("	}")
+	gen.P("	")
+	gen.P("	matched, resolved := resolvePathPermissions(rule, r)")
+	gen.P("	caller, err := callerGrants(r.Context(), checker)")
+	gen.P("	switch {")
+	gen.P("	case err == nil && resolved && matched.Check(caller):")
+	gen.P("	case err != nil && !auditOnly:")
+	gen.P("		writeAuthzError(w, http.StatusUnauthorized)")
+	gen.P("		return")
+	gen.P("	case !auditOnly:")
+	gen.P("		writeAuthzError(w, http.StatusForbidden)")
+	gen.P("		return")
+	gen.P("	case err != nil:")
+	gen.P("		auditDenial(r, http.StatusUnauthorized, route, ruleAttrs(matched, \"error\", err.Error())...)")
+	gen.P("	default:")
+	gen.P("		auditDenial(r, http.StatusForbidden, route, ruleAttrs(matched,")
+	gen.P("			\"caller_permissions\", slices.Sorted(maps.Keys(caller.permissions)),")
+	gen.P("			\"caller_roles\", slices.Sorted(maps.Keys(caller.roles)),")
+	gen.P("		)...)")
+	gen.P("	}")
+	gen.P("	next.ServeHTTP(w, r.WithContext(withMatchedRule(r.Context(), matched)))")
+	gen.P("}")
+	gen.P()
+
+	// Generate the context of the handlers
+	gen.P("// matchedRuleKey is the context key of the rule matched by the middleware")
+	gen.P("type matchedRuleKey struct{}")
+	gen.P()
+	gen.P("// withMatchedRule returns a copy of ctx carrying the rule matched by the middleware")
+	gen.P("func withMatchedRule(ctx context.Context, rule AuthzRule) context.Context {")
+	gen.P("	return context.WithValue(ctx, matchedRuleKey{}, rule)")
+	gen.P("}")
+	gen.P()
+	gen.P("// PermissionsFromContext returns the permissions required by the rule the middleware matched, e.g. for audit logs")
+	gen.P("// Templated permissions are resolved, and it returns nil outside of the middleware")
+	gen.P("func PermissionsFromContext(ctx context.Context) []string {")
+	gen.P("	rule, _ := ctx.Value(matchedRuleKey{}).(AuthzRule)")
+	gen.P("	return rule.Permissions")
+	gen.P("}")
+	gen.P()
+	gen.P("// NoAuthRequiredFromContext reports whether the middleware matched a public route, a rule without auth required")
+	gen.P("func NoAuthRequiredFromContext(ctx context.Context) bool {")
+	gen.P("	rule, _ := ctx.Value(matchedRuleKey{}).(AuthzRule)")
+	gen.P("	return rule.NoAuthRequired")
+	gen.P("}")
+	gen.P()
+
+	gen.P("// resolvePathPermissions returns rule with its templated permissions resolved from the path variables of r")
+	gen.P("// It returns false, along with rule as is, when ResolvePermissions refuses them, the request then being denied")
+	gen.P("func resolvePathPermissions(rule AuthzRule, r *http.Request) (AuthzRule, bool) {")
+	gen.P("	if !rule.TemplatedPermissions {")
+	gen.P("		return rule, true")
+	gen.P("	}")
+	gen.P("	pathParams := make(map[string]string, len(rule.PathParams))")
+	gen.P("	for _, name := range rule.PathParams {")
+	gen.P("		pathParams[name] = r.PathValue(name)")
+	gen.P("		// Nested variables can be referenced by field path as well, e.g. {item.id} for item_id")
+	gen.P("		if field, ok := rule.PathParamFields[name]; ok {")
+	gen.P("			pathParams[field] = r.PathValue(name)")
+	gen.P("		}")
+	gen.P("	}")
+	gen.P("	permissions, ok := ResolvePermissions(rule, pathParams)")
+	gen.P("	if !ok {")
+	gen.P("		return rule, false")
+	gen.P("	}")
+	gen.P("	rule.Permissions = permissions")
+	gen.P("	return rule, true")
+	gen.P("}")
+}
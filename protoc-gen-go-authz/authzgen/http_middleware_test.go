@@ -0,0 +1,66 @@
+package authzgen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGeneratedEnvMiddleware(t *testing.T) {
+	plugin := newTestPlugin(t, nil, testProtoFiles...)
+	runGeneratedTests(t, plugin, middlewareOptions(), []string{"authzmap/checker_test.go", "authzmap/env_middleware_test.go"})
+}
+
+func TestGeneratedDefaultDeny(t *testing.T) {
+	tests := []string{"authzmap/checker_test.go", "authzmap/default_deny_test.go"}
+	for _, allowUnmatched := range []bool{false, true} {
+		opts := middlewareOptions()
+		opts.HTTPAllowUnmatched = allowUnmatched
+		runGeneratedTests(t, newTestPlugin(t, nil, testProtoFiles...), opts, tests)
+	}
+
+	// The security implication of passing unmatched requests through is told in the generated code
+	generated := generateTestFiles(t, testProtoFiles...)["authzmap/generated_authz_middleware.go"]
+	if want := "a method added without authz option is then served to any caller"; !strings.Contains(generated, want) {
+		t.Errorf("generated_authz_middleware.go does not tell %q", want)
+	}
+}
+
+func TestGeneratedEmptyServices(t *testing.T) {
+	sources := map[string]string{"empty.proto": `
+syntax = "proto3";
+
+package empty.v1;
+
+import "google/api/annotations.proto";
+import "proto/v1/option.proto";
+
+option go_package = "example.com/authztest/empty";
+
+service EmptyService {}
+
+service DefaultsOnlyService {
+  option (proto.v1.service_authz) = {permissions: ["admin:all"]};
+}
+
+service UnannotatedService {
+  rpc Get(Request) returns (Response) {
+    option (google.api.http) = {get: "/v1/unannotated"};
+  }
+}
+
+message Request {}
+
+message Response {}
+`}
+	rules := parseTestFiles(t, sources, "empty.proto")
+	if len(rules) != 0 {
+		t.Fatalf("rules = %+v, want none", rules)
+	}
+
+	// The generated packages compile without any rule, and deny every route
+	opts := middlewareOptions()
+	opts.Targets[TargetRegistry] = true
+	opts.Targets[TargetConstants] = true
+	opts.Targets[TargetTestHelper] = true
+	runGeneratedTests(t, newTestPlugin(t, sources, "empty.proto"), opts, []string{"authzmap/checker_test.go", "authzmap/empty_test.go"})
+}
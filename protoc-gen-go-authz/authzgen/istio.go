@@ -0,0 +1,230 @@
+package authzgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+// istioPolicy is a security.istio.io/v1 AuthorizationPolicy resource.
+type istioPolicy struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Metadata   struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace,omitempty"`
+	} `json:"metadata"`
+	Spec struct {
+		Selector *istioSelector `json:"selector,omitempty"`
+		Action   string         `json:"action"`
+		Rules    []*istioRule   `json:"rules"`
+	} `json:"spec"`
+}
+
+// istioSelector selects the workloads a policy applies to.
+type istioSelector struct {
+	MatchLabels map[string]string `json:"matchLabels"`
+}
+
+// istioRule matches the requests to any of its operations whose source and conditions all match.
+type istioRule struct {
+	From []istioSource    `json:"from,omitempty"`
+	To   []istioOperation `json:"to"`
+	When []istioCondition `json:"when,omitempty"`
+}
+
+// istioSource matches the requests carrying a JWT whose principal matches one of requestPrincipals.
+type istioSource struct {
+	Source struct {
+		RequestPrincipals []string `json:"requestPrincipals"`
+	} `json:"source"`
+}
+
+// istioOperation matches the requests to one of paths with one of methods.
+type istioOperation struct {
+	Operation struct {
+		Methods []string `json:"methods"`
+		Paths   []string `json:"paths"`
+	} `json:"operation"`
+}
+
+// istioCondition matches the requests whose key has any of values, e.g. a JWT claim listing one of them.
+type istioCondition struct {
+	Key    string   `json:"key"`
+	Values []string `json:"values"`
+}
+
+// generateIstioFile writes the authorization rules as Istio AuthorizationPolicy resources, one ALLOW policy per
+// service in namespace, selecting the workloads labeled with selector. Its rules group the routes requiring the same
+// permissions and roles, matched against the permissionsClaim and rolesClaim claims of the JWT validated by the mesh,
+// and a DENY policy lists the routes denied to the callers holding some permissions.
+func generateIstioFile(plugin *protogen.Plugin, out outputPackage, rules []Rule, namespace string, selector map[string]string, permissionsClaim, rolesClaim string, logger *slog.Logger) error {
+	var policies []*istioPolicy
+	newPolicy := func(name, action string) *istioPolicy {
+		policy := &istioPolicy{APIVersion: "security.istio.io/v1", Kind: "AuthorizationPolicy"}
+		policy.Metadata.Name, policy.Metadata.Namespace = name, namespace
+		if len(selector) > 0 {
+			policy.Spec.Selector = &istioSelector{MatchLabels: selector}
+		}
+		policy.Spec.Action = action
+		policies = append(policies, policy)
+		return policy
+	}
+	// add adds the operation of rule to the rule of policy matching from and when, grouped by key
+	add := func(policy *istioPolicy, grouped map[string]*istioRule, key string, from []istioSource, when []istioCondition, rule Rule) {
+		operation, ok := istioRuleOperation(rule)
+		if !ok {
+			logger.Warn(fmt.Sprintf("skipping Istio policy of %s %s: path template not supported by Istio", rule.HTTPMethod, rule.HTTPPath))
+			return
+		}
+		group, ok := grouped[key]
+		if !ok {
+			group = &istioRule{From: from, When: when}
+			grouped[key] = group
+			policy.Spec.Rules = append(policy.Spec.Rules, group)
+		}
+		group.To = append(group.To, operation)
+	}
+	permissionsKey := "request.auth.claims[" + permissionsClaim + "]"
+	rolesKey := "request.auth.claims[" + rolesClaim + "]"
+
+	// Every service gets its own policies, its rules keeping the order of the routes
+	rules = slices.Clone(rules)
+	slices.SortStableFunc(rules, func(a, b Rule) int {
+		return strings.Compare(string(a.ProtoPackage.Append(a.ServiceName)), string(b.ProtoPackage.Append(b.ServiceName)))
+	})
+	var allow, deny *istioPolicy
+	var allowRules, denyRules map[string]*istioRule
+	service := ""
+	for _, rule := range rules {
+		if name := string(rule.ProtoPackage) + "." + string(rule.ServiceName); name != service {
+			service = name
+			policyName := strings.ReplaceAll(string(rule.ProtoPackage), ".", "-") + "-" + strings.ReplaceAll(regoSnakeCase(string(rule.ServiceName)), "_", "-")
+			allow, deny = newPolicy(policyName, "ALLOW"), nil
+			allowRules, denyRules = make(map[string]*istioRule), make(map[string]*istioRule)
+		}
+
+		// Public routes are listed under a rule without source nor condition
+		if rule.NoAuthRequired {
+			add(allow, allowRules, "public", nil, nil, rule)
+			continue
+		}
+
+		if denied := rule.DeniedPermissions(); len(denied) > 0 {
+			if deny == nil {
+				deny = newPolicy(allow.Metadata.Name+"-deny", "DENY")
+			}
+			when := []istioCondition{{Key: permissionsKey, Values: denied}}
+			add(deny, denyRules, "denied "+strings.Join(denied, ","), nil, when, rule)
+		}
+
+		// Every condition of a rule must match, the values of a condition being alternatives
+		var roles []istioCondition
+		if len(rule.Roles) > 0 {
+			roles = []istioCondition{{Key: rolesKey, Values: rule.Roles}}
+		}
+		alternatives := istioAlternatives(rule, permissionsKey)
+		if len(alternatives) == 0 {
+			logger.Warn(fmt.Sprintf("skipping Istio policy of %s: templated permissions cannot be resolved by Istio", rule.FullMethodName()))
+			continue
+		}
+		for _, when := range alternatives {
+			when = append(when, roles...)
+			var from []istioSource
+			if len(when) == 0 {
+				// Without permissions nor roles, any caller carrying a valid JWT is granted access
+				from = []istioSource{{}}
+				from[0].Source.RequestPrincipals = []string{"*"}
+			}
+			key, err := json.Marshal(struct {
+				From []istioSource
+				When []istioCondition
+			}{from, when})
+			if err != nil {
+				return fmt.Errorf("failed to marshal Istio rule: %w", err)
+			}
+			add(allow, allowRules, string(key), from, when, rule)
+		}
+	}
+
+	var document strings.Builder
+	document.WriteString("# Code generated by protoc-gen-go-authz. DO NOT EDIT.\n")
+	for i, policy := range policies {
+		if i > 0 {
+			document.WriteString("---\n")
+		}
+		content, err := json.Marshal(policy)
+		if err != nil {
+			return fmt.Errorf("failed to marshal Istio authorization policy: %w", err)
+		}
+		node, err := decodeYAMLNode(json.NewDecoder(bytes.NewReader(content)))
+		if err != nil {
+			return fmt.Errorf("failed to convert Istio authorization policy to YAML: %w", err)
+		}
+		node.write(&document, "", "")
+	}
+
+	gen := out.newFile(plugin, "istio_authorization_policies.yaml")
+	_, err := gen.Write([]byte(document.String()))
+	return err
+}
+
+// istioAlternatives returns the conditions of each alternative satisfying the permissions of a rule, a single
+// condition listing them when any one of them is enough. Templated permissions are left out, their placeholders
+// cannot be resolved from the request by Istio.
+func istioAlternatives(rule Rule, permissionsKey string) [][]istioCondition {
+	alternatives := securityAlternatives(rule)
+	var anyOf []string
+	var conditions [][]istioCondition
+	for _, set := range alternatives {
+		if slices.ContainsFunc(set, IsTemplatedPermission) {
+			continue
+		}
+		if len(set) == 1 {
+			anyOf = append(anyOf, set[0])
+			continue
+		}
+		var when []istioCondition
+		for _, permission := range set {
+			when = append(when, istioCondition{Key: permissionsKey, Values: []string{permission}})
+		}
+		conditions = append(conditions, when)
+	}
+	if len(anyOf) > 0 {
+		conditions = append([][]istioCondition{{{Key: permissionsKey, Values: anyOf}}}, conditions...)
+	}
+	return conditions
+}
+
+// istioRuleOperation returns the operation matching the method and path template of a rule, converted to an Istio
+// path template, e.g. /v1/users/{*} for /v1/users/{id}, or the gRPC full method name of the rules without HTTP
+// annotation. It returns false for the templates routePattern cannot express either.
+func istioRuleOperation(rule Rule) (istioOperation, bool) {
+	var operation istioOperation
+	if rule.Transport != TransportHTTP {
+		operation.Operation.Methods, operation.Operation.Paths = []string{"POST"}, []string{rule.GRPCMethod}
+		return operation, true
+	}
+
+	pattern, ok := routePattern(rule)
+	if !ok {
+		return operation, false
+	}
+	_, path, _ := strings.Cut(pattern, " ")
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		switch {
+		case strings.HasSuffix(segment, "...}"):
+			segments[i] = "{**}"
+		case strings.HasPrefix(segment, "{"):
+			segments[i] = "{*}"
+		}
+	}
+	operation.Operation.Methods, operation.Operation.Paths = []string{rule.HTTPMethod}, []string{strings.Join(segments, "/")}
+	return operation, true
+}
@@ -0,0 +1,33 @@
+package authzgen
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+// generateJSONFile writes the authorization rules as a JSON document for external policy engines.
+// Rules are expected sorted with SortRules so that the output can be committed and diffed.
+func generateJSONFile(plugin *protogen.Plugin, out outputPackage, rules []Rule) error {
+	content, err := MarshalRulesJSON(rules)
+	if err != nil {
+		return err
+	}
+
+	gen := out.newFile(plugin, "authz_rules.json")
+	_, err = gen.Write(content)
+	return err
+}
+
+// MarshalRulesJSON returns the JSON document of the json target listing rules, indented and ending with a newline.
+func MarshalRulesJSON(rules []Rule) ([]byte, error) {
+	document := struct {
+		Rules []Rule `json:"rules"`
+	}{Rules: rules}
+	content, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal authz rules: %w", err)
+	}
+	return append(content, '\n'), nil
+}
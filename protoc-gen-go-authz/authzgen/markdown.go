@@ -0,0 +1,198 @@
+package authzgen
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// generateMarkdownFiles writes, next to the pb files of every proto package declaring rules, an AUTHZ.md
+// document listing the endpoints of each service along with the permissions they require, for readers of the
+// API rather than of the protos. Methods inheriting the file or service default are marked as such.
+func generateMarkdownFiles(plugin *protogen.Plugin, rules []Rule) {
+	methodRules := make(map[protoreflect.FullName][]Rule)
+	for _, rule := range rules {
+		method := rule.FullMethodName()
+		methodRules[method] = append(methodRules[method], rule)
+	}
+
+	// Services are grouped by proto package, sorted by name rather than in the order of the proto files so that the
+	// documents do not change with the order protoc is given the files in
+	var packages []protoreflect.FullName
+	packageDirs := make(map[protoreflect.FullName]string)
+	packageServices := make(map[protoreflect.FullName][]*protogen.Service)
+	for _, file := range plugin.Files {
+		if !file.Generate {
+			continue
+		}
+		for _, service := range file.Services {
+			if !serviceHasRules(service, methodRules) {
+				continue
+			}
+			protoPackage := file.Desc.Package()
+			if _, ok := packageDirs[protoPackage]; !ok {
+				packages = append(packages, protoPackage)
+				packageDirs[protoPackage] = path.Dir(file.GeneratedFilenamePrefix)
+			}
+			packageServices[protoPackage] = append(packageServices[protoPackage], service)
+		}
+	}
+	slices.Sort(packages)
+	for _, services := range packageServices {
+		slices.SortFunc(services, func(a, b *protogen.Service) int {
+			return strings.Compare(string(a.Desc.Name()), string(b.Desc.Name()))
+		})
+	}
+
+	for _, protoPackage := range packages {
+		gen := plugin.NewGeneratedFile(path.Join(packageDirs[protoPackage], "AUTHZ.md"), "")
+		gen.P("<!-- Code generated by protoc-gen-go-authz. DO NOT EDIT. -->")
+		gen.P()
+		gen.P("# Authorization of ", protoPackage)
+		gen.P()
+		gen.P("Permissions separated by `or` are alternatives, holding any one of them grants access.")
+
+		for _, service := range packageServices[protoPackage] {
+			gen.P()
+			gen.P("## ", service.Desc.Name())
+			gen.P()
+
+			// The defaults are the requirements of the methods inheriting them, methods declaring their own
+			// authz option do not tell them
+			defaults := make(map[Level]string)
+			for _, method := range service.Methods {
+				for _, rule := range methodRules[method.Desc.FullName()] {
+					if rule.Level != LevelMethod {
+						defaults[rule.Level] = markdownRequirement(rule)
+					}
+				}
+			}
+			if requirement, ok := defaults[LevelService]; ok {
+				gen.P("Service default, applied to the methods without authz option of their own: ", requirement, ".")
+				gen.P()
+			}
+			if requirement, ok := defaults[LevelFile]; ok {
+				gen.P("File default, applied to the methods without authz option of their own or of the service: ", requirement, ".")
+				gen.P()
+			}
+
+			gen.P("| Method | HTTP | Permissions | Public | Description |")
+			gen.P("| --- | --- | --- | --- | --- |")
+			for _, method := range service.Methods {
+				for _, rule := range methodRules[method.Desc.FullName()] {
+					route := "gRPC only"
+					if rule.Transport == TransportHTTP {
+						route = "`" + rule.HTTPMethod + " " + rule.HTTPPath + "`"
+					}
+					requirement := markdownRequirement(rule)
+					if rule.Level != LevelMethod {
+						requirement += " (" + string(rule.Level) + " default)"
+					}
+					public := "no"
+					if rule.NoAuthRequired {
+						public = "yes"
+					}
+					name := string(method.Desc.Name())
+					if rule.Deprecated {
+						name = "~~" + name + "~~"
+					}
+					gen.P("| ", name, " | ", route, " | ", requirement, " | ", public, " | ", markdownDescription(rule.Description), " |")
+				}
+			}
+		}
+	}
+}
+
+// serviceHasRules reports whether some methods of service have rules.
+func serviceHasRules(service *protogen.Service, methodRules map[protoreflect.FullName][]Rule) bool {
+	for _, method := range service.Methods {
+		if len(methodRules[method.Desc.FullName()]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// markdownRequirement describes what a rule requires from the caller, e.g. `read:all` or `admin:all`.
+func markdownRequirement(rule Rule) string {
+	if rule.NoAuthRequired {
+		return "none"
+	}
+
+	var parts []string
+	switch {
+	case rule.Require != nil:
+		parts = append(parts, markdownExpr(*rule.Require))
+	case len(rule.Permissions) > 0 || len(rule.AllowedScopes) > 0:
+		var alternatives []string
+		if len(rule.Permissions) > 0 {
+			alternatives = append(alternatives, markdownPermissions(rule.Permissions, " or "))
+		}
+		if len(rule.AllowedScopes) > 0 {
+			alternatives = append(alternatives, "scope "+markdownPermissions(rule.AllowedScopes, " or "))
+		}
+		parts = append(parts, strings.Join(alternatives, " or "))
+	default:
+		parts = append(parts, "authentication")
+	}
+	if len(rule.Roles) > 0 {
+		parts = append(parts, "role "+markdownPermissions(rule.Roles, " or "))
+	}
+	if denied := rule.DeniedPermissions(); len(denied) > 0 {
+		parts = append(parts, "denied to "+markdownPermissions(denied, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// markdownExpr describes a permission requirement, clauses combining several permissions being parenthesized
+// when combined with other clauses, e.g. (`read:all` or `read:test`) and `write:test`.
+func markdownExpr(expr PermissionExpr) string {
+	var clauses []string
+	if len(expr.AnyOf) > 0 {
+		clauses = append(clauses, markdownPermissions(expr.AnyOf, " or "))
+	}
+	if len(expr.AllOf) > 0 {
+		clauses = append(clauses, markdownPermissions(expr.AllOf, " and "))
+	}
+	for _, nested := range expr.All {
+		clauses = append(clauses, markdownExpr(nested))
+	}
+	if len(expr.Any) > 0 {
+		alternatives := make([]string, 0, len(expr.Any))
+		for _, nested := range expr.Any {
+			alternatives = append(alternatives, markdownClause(markdownExpr(nested), len(expr.Any)))
+		}
+		clauses = append(clauses, strings.Join(alternatives, " or "))
+	}
+	for i, clause := range clauses {
+		clauses[i] = markdownClause(clause, len(clauses))
+	}
+	return strings.Join(clauses, " and ")
+}
+
+// markdownClause parenthesizes a clause combining several permissions when it is one of several clauses.
+func markdownClause(clause string, clauses int) string {
+	if clauses > 1 && strings.Contains(clause, "` ") {
+		return "(" + clause + ")"
+	}
+	return clause
+}
+
+// markdownPermissions formats permissions as code spans joined by sep.
+func markdownPermissions(permissions []string, sep string) string {
+	formatted := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		formatted = append(formatted, fmt.Sprintf("`%s`", permission))
+	}
+	return strings.Join(formatted, sep)
+}
+
+// markdownDescription flattens the description of a method into a table cell.
+func markdownDescription(description string) string {
+	description = strings.Join(strings.Fields(description), " ")
+	return strings.ReplaceAll(description, "|", `\|`)
+}
@@ -0,0 +1,183 @@
+package authzgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+// openAPIMethods are the HTTP methods OpenAPI can describe an operation for.
+var openAPIMethods = map[string]bool{
+	"GET": true, "PUT": true, "POST": true, "DELETE": true, "OPTIONS": true, "HEAD": true, "PATCH": true, "TRACE": true,
+}
+
+// openAPIPath converts a path template to the OpenAPI form, e.g. /v1/{name=projects/*} becomes /v1/{name}.
+func openAPIPath(path string) string {
+	return templateVariableRegex.ReplaceAllString(path, "{$1}")
+}
+
+// securityAlternatives returns the permission sets satisfying a rule, any of which is enough.
+// The allowed scopes are alternatives like the permissions, the targets not telling them apart.
+// An empty set means being authenticated is enough.
+func securityAlternatives(rule Rule) [][]string {
+	if rule.Require != nil {
+		return exprAlternatives(*rule.Require)
+	}
+	grants := slices.Concat(rule.Permissions, rule.AllowedScopes)
+	if len(grants) == 0 {
+		return [][]string{{}}
+	}
+
+	alternatives := make([][]string, 0, len(grants))
+	for _, permission := range grants {
+		alternatives = append(alternatives, []string{permission})
+	}
+	return alternatives
+}
+
+// exprAlternatives returns the expression in disjunctive normal form: permission sets, any of which satisfies it.
+func exprAlternatives(e PermissionExpr) [][]string {
+	// Every non-empty clause must hold, so the alternatives of the clauses are combined
+	result := [][]string{{}}
+	combine := func(clause [][]string) {
+		var combined [][]string
+		for _, left := range result {
+			for _, right := range clause {
+				set := append(append([]string{}, left...), right...)
+				combined = append(combined, set)
+			}
+		}
+		result = combined
+	}
+
+	if len(e.AnyOf) > 0 {
+		clause := make([][]string, 0, len(e.AnyOf))
+		for _, permission := range e.AnyOf {
+			clause = append(clause, []string{permission})
+		}
+		combine(clause)
+	}
+	if len(e.AllOf) > 0 {
+		combine([][]string{e.AllOf})
+	}
+	for _, nested := range e.All {
+		combine(exprAlternatives(nested))
+	}
+	if len(e.Any) > 0 {
+		var clause [][]string
+		for _, nested := range e.Any {
+			clause = append(clause, exprAlternatives(nested)...)
+		}
+		combine(clause)
+	}
+
+	return result
+}
+
+// openAPIOperation is the part of an OpenAPI operation generated from a rule.
+type openAPIOperation struct {
+	path   string         // OpenAPI path, e.g. /v1/{name}
+	method string         // lower-cased HTTP method
+	fields map[string]any // security requirement, deprecation and description
+}
+
+// openAPIOperations returns the operations of the HTTP rules, in the order of the rules. Permissions are listed as
+// the scopes of securityScheme, operations without authentication get an empty security.
+func openAPIOperations(rules []Rule, securityScheme string, logger *slog.Logger) []openAPIOperation {
+	var operations []openAPIOperation
+	for _, rule := range rules {
+		// Rules keyed by gRPC path are not HTTP operations
+		if rule.Transport != TransportHTTP {
+			continue
+		}
+		method := strings.ToUpper(rule.HTTPMethod)
+		if !openAPIMethods[method] {
+			logger.Warn(fmt.Sprintf("skipping OpenAPI operation %s %s: method not supported by OpenAPI", method, rule.HTTPPath))
+			continue
+		}
+
+		security := []map[string][]string{}
+		if !rule.NoAuthRequired {
+			for _, scopes := range securityAlternatives(rule) {
+				security = append(security, map[string][]string{securityScheme: scopes})
+			}
+		}
+
+		operation := map[string]any{"security": security}
+		if rule.Deprecated {
+			operation["deprecated"] = true
+		}
+		if rule.Description != "" {
+			operation["description"] = rule.Description
+		}
+		operations = append(operations, openAPIOperation{path: openAPIPath(rule.HTTPPath), method: strings.ToLower(method), fields: operation})
+	}
+	return operations
+}
+
+// generateOpenAPIFile writes a partial OpenAPI v3 document declaring the security requirements of every operation.
+func generateOpenAPIFile(plugin *protogen.Plugin, out outputPackage, rules []Rule, securityScheme string, logger *slog.Logger) error {
+	paths := make(map[string]map[string]any)
+	for _, operation := range openAPIOperations(rules, securityScheme, logger) {
+		if paths[operation.path] == nil {
+			paths[operation.path] = make(map[string]any)
+		}
+		paths[operation.path][operation.method] = operation.fields
+	}
+
+	document := map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]string{
+			"title":   "Authorization requirements",
+			"version": "1.0.0",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"securitySchemes": map[string]any{
+				securityScheme: map[string]string{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+	content, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal OpenAPI document: %w", err)
+	}
+
+	gen := out.newFile(plugin, "authz_openapi.json")
+	_, err = gen.Write(append(content, '\n'))
+	return err
+}
+
+// generateOpenAPIOverlayFile writes an OpenAPI Overlay document updating the security requirements of every
+// operation, to be applied to the spec generated from the same protos, e.g. by protoc-gen-openapiv2. The security
+// scheme is expected to be declared by the spec, the overlay only referencing it.
+func generateOpenAPIOverlayFile(plugin *protogen.Plugin, out outputPackage, rules []Rule, securityScheme string, logger *slog.Logger) error {
+	actions := []map[string]any{}
+	for _, operation := range openAPIOperations(rules, securityScheme, logger) {
+		actions = append(actions, map[string]any{
+			"target": "$.paths['" + strings.ReplaceAll(operation.path, "'", `\'`) + "']." + operation.method,
+			"update": operation.fields,
+		})
+	}
+
+	document := map[string]any{
+		"overlay": "1.0.0",
+		"info": map[string]string{
+			"title":   "Authorization requirements",
+			"version": "1.0.0",
+		},
+		"actions": actions,
+	}
+	content, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal OpenAPI overlay: %w", err)
+	}
+
+	gen := out.newFile(plugin, "authz_openapi_overlay.json")
+	_, err = gen.Write(append(content, '\n'))
+	return err
+}
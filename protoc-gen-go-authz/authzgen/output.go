@@ -0,0 +1,61 @@
+package authzgen
+
+import (
+	"fmt"
+	"go/token"
+	"path"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+// Default location of the outputs generated once per run.
+const (
+	defaultOutputDir       = "authzmap"
+	defaultOutputGoPackage = "github.com/aymenworks/public-medium-protocgen/gen/authzmap"
+)
+
+// outputPackage is the directory and Go package the outputs generated once per run are written to, the authz map
+// and the files of the targets, unlike the registry files written next to the pb files.
+type outputPackage struct {
+	dir        string // relative to the output directory, e.g. authzmap
+	importPath protogen.GoImportPath
+	name       protogen.GoPackageName
+}
+
+// newOutputPackage returns the output package writing to dir, relative to the output directory, the Go files
+// belonging to goPackage, an import path optionally followed by ;name as in the go_package file option, e.g.
+// example.com/internal/authz;authz. The package name defaults to the last element of the import path.
+func newOutputPackage(dir, goPackage string) (outputPackage, error) {
+	dir = path.Clean(dir)
+	if path.IsAbs(dir) || dir == ".." || strings.HasPrefix(dir, "../") {
+		return outputPackage{}, fmt.Errorf("output directory %q must be relative to the output directory, without ..", dir)
+	}
+
+	importPath, name, ok := strings.Cut(goPackage, ";")
+	if !ok {
+		name = path.Base(importPath)
+	}
+	if importPath == "" {
+		return outputPackage{}, fmt.Errorf("output Go package %q has no import path", goPackage)
+	}
+	if !token.IsIdentifier(name) {
+		return outputPackage{}, fmt.Errorf("output Go package name %q is not a valid identifier, set it with %s;name", name, importPath)
+	}
+	return outputPackage{dir: dir, importPath: protogen.GoImportPath(importPath), name: protogen.GoPackageName(name)}, nil
+}
+
+// newFile creates the output file name in the output directory.
+func (o outputPackage) newFile(plugin *protogen.Plugin, name string) *protogen.GeneratedFile {
+	return plugin.NewGeneratedFile(path.Join(o.dir, name), o.importPath)
+}
+
+// newGoFile creates the Go output file name in the output directory, starting with its header and package clause.
+func (o outputPackage) newGoFile(plugin *protogen.Plugin, name string) *protogen.GeneratedFile {
+	gen := o.newFile(plugin, name)
+	gen.P("// Code generated by protoc-gen-go-authz. DO NOT EDIT.")
+	gen.P()
+	gen.P("package ", o.name)
+	gen.P()
+	return gen
+}
@@ -0,0 +1,121 @@
+package authzgen
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Overlap is a pair of HTTP rules of the same verb whose path templates match common paths,
+// e.g. GET /v1/users/{id} and GET /v1/users/me.
+type Overlap struct {
+	A, B Rule
+}
+
+// SameRequirements reports whether both rules of the overlap grant access to the same callers.
+func (o Overlap) SameRequirements() bool {
+	return o.A.NoAuthRequired == o.B.NoAuthRequired &&
+		slices.Equal(o.A.Permissions, o.B.Permissions) &&
+		slices.Equal(o.A.DeniedPermissions(), o.B.DeniedPermissions()) &&
+		slices.Equal(o.A.Roles, o.B.Roles) &&
+		slices.Equal(o.A.AllowedScopes, o.B.AllowedScopes) &&
+		reflect.DeepEqual(o.A.Require, o.B.Require)
+}
+
+// Ambiguous reports whether neither route of the overlap is more specific than the other, matching a subset of the
+// paths of the other, e.g. GET /v1/{a}/x and GET /v1/x/{b} which both match /v1/x/x. The route trie then applies the
+// rule whose literal segment comes first to the common paths, which the authors of the routes may not expect.
+func (o Overlap) Ambiguous() bool {
+	segmentsA, _ := templateSegments(o.A.HTTPPath)
+	segmentsB, _ := templateSegments(o.B.HTTPPath)
+	return segmentsCover(segmentsA, segmentsB) == segmentsCover(segmentsB, segmentsA)
+}
+
+// FindOverlaps returns the pairs of HTTP rules whose path templates differ but match common paths.
+// Templates differing by the names of their variables only are duplicate routes, reported by ValidateRules instead,
+// while the ones spelling the same segments differently, e.g. /v1/{name=projects/*} and /v1/projects/{id}, overlap.
+func FindOverlaps(rules []Rule) []Overlap {
+	// The routes are compared pairwise, their segments are computed once rather than for every pair
+	type route struct {
+		rule     Rule
+		key      string
+		segments []string
+		verb     string
+	}
+	routes := make([]route, 0, len(rules))
+	for _, rule := range rules {
+		if rule.Transport != TransportHTTP {
+			continue
+		}
+		segments, verb := templateSegments(rule.HTTPPath)
+		routes = append(routes, route{rule: rule, key: routeKey(rule), segments: segments, verb: verb})
+	}
+
+	var overlaps []Overlap
+	for i, a := range routes {
+		for _, b := range routes[i+1:] {
+			if !strings.EqualFold(a.rule.HTTPMethod, b.rule.HTTPMethod) || a.key == b.key || a.verb != b.verb {
+				continue
+			}
+			if !segmentsOverlap(a.segments, b.segments) {
+				continue
+			}
+			overlaps = append(overlaps, Overlap{A: a.rule, B: b.rule})
+		}
+	}
+	return overlaps
+}
+
+// templateSegments returns the segments of a path template, variables being replaced by their pattern,
+// * for a single segment and ** for any number of them, e.g. /v1/{name=projects/*}/users/{id} is v1 projects * users *.
+// The custom verb, such as :cancel, is returned apart as it is matched separately from the segments.
+func templateSegments(template string) (segments []string, verb string) {
+	expanded := templateVariableRegex.ReplaceAllStringFunc(template, func(variable string) string {
+		if pattern := templateVariableRegex.FindStringSubmatch(variable)[2]; pattern != "" {
+			return pattern
+		}
+		return "*"
+	})
+	segments = strings.Split(strings.Trim(expanded, "/"), "/")
+	last := segments[len(segments)-1]
+	if i := strings.LastIndex(last, ":"); i >= 0 {
+		segments[len(segments)-1], verb = last[:i], last[i+1:]
+	}
+	return segments, verb
+}
+
+// segmentsOverlap reports whether two template segment lists match at least one common path.
+// A ** segment, which is always the last one, matches whatever follows.
+func segmentsOverlap(a, b []string) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] == "**" || b[i] == "**" {
+			return true
+		}
+		if !segmentOverlaps(a[i], b[i]) {
+			return false
+		}
+	}
+	return len(a) == len(b) ||
+		(len(a) == len(b)+1 && a[len(a)-1] == "**") ||
+		(len(b) == len(a)+1 && b[len(b)-1] == "**")
+}
+
+// segmentOverlaps reports whether two template segments match a common path segment.
+func segmentOverlaps(a, b string) bool {
+	return a == b || a == "*" || b == "*"
+}
+
+// segmentsCover reports whether the template segment list general matches every path specific matches.
+func segmentsCover(general, specific []string) bool {
+	for i := 0; i < len(general) && i < len(specific); i++ {
+		switch {
+		case general[i] == "**":
+			return true
+		case specific[i] == "**":
+			return false
+		case general[i] != "*" && general[i] != specific[i]:
+			return false
+		}
+	}
+	return len(general) == len(specific) || (len(general) == len(specific)+1 && general[len(general)-1] == "**")
+}
@@ -0,0 +1,58 @@
+package authzgen
+
+import (
+	"log/slog"
+	"testing"
+)
+
+func TestOverlapAmbiguous(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"/v1/users/{id}", "/v1/users/me", false},
+		{"/v1/users/{path=**}", "/v1/users/{id}", false},
+		{"/v1/{a}/x", "/v1/x/{b}", true},
+		{"/v1/{name=projects/*}", "/v1/projects/{id}", true},
+		{"/v1/{a}/{b=**}", "/v1/x/{c}", false},
+		{"/v1/{a}/{b=**}", "/v1/{c=**}", false},
+		{"/v1/{a}/x/{b=**}", "/v1/x/{c}/y", true},
+	}
+	for _, tt := range tests {
+		a := Rule{HTTPMethod: "GET", HTTPPath: tt.a, Transport: TransportHTTP}
+		b := Rule{HTTPMethod: "GET", HTTPPath: tt.b, Transport: TransportHTTP}
+		overlaps := FindOverlaps([]Rule{a, b})
+		if len(overlaps) != 1 {
+			t.Fatalf("FindOverlaps(%s, %s) = %v, want one overlap", tt.a, tt.b, overlaps)
+		}
+		if got := overlaps[0].Ambiguous(); got != tt.want {
+			t.Errorf("Overlap{%s, %s}.Ambiguous() = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestReportOverlaps(t *testing.T) {
+	route := func(path string, permissions ...string) Rule {
+		return Rule{HTTPMethod: "GET", HTTPPath: path, Transport: TransportHTTP, Permissions: permissions}
+	}
+	tests := []struct {
+		name    string
+		rules   []Rule
+		strict  bool
+		wantErr bool
+	}{
+		{"same permissions", []Rule{route("/v1/users/{id}", "read"), route("/v1/users/me", "read")}, true, false},
+		{"ambiguous with the same permissions", []Rule{route("/v1/{a}/x", "read"), route("/v1/x/{b}", "read")}, true, false},
+		{"different permissions", []Rule{route("/v1/users/{id}", "read"), route("/v1/users/me", "self")}, false, false},
+		{"different permissions in strict mode", []Rule{route("/v1/users/{id}", "read"), route("/v1/users/me", "self")}, true, true},
+		{"ambiguous with different permissions", []Rule{route("/v1/{a}/x", "read"), route("/v1/x/{b}", "write")}, false, false},
+		{"ambiguous with different permissions in strict mode", []Rule{route("/v1/{a}/x", "read"), route("/v1/x/{b}", "write")}, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := reportOverlaps(tt.rules, tt.strict, slog.New(slog.DiscardHandler)); (err != nil) != tt.wantErr {
+				t.Errorf("reportOverlaps(strict=%v) error = %v, want error %v", tt.strict, err, tt.wantErr)
+			}
+		})
+	}
+}
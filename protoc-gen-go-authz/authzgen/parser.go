@@ -0,0 +1,2240 @@
+// Package authzgen extracts the authorization rules declared with authz options in proto files
+// and generates the authz map and the outputs of the targets from them.
+//
+// It is the parser and generator behind protoc-gen-go-authz, exposed so that other tools, such as linters,
+// can consume the rules without running the plugin:
+//
+//	rules, warnings, err := authzgen.ParseFile(file)
+//
+// or run the generation with their own options:
+//
+//	err := authzgen.Generate(plugin, authzgen.DefaultOptions())
+package authzgen
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// errAuthzExtensionNotLinked is returned when the authz extension type is not registered in this binary.
+var errAuthzExtensionNotLinked = errors.New("authz extension type not linked")
+
+// errAuthzExtensionNotDeclared is returned when none of the request files declares the authz extension.
+var errAuthzExtensionNotDeclared = errors.New("authz extension not declared")
+
+// errAuthzNotUninterpreted is returned when the options carry no uninterpreted authz option.
+var errAuthzNotUninterpreted = errors.New("authz option not uninterpreted")
+
+// errNoAuthzOption is returned when a method, service or file has no authz option.
+var errNoAuthzOption = errors.New("authz options not found")
+
+// errInvalidPermission is returned when a permission does not match the permission pattern.
+var errInvalidPermission = errors.New("invalid permission")
+
+// errInvalidRole is returned when a role name is rejected.
+var errInvalidRole = errors.New("invalid role")
+
+// errInvalidAuthzOption is returned when an authz option is declared in a way the plugin rejects.
+var errInvalidAuthzOption = errors.New("invalid authz option")
+
+// DefaultPermissionPattern is the format permissions must follow by default, e.g. user:read.
+const DefaultPermissionPattern = `^[a-z][a-z0-9_]*(:[a-z][a-z0-9_]*)+$`
+
+// errNoHTTPAnnotation is returned when a method has no google.api.http annotation.
+var errNoHTTPAnnotation = errors.New("no HTTP annotation found")
+
+// authzOptions holds the values of an authz option.
+type authzOptions struct {
+	Permissions       []string
+	Denied            []string // permissions declared with the DENY effect
+	Roles             []string // roles the caller must hold one of, on top of the permissions
+	AllowedScopes     []string // OAuth scopes granting access like the permissions, kept apart from them
+	NoAuthRequired    bool
+	NoAuthRequiredSet bool // whether no_auth_required was set explicitly, to false included
+	Require           *PermissionExpr
+	// Strategy applies when the option is inherited as a default and a more specific option lists permissions
+	Strategy authzStrategy
+	// EnvOverrides are the options replacing this one in an environment, e.g. staging
+	EnvOverrides map[string]authzOptions
+}
+
+// authzStrategy defines how the permissions of a default option combine with more specific options.
+type authzStrategy string
+
+const (
+	// authzStrategyReplace makes the more specific option override the default entirely.
+	authzStrategyReplace authzStrategy = "replace"
+	// authzStrategyMerge appends the permissions of the more specific option to the default ones.
+	authzStrategyMerge authzStrategy = "merge"
+)
+
+// authzStrategyFromEnum maps the defaults_strategy enum value names to strategies.
+var authzStrategyFromEnum = map[string]authzStrategy{
+	"DEFAULTS_STRATEGY_UNSPECIFIED": authzStrategyReplace,
+	"DEFAULTS_STRATEGY_REPLACE":     authzStrategyReplace,
+	"DEFAULTS_STRATEGY_MERGE":       authzStrategyMerge,
+}
+
+// descriptorOptions is implemented by the descriptor options messages carrying authz extensions.
+type descriptorOptions interface {
+	proto.Message
+	GetUninterpretedOption() []*descriptorpb.UninterpretedOption
+}
+
+// isEmpty reports whether the option neither requires permissions or roles nor disables authentication.
+func (o authzOptions) isEmpty() bool {
+	return !o.hasRequirements() && !o.NoAuthRequired
+}
+
+// hasRequirements reports whether the option lists permissions, scopes or roles, or declares a requirement.
+func (o authzOptions) hasRequirements() bool {
+	return len(o.Permissions) > 0 || len(o.Denied) > 0 || len(o.Roles) > 0 || len(o.AllowedScopes) > 0 || o.Require != nil
+}
+
+// permissionEffects returns the listed permissions along with their effect, or nil when none is denied.
+func (o authzOptions) permissionEffects() []Permission {
+	if len(o.Denied) == 0 {
+		return nil
+	}
+
+	effects := make([]Permission, 0, len(o.Permissions)+len(o.Denied))
+	for _, permission := range o.Permissions {
+		effects = append(effects, Permission{Name: permission, Effect: EffectAllow})
+	}
+	for _, permission := range o.Denied {
+		effects = append(effects, Permission{Name: permission, Effect: EffectDeny})
+	}
+	return effects
+}
+
+// addPermissions adds permissions to the allowed or denied ones depending on their effect.
+func (o *authzOptions) addPermissions(permissions []Permission) {
+	for _, permission := range permissions {
+		if permission.Effect == EffectDeny {
+			o.Denied = append(o.Denied, permission.Name)
+		} else {
+			o.Permissions = append(o.Permissions, permission.Name)
+		}
+	}
+}
+
+// effectFromEnum maps the effect enum value names to effects, the short names being accepted for custom schemas.
+var effectFromEnum = map[string]string{
+	"EFFECT_UNSPECIFIED": EffectAllow,
+	"EFFECT_ALLOW":       EffectAllow,
+	"EFFECT_DENY":        EffectDeny,
+	"ALLOW":              EffectAllow,
+	"DENY":               EffectDeny,
+}
+
+// allPermissions returns the union of the listed permissions and the ones referenced by the requirement.
+func (o authzOptions) allPermissions() []string {
+	permissions := make([]string, 0, len(o.Permissions))
+	seen := make(map[string]bool, len(o.Permissions))
+	add := func(permission string) {
+		if !seen[permission] {
+			seen[permission] = true
+			permissions = append(permissions, permission)
+		}
+	}
+
+	for _, permission := range o.Permissions {
+		add(permission)
+	}
+	if o.Require != nil {
+		o.Require.Walk(add)
+	}
+
+	return permissions
+}
+
+// envOverrides returns the requirements of the environment overrides of the option, nil when there is none.
+func (o authzOptions) envOverrides() map[string]RuleOverride {
+	if len(o.EnvOverrides) == 0 {
+		return nil
+	}
+	overrides := make(map[string]RuleOverride, len(o.EnvOverrides))
+	for env, override := range o.EnvOverrides {
+		overrides[env] = RuleOverride{
+			Permissions:       override.allPermissions(),
+			PermissionEffects: override.permissionEffects(),
+			Roles:             override.Roles,
+			AllowedScopes:     override.AllowedScopes,
+			Require:           override.Require,
+			NoAuthRequired:    override.NoAuthRequired,
+		}
+	}
+	return overrides
+}
+
+// checkEnvOverrides rejects nested overrides and the overrides neither requiring permissions or roles nor
+// declaring no_auth_required, which would be read as "any authenticated caller".
+func (o authzOptions) checkEnvOverrides() error {
+	for _, env := range slices.Sorted(maps.Keys(o.EnvOverrides)) {
+		override := o.EnvOverrides[env]
+		if env == "" {
+			return fmt.Errorf("%w: env_overrides with an empty environment name", errInvalidAuthzOption)
+		}
+		if len(override.EnvOverrides) > 0 {
+			return fmt.Errorf("%w: env_overrides of environment %s declares env_overrides", errInvalidAuthzOption, env)
+		}
+		if override.isEmpty() && !override.NoAuthRequiredSet {
+			return fmt.Errorf("%w: env_overrides of environment %s declares neither permissions nor no_auth_required", errInvalidAuthzOption, env)
+		}
+	}
+	return nil
+}
+
+// authzDefaults is an authz option inherited by methods from their enclosing file or service.
+type authzDefaults struct {
+	Options authzOptions
+	Level   Level
+}
+
+// applyDefaults combines the options declared at level with the inherited defaults.
+// The most specific non-empty option wins, unless the defaults use the merge strategy in which case
+// its permissions are appended to the inherited ones. Disabling authentication always drops inherited permissions.
+func applyDefaults(defaults *authzDefaults, options authzOptions, level Level) (authzOptions, Level) {
+	switch {
+	case defaults == nil:
+		return options, level
+	case options.isEmpty():
+		return defaults.Options, defaults.Level
+	case options.NoAuthRequired || defaults.Options.Strategy != authzStrategyMerge:
+		return options, level
+	}
+
+	merged := options
+	merged.Permissions = mergePermissions(defaults.Options.Permissions, options.Permissions)
+	merged.Denied = mergePermissions(defaults.Options.Denied, options.Denied)
+	merged.Roles = mergePermissions(defaults.Options.Roles, options.Roles)
+	merged.AllowedScopes = mergePermissions(defaults.Options.AllowedScopes, options.AllowedScopes)
+
+	if defaults.Options.Require != nil && options.Require != nil {
+		merged.Require = &PermissionExpr{All: []PermissionExpr{*defaults.Options.Require, *options.Require}}
+	} else if options.Require == nil {
+		merged.Require = defaults.Options.Require
+	}
+
+	return merged, level
+}
+
+// mergePermissions returns the union of two permission lists, in order.
+func mergePermissions(inherited, declared []string) []string {
+	merged := make([]string, 0, len(inherited)+len(declared))
+	seen := make(map[string]bool, cap(merged))
+	for _, permissions := range [][]string{inherited, declared} {
+		for _, permission := range permissions {
+			if !seen[permission] {
+				seen[permission] = true
+				merged = append(merged, permission)
+			}
+		}
+	}
+	return merged
+}
+
+// ExtensionNames holds the full names of the authz extensions declared on each kind of options.
+type ExtensionNames struct {
+	Method  protoreflect.FullName
+	Service protoreflect.FullName
+	File    protoreflect.FullName
+}
+
+// DefaultExtensionNames are the full names of the authz extensions declared in proto/v1/option.proto.
+var DefaultExtensionNames = ExtensionNames{
+	Method:  "proto.v1.authz",
+	Service: "proto.v1.service_authz",
+	File:    "proto.v1.file_authz",
+}
+
+// DefaultExtensionNumber is the field number of the authz extensions declared in proto/v1/option.proto.
+const DefaultExtensionNumber protoreflect.FieldNumber = 50001
+
+// DefaultStrictExemptServices are the well-known gRPC services exempt from the strict mode by default.
+var DefaultStrictExemptServices = []protoreflect.FullName{
+	"grpc.health.v1.Health",
+	"grpc.reflection.v1.ServerReflection",
+	"grpc.reflection.v1alpha.ServerReflection",
+}
+
+// Parser handles parsing of authz options from proto files.
+type Parser struct {
+	extensionNames       ExtensionNames
+	authzExtensionNumber protoreflect.FieldNumber
+	extensionTypes       *protoregistry.Types
+
+	// GRPCFallback makes methods without HTTP annotation produce a rule keyed by their gRPC path.
+	GRPCFallback bool
+
+	// PermissionPattern is the format every permission must match, nil disables the check.
+	PermissionPattern *regexp.Regexp
+
+	// Logger receives the debug diagnostics of the parser, it discards them by default.
+	Logger *slog.Logger
+
+	// Strict makes methods without authz option, neither declared nor inherited, fail the parsing instead of being skipped.
+	// no_auth_required must be set explicitly on public methods.
+	Strict bool
+
+	// AllowEmptyPermissions accepts methods resolving to no permission without declaring no_auth_required,
+	// for "authenticated but unrestricted" semantics. Otherwise they fail the parsing.
+	AllowEmptyPermissions bool
+
+	// NoAuthConflictWarning downgrades methods declaring permissions or roles along with no_auth_required
+	// from an error to a warning, the method being public, to migrate legacy protos.
+	NoAuthConflictWarning bool
+
+	// StrictExemptServices are the full names of the services whose methods are still skipped in strict mode.
+	StrictExemptServices []protoreflect.FullName
+
+	// SourceFallback reads the method, service and file authz options from the proto source, relative to the working
+	// directory, when they are set but the authz extension is declared in none of the files given to NewParser.
+	// Without it, the default, such options fail the parsing and no proto source is ever read.
+	SourceFallback bool
+
+	// warnings holds the diagnostics that do not fail the generation, see Warnings.
+	warnings []Warning
+
+	// sourcePatterns match the method, service and file authz options in the proto source, keyed by extension name.
+	sourcePatterns map[protoreflect.FullName]sourceOptionPatterns
+
+	// fileCache holds the services located by the source scanner in each proto file, keyed by path,
+	// so that each file is read and scanned at most once per run.
+	fileCache map[string]*scannedFile
+}
+
+// NewParser creates a new parser for the authz extensions named extensionNames,
+// all declared with extensionNumber on their respective options.
+// The extensions declared in files are used to decode the authz option when its Go type is not linked in.
+func NewParser(files []*protogen.File, extensionNames ExtensionNames, extensionNumber protoreflect.FieldNumber) *Parser {
+	p := &Parser{
+		extensionNames:       extensionNames,
+		authzExtensionNumber: extensionNumber,
+		extensionTypes:       new(protoregistry.Types),
+		GRPCFallback:         true,
+		PermissionPattern:    regexp.MustCompile(DefaultPermissionPattern),
+		Logger:               slog.New(slog.DiscardHandler),
+		StrictExemptServices: DefaultStrictExemptServices,
+		sourcePatterns: map[protoreflect.FullName]sourceOptionPatterns{
+			extensionNames.Method:  newSourceOptionPatterns(extensionNames.Method),
+			extensionNames.Service: newSourceOptionPatterns(extensionNames.Service),
+			extensionNames.File:    newSourceOptionPatterns(extensionNames.File),
+		},
+	}
+	for _, file := range files {
+		p.registerExtensions(file.Desc.Extensions(), file.Desc.Messages())
+	}
+
+	return p
+}
+
+// ParseFile extracts all authz rules from a proto file using the default extensions and settings, along with the
+// warnings of the parser, such as skipped methods. The extensions are looked up in the file and the files it imports,
+// transitively.
+func ParseFile(file *protogen.File) ([]Rule, []Warning, error) {
+	p := NewParser(nil, DefaultExtensionNames, DefaultExtensionNumber)
+	p.registerFileExtensions(file.Desc, make(map[string]bool))
+	rules, err := p.ParseFile(file)
+	return rules, p.Warnings(), err
+}
+
+// registerFileExtensions registers dynamic types for the extensions of file and of every file it imports.
+func (p *Parser) registerFileExtensions(file protoreflect.FileDescriptor, seen map[string]bool) {
+	if seen[file.Path()] {
+		return
+	}
+	seen[file.Path()] = true
+
+	p.registerExtensions(file.Extensions(), file.Messages())
+	imports := file.Imports()
+	for i := range imports.Len() {
+		p.registerFileExtensions(imports.Get(i).FileDescriptor, seen)
+	}
+}
+
+// registerExtensions registers dynamic types for the given extensions and the ones nested in messages.
+func (p *Parser) registerExtensions(extensions protoreflect.ExtensionDescriptors, messages protoreflect.MessageDescriptors) {
+	for i := range extensions.Len() {
+		extension := extensions.Get(i)
+		if err := p.extensionTypes.RegisterExtension(dynamicpb.NewExtensionType(extension)); err != nil {
+			p.warn(warningAt(extension, "failed to register extension %s: %v", extension.FullName(), err))
+		}
+	}
+
+	for i := range messages.Len() {
+		message := messages.Get(i)
+		p.registerExtensions(message.Extensions(), message.Messages())
+	}
+}
+
+// ParseFile extracts all authz rules from a proto file.
+// Malformed authz options are reported as errors, all of them joined, along with the rules of the other methods.
+// Methods without authz option are skipped.
+func (p *Parser) ParseFile(file *protogen.File) ([]Rule, error) {
+	rules := make([]Rule, 0, len(file.Services))
+
+	// The file level option is the default of every service and method of the file.
+	// A malformed one is reported along with the errors of the methods, which are still parsed without it.
+	var errs []error
+	var fileDefaults *authzDefaults
+	options, err := p.extractFileAuthzOptions(file)
+	switch {
+	case err == nil && !options.isEmpty():
+		fileDefaults = &authzDefaults{Options: options, Level: LevelFile}
+	case err != nil && !errors.Is(err, errNoAuthzOption):
+		errs = append(errs, fmt.Errorf("file %s: %w", file.Desc.Path(), err))
+	}
+
+	for _, service := range file.Services {
+		p.debugf("service: %s", service.Desc.Name())
+		// The rules of the well-formed methods are kept so that callers get every error along with partial results
+		serviceRules, err := p.parseService(service, fileDefaults)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		rules = append(rules, serviceRules...)
+	}
+
+	// Wildcard permissions are expanded into the permissions they match across the file.
+	// The ones matching nothing are kept, and reported once per method.
+	expander := newWildcardExpander(rules)
+	unmatched := make(map[string]bool)
+	for i := range rules {
+		rules[i] = expander.expandRule(rules[i])
+		for _, permission := range rules[i].Permissions {
+			key := string(rules[i].FullMethodName()) + " " + permission
+			if isWildcardPermission(permission) && !unmatched[key] {
+				unmatched[key] = true
+				p.warn(Warning{
+					File:    rules[i].SourceFile,
+					Line:    rules[i].SourceLine,
+					Message: fmt.Sprintf("wildcard permission %s of method %s matches no known permission", permission, rules[i].FullMethodName()),
+				})
+			}
+		}
+	}
+
+	return rules, errors.Join(errs...)
+}
+
+// parseService extracts authz rules from all methods in a service.
+// fileDefaults, when set, applies to methods if neither they nor the service declare an authz option.
+func (p *Parser) parseService(service *protogen.Service, fileDefaults *authzDefaults) ([]Rule, error) {
+	rules := make([]Rule, 0, len(service.Methods))
+
+	// The service level option overrides, or merges into, the file default for methods without their own authz option.
+	// A malformed one is reported along with the errors of the methods, which are still parsed with the file default.
+	var errs []error
+	defaults := fileDefaults
+	options, err := p.extractServiceAuthzOptions(service)
+	switch {
+	case err == nil && !options.isEmpty():
+		serviceOptions, level := applyDefaults(fileDefaults, options, LevelService)
+		defaults = &authzDefaults{Options: serviceOptions, Level: level}
+	case err != nil && !errors.Is(err, errNoAuthzOption):
+		errs = append(errs, fmt.Errorf("service %s%s: %w", service.Desc.FullName(), at(service.Desc), err))
+	}
+
+	// Services without methods, e.g. placeholders, produce no rule, their authz option applying to nothing
+	if len(service.Methods) == 0 {
+		p.debugf("service %s has no method", service.Desc.FullName())
+		if err == nil && !options.isEmpty() {
+			p.warn(warningAt(service.Desc, "service %s declares an authz option but no method", service.Desc.FullName()))
+		}
+	}
+
+	for _, method := range service.Methods {
+		p.debugf("method: %s", method.Desc.Name())
+		methodRules, err := p.parseMethod(method, defaults)
+		// Methods without authz option, or without HTTP annotation when there is no gRPC fallback, are legitimately skipped
+		if errors.Is(err, errNoAuthzOption) && p.Strict && !slices.Contains(p.StrictExemptServices, service.Desc.FullName()) {
+			errs = append(errs, fmt.Errorf("service %s method %s%s: no authz option, every method must declare one in strict mode", service.Desc.FullName(), method.Desc.Name(), at(method.Desc)))
+			continue
+		}
+		if errors.Is(err, errNoAuthzOption) {
+			p.warn(warningAt(method.Desc, "skipping method %s: no authz option", method.Desc.FullName()))
+			continue
+		}
+		if errors.Is(err, errNoHTTPAnnotation) {
+			p.warn(warningAt(method.Desc, "skipping method %s: no HTTP annotation", method.Desc.FullName()))
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("service %s method %s%s: %w", service.Desc.FullName(), method.Desc.Name(), at(method.Desc), err))
+			continue
+		}
+		rules = append(rules, methodRules...)
+	}
+
+	return rules, errors.Join(errs...)
+}
+
+// parseMethod extracts authz rules from a single method, one per HTTP binding.
+// defaults, when set, applies if the method has no non-empty authz option of its own.
+func (p *Parser) parseMethod(method *protogen.Method, defaults *authzDefaults) ([]Rule, error) {
+	// Extract authz permissions and no_auth_required flag
+	level := LevelMethod
+	options, err := p.extractAuthzOptions(method)
+	if errors.Is(err, errNoAuthzOption) && defaults != nil {
+		options, err = authzOptions{}, nil
+	}
+	if err == nil {
+		options, level = applyDefaults(defaults, options, level)
+	}
+	p.debugf("permissions: %v, roles: %v, noAuthRequired: %v, level: %s", options.Permissions, options.Roles, options.NoAuthRequired, level)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract authz options: %w", err)
+	}
+	if options.isEmpty() && !options.NoAuthRequiredSet && !p.AllowEmptyPermissions {
+		// Such a rule is read as "any authenticated caller" by some consumers, the decision must be explicit
+		return nil, fmt.Errorf("%w: method %s declares neither permissions nor no_auth_required", errInvalidAuthzOption, method.Desc.Name())
+	}
+
+	streamingType := streamingTypeOf(method.Desc)
+	methodOpts, _ := method.Desc.Options().(*descriptorpb.MethodOptions)
+	deprecated := methodOpts.GetDeprecated()
+	description := commentText(method.Comments.Leading)
+	sourceFile, sourceLine := sourceLocation(method.Desc)
+	grpcMethod := "/" + string(method.Parent.Desc.FullName()) + "/" + string(method.Desc.Name())
+
+	// Extract HTTP information
+	bindings, err := p.extractHTTPInfo(method)
+	p.debugf("bindings: %+v", bindings)
+	if errors.Is(err, errNoHTTPAnnotation) && p.GRPCFallback {
+		if err := validatePermissionPlaceholders(options, method.Desc, nil); err != nil {
+			return nil, err
+		}
+		return []Rule{{
+			GRPCMethod:        grpcMethod,
+			Transport:         TransportGRPC,
+			Permissions:       options.allPermissions(),
+			PermissionEffects: options.permissionEffects(),
+			Roles:             options.Roles,
+			AllowedScopes:     options.AllowedScopes,
+			Require:           options.Require,
+			NoAuthRequired:    options.NoAuthRequired,
+			EnvOverrides:      options.envOverrides(),
+			Deprecated:        deprecated,
+			Description:       description,
+			Level:             level,
+			StreamingType:     streamingType,
+			ProtoPackage:      method.Parent.Desc.ParentFile().Package(),
+			ServiceName:       method.Parent.Desc.Name(),
+			MethodName:        method.Desc.Name(),
+			SourceFile:        sourceFile,
+			SourceLine:        sourceLine,
+		}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract HTTP info: %w", err)
+	}
+
+	rules := make([]Rule, 0, len(bindings))
+	for _, binding := range bindings {
+		// grpc-gateway fails at runtime on variables not bound to a singular field of the request
+		if err := validatePathParamFields(method.Input.Desc, binding); err != nil {
+			return nil, fmt.Errorf("%s %s: %w", binding.Method, binding.Path, err)
+		}
+		if err := validatePermissionPlaceholders(options, method.Desc, &binding); err != nil {
+			return nil, fmt.Errorf("%s %s: %w", binding.Method, binding.Path, err)
+		}
+		// Most proxies reject a body on these methods
+		if binding.Body != "" && (binding.Method == "GET" || binding.Method == "DELETE") {
+			p.warn(warningAt(method.Desc, "%s %s declares body %q", binding.Method, binding.Path, binding.Body))
+		}
+		rules = append(rules, Rule{
+			HTTPPath:          binding.Path,
+			HTTPMethod:        binding.Method,
+			PathParams:        binding.PathParams,
+			PathParamPatterns: binding.PathParamPatterns,
+			PathParamFields:   binding.PathParamFields,
+			GRPCMethod:        grpcMethod,
+			Transport:         TransportHTTP,
+			Body:              binding.Body,
+			ResponseBody:      binding.ResponseBody,
+			Permissions:       options.allPermissions(),
+			PermissionEffects: options.permissionEffects(),
+			Roles:             options.Roles,
+			AllowedScopes:     options.AllowedScopes,
+			Require:           options.Require,
+			NoAuthRequired:    options.NoAuthRequired,
+			EnvOverrides:      options.envOverrides(),
+			Deprecated:        deprecated,
+			Description:       description,
+			Level:             level,
+			StreamingType:     streamingType,
+			ProtoPackage:      method.Parent.Desc.ParentFile().Package(),
+			ServiceName:       method.Parent.Desc.Name(),
+			MethodName:        method.Desc.Name(),
+			SourceFile:        sourceFile,
+			SourceLine:        sourceLine,
+		})
+	}
+
+	return rules, nil
+}
+
+// commentText returns the text of a comment, without the space following the comment markers and the blank
+// lines around it, e.g. "Lists the users.\nPaginated." for a two-line leading comment.
+func commentText(comments protogen.Comments) string {
+	lines := strings.Split(strings.TrimRight(string(comments), "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(strings.TrimPrefix(line, " "), " \t")
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// sourceLocation returns the file path and 1-based line declaring desc.
+// Both are empty when the descriptor carries no source info.
+func sourceLocation(desc protoreflect.Descriptor) (string, int) {
+	location := desc.ParentFile().SourceLocations().ByDescriptor(desc)
+	if location.Path == nil {
+		return "", 0
+	}
+	return desc.ParentFile().Path(), location.StartLine + 1
+}
+
+// at returns the " at file:line" suffix locating desc in error messages, or nothing without source info.
+func at(desc protoreflect.Descriptor) string {
+	file, line := sourceLocation(desc)
+	if file == "" {
+		return ""
+	}
+	return fmt.Sprintf(" at %s:%d", file, line)
+}
+
+// ValidateRules reports the routes claimed by more than one method, along with the permissions each one requires.
+// Paths are compared once their variables are normalized, /v1/users/{id} and /v1/users/{user_id} being the same route.
+func ValidateRules(rules []Rule) error {
+	routes := make(map[string][]Rule)
+	var keys []string
+	for _, rule := range rules {
+		// gRPC paths are unique per method
+		if rule.Transport != TransportHTTP {
+			continue
+		}
+		key := routeKey(rule)
+		if _, ok := routes[key]; !ok {
+			keys = append(keys, key)
+		}
+		routes[key] = append(routes[key], rule)
+	}
+
+	var errs []error
+	for _, key := range keys {
+		conflicting := routes[key]
+		methods := make(map[protoreflect.FullName]bool, len(conflicting))
+		descriptions := make([]string, 0, len(conflicting))
+		for _, rule := range conflicting {
+			methods[rule.FullMethodName()] = true
+			requirement := fmt.Sprintf("permissions %v", rule.Permissions)
+			if rule.NoAuthRequired {
+				requirement = "no auth required"
+			}
+			description := fmt.Sprintf("%s (%s %s, %s)", rule.FullMethodName(), rule.HTTPMethod, rule.HTTPPath, requirement)
+			if rule.SourceFile != "" {
+				description += fmt.Sprintf(" at %s:%d", rule.SourceFile, rule.SourceLine)
+			}
+			descriptions = append(descriptions, description)
+		}
+		if len(methods) > 1 {
+			errs = append(errs, fmt.Errorf("duplicate route %s: %s", key, strings.Join(descriptions, ", ")))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// routeKey returns the route of an HTTP rule with its variables left unnamed, e.g. GET /v1/users/{} or
+// GET /v1/{=projects/*}, which ValidateRules reports as duplicate when several methods declare it.
+func routeKey(rule Rule) string {
+	path := templateVariableRegex.ReplaceAllStringFunc(rule.HTTPPath, func(variable string) string {
+		if pattern := templateVariableRegex.FindStringSubmatch(variable)[2]; pattern != "" && pattern != "*" {
+			return "{=" + pattern + "}"
+		}
+		return "{}"
+	})
+	return strings.ToUpper(rule.HTTPMethod) + " " + path
+}
+
+// extractAuthzOptions extracts both permissions and no_auth_required from the authz extension of a method.
+// errNoAuthzOption is returned when the method has no authz option.
+func (p *Parser) extractAuthzOptions(method *protogen.Method) (authzOptions, error) {
+	methodOpts, ok := method.Desc.Options().(*descriptorpb.MethodOptions)
+	if !ok || methodOpts == nil {
+		return authzOptions{}, errNoAuthzOption
+	}
+
+	options, err := p.extractFromOptions(methodOpts, p.extensionNames.Method)
+	switch {
+	case errors.Is(err, errAuthzExtensionNotDeclared) && !p.SourceFallback:
+		return authzOptions{}, fmt.Errorf("method %s: %w, the files must include the one declaring %s", method.Desc.Name(), err, p.extensionNames.Method)
+	case errors.Is(err, errAuthzExtensionNotDeclared):
+		// Extract options by examining the proto file directly
+		if options, err = p.extractFromProtoSource(method); err != nil {
+			return authzOptions{}, err
+		}
+	case err != nil:
+		return authzOptions{}, fmt.Errorf("method %s: %w", method.Desc.Name(), err)
+	}
+
+	if err := p.checkNoAuthConflict(options, method.Desc, "method"); err != nil {
+		return authzOptions{}, err
+	}
+	return options, nil
+}
+
+// checkNoAuthConflict rejects an option of desc, a method, service or file, declaring permissions or roles along
+// with no_auth_required, or only reports it when NoAuthConflictWarning is set.
+// Consumers disagree on whether such a method is public, the generated code treats it as such.
+func (p *Parser) checkNoAuthConflict(options authzOptions, desc protoreflect.Descriptor, kind string) error {
+	for _, env := range slices.Sorted(maps.Keys(options.EnvOverrides)) {
+		if err := p.checkNoAuthConflict(options.EnvOverrides[env], desc, "env_overrides "+env+" of "+kind); err != nil {
+			return fmt.Errorf("env_overrides of environment %s: %w", env, err)
+		}
+	}
+	if !options.NoAuthRequired || !options.hasRequirements() {
+		return nil
+	}
+	name := string(desc.FullName())
+	if file, ok := desc.(protoreflect.FileDescriptor); ok {
+		name = file.Path()
+	}
+	if !p.NoAuthConflictWarning {
+		return fmt.Errorf("%w: %s %s declares permissions or roles along with no_auth_required", errInvalidAuthzOption, kind, name)
+	}
+	p.warn(warningAt(desc, "%s %s declares permissions or roles along with no_auth_required, it is public", kind, name))
+	return nil
+}
+
+// extractServiceAuthzOptions extracts the default authz option of a service.
+// errNoAuthzOption is returned when the service has no authz option.
+func (p *Parser) extractServiceAuthzOptions(service *protogen.Service) (authzOptions, error) {
+	serviceOpts, ok := service.Desc.Options().(*descriptorpb.ServiceOptions)
+	if !ok || serviceOpts == nil {
+		return authzOptions{}, errNoAuthzOption
+	}
+
+	options, err := p.extractFromOptions(serviceOpts, p.extensionNames.Service)
+	switch {
+	case errors.Is(err, errAuthzExtensionNotDeclared) && !p.SourceFallback:
+		return authzOptions{}, fmt.Errorf("%w, the files must include the one declaring %s", err, p.extensionNames.Service)
+	case errors.Is(err, errAuthzExtensionNotDeclared):
+		options, err = p.extractServiceFromProtoSource(service)
+	}
+	if err == nil {
+		err = p.checkNoAuthConflict(options, service.Desc, "service")
+	}
+	// The caller reports the location of the option
+	return options, err
+}
+
+// extractFileAuthzOptions extracts the default authz option of a file.
+// errNoAuthzOption is returned when the file has no authz option.
+func (p *Parser) extractFileAuthzOptions(file *protogen.File) (authzOptions, error) {
+	fileOpts, ok := file.Desc.Options().(*descriptorpb.FileOptions)
+	if !ok || fileOpts == nil {
+		return authzOptions{}, errNoAuthzOption
+	}
+
+	options, err := p.extractFromOptions(fileOpts, p.extensionNames.File)
+	switch {
+	case errors.Is(err, errAuthzExtensionNotDeclared) && !p.SourceFallback:
+		return authzOptions{}, fmt.Errorf("%w, the files must include the one declaring %s", err, p.extensionNames.File)
+	case errors.Is(err, errAuthzExtensionNotDeclared):
+		options, err = p.extractFileFromProtoSource(file)
+	}
+	if err == nil {
+		err = p.checkNoAuthConflict(options, file.Desc, "file")
+	}
+	// The caller reports the location of the option
+	return options, err
+}
+
+// extractFromOptions extracts the authz extension named extensionName from descriptor options.
+// errNoAuthzOption is returned when the options do not carry it, and errAuthzExtensionNotDeclared
+// when they do but the extension cannot be resolved at all.
+func (p *Parser) extractFromOptions(opts descriptorOptions, extensionName protoreflect.FullName) (authzOptions, error) {
+	// Descriptors not interpreted by the compiler only carry the option as uninterpreted_option entries
+	options, err := p.extractFromUninterpretedOptions(opts, extensionName)
+	if !errors.Is(err, errAuthzNotUninterpreted) {
+		return options, err
+	}
+
+	// Prefer the decoded extension, it does not depend on the proto source being readable
+	options, err = p.extractViaReflection(opts, extensionName)
+	if !errors.Is(err, errAuthzExtensionNotLinked) {
+		return options, err
+	}
+
+	// Otherwise decode the extension using its descriptor from the request
+	options, err = p.extractViaDescriptor(opts, extensionName)
+	if errors.Is(err, errAuthzExtensionNotDeclared) && !hasUnknownField(opts, p.authzExtensionNumber) {
+		// An option cannot be set without importing its declaration, which is only missing when the file declaring
+		// it was left out of the request, its value then being kept as an unknown field
+		return authzOptions{}, errNoAuthzOption
+	}
+	return options, err
+}
+
+// hasUnknownField reports whether the unknown fields of a message carry the field number.
+func hasUnknownField(message proto.Message, number protoreflect.FieldNumber) bool {
+	unknown := message.ProtoReflect().GetUnknown()
+	for len(unknown) > 0 {
+		fieldNumber, _, n := protowire.ConsumeField(unknown)
+		if n < 0 {
+			return false
+		}
+		if fieldNumber == number {
+			return true
+		}
+		unknown = unknown[n:]
+	}
+	return false
+}
+
+// extractFromUninterpretedOptions extracts permissions and no_auth_required from the uninterpreted_option
+// entries matching the given authz extension, decoding their aggregate value text.
+func (p *Parser) extractFromUninterpretedOptions(opts descriptorOptions, extensionName protoreflect.FullName) (authzOptions, error) {
+	for _, option := range opts.GetUninterpretedOption() {
+		nameParts := option.GetName()
+		if len(nameParts) != 1 || !nameParts[0].GetIsExtension() {
+			continue
+		}
+		if strings.TrimPrefix(nameParts[0].GetNamePart(), ".") != string(extensionName) {
+			continue
+		}
+
+		p.debugf("uninterpreted authz option %s: %s", extensionName, option.GetAggregateValue())
+		return p.parseAuthzBody(option.GetAggregateValue())
+	}
+
+	return authzOptions{}, errAuthzNotUninterpreted
+}
+
+// extractViaReflection extracts permissions and no_auth_required from the decoded options.
+// It requires the authz extension type to be linked in, otherwise errAuthzExtensionNotLinked is returned.
+func (p *Parser) extractViaReflection(opts descriptorOptions, extensionName protoreflect.FullName) (authzOptions, error) {
+	authzType, err := protoregistry.GlobalTypes.FindExtensionByNumber(
+		opts.ProtoReflect().Descriptor().FullName(),
+		p.authzExtensionNumber,
+	)
+	if err != nil {
+		return authzOptions{}, errAuthzExtensionNotLinked
+	}
+	if err := p.checkAuthzExtensionName(authzType, extensionName); err != nil {
+		return authzOptions{}, err
+	}
+
+	return p.decodeAuthzExtension(opts, authzType)
+}
+
+// extractViaDescriptor extracts permissions and no_auth_required using the authz extension descriptor
+// declared in the request files, so neither the Go type nor the proto source is needed.
+func (p *Parser) extractViaDescriptor(opts descriptorOptions, extensionName protoreflect.FullName) (authzOptions, error) {
+	authzType, err := p.extensionTypes.FindExtensionByNumber(
+		opts.ProtoReflect().Descriptor().FullName(),
+		p.authzExtensionNumber,
+	)
+	if err != nil {
+		return authzOptions{}, errAuthzExtensionNotDeclared
+	}
+	if err := p.checkAuthzExtensionName(authzType, extensionName); err != nil {
+		return authzOptions{}, err
+	}
+
+	// Without a linked Go type the extension is kept as unknown fields, decode it again with the dynamic type.
+	// The dynamic type extends the request's options descriptor, so the options must be decoded as a dynamic message too.
+	raw, err := proto.Marshal(opts)
+	if err != nil {
+		return authzOptions{}, fmt.Errorf("failed to marshal options: %w", err)
+	}
+	resolvedOpts := dynamicpb.NewMessage(authzType.TypeDescriptor().ContainingMessage())
+	if err := (proto.UnmarshalOptions{Resolver: p.extensionTypes}).Unmarshal(raw, resolvedOpts); err != nil {
+		return authzOptions{}, fmt.Errorf("failed to decode options: %w", err)
+	}
+
+	return p.decodeAuthzExtension(resolvedOpts, authzType)
+}
+
+// checkAuthzExtensionName ensures the extension found by number is the expected authz extension.
+func (p *Parser) checkAuthzExtensionName(authzType protoreflect.ExtensionType, extensionName protoreflect.FullName) error {
+	if name := authzType.TypeDescriptor().FullName(); name != extensionName {
+		return fmt.Errorf("extension number %d is %s, expected %s", p.authzExtensionNumber, name, extensionName)
+	}
+	return nil
+}
+
+// decodeAuthzExtension reads the authz extension of type authzType from the given options.
+func (p *Parser) decodeAuthzExtension(opts proto.Message, authzType protoreflect.ExtensionType) (authzOptions, error) {
+	if !proto.HasExtension(opts, authzType) {
+		return authzOptions{}, errNoAuthzOption
+	}
+
+	authz, ok := proto.GetExtension(opts, authzType).(proto.Message)
+	if !ok {
+		return authzOptions{}, fmt.Errorf("authz option %s is not a message", authzType.TypeDescriptor().FullName())
+	}
+
+	return p.authzFromMessage(authz.ProtoReflect())
+}
+
+// authzFromMessage reads the permissions and no_auth_required fields from a decoded authz message.
+func (p *Parser) authzFromMessage(authz protoreflect.Message) (authzOptions, error) {
+	fields := authz.Descriptor().Fields()
+
+	options := authzOptions{Permissions: []string{}}
+	for _, name := range []protoreflect.Name{"permissions", "permission_effects"} {
+		field := fields.ByName(name)
+		if field == nil {
+			continue
+		}
+		permissions, err := permissionsFromList(field, authz.Get(field))
+		if err != nil {
+			return authzOptions{}, fmt.Errorf("authz field %s: %w", name, err)
+		}
+		options.addPermissions(permissions)
+	}
+
+	if field := fields.ByName("roles"); field != nil {
+		if !field.IsList() || field.Kind() != protoreflect.StringKind {
+			return authzOptions{}, fmt.Errorf("authz field roles must be a repeated string")
+		}
+		list := authz.Get(field).List()
+		for i := range list.Len() {
+			options.Roles = append(options.Roles, list.Get(i).String())
+		}
+	}
+
+	// Scopes grant access like permissions, they are kept apart for the checkers telling them apart
+	if field := fields.ByName("scopes"); field != nil {
+		if !field.IsList() || field.Kind() != protoreflect.StringKind {
+			return authzOptions{}, fmt.Errorf("authz field scopes must be a repeated string")
+		}
+		list := authz.Get(field).List()
+		for i := range list.Len() {
+			options.AllowedScopes = append(options.AllowedScopes, list.Get(i).String())
+		}
+	}
+
+	// Overrides are authz options themselves, keyed by environment
+	if field := fields.ByName("env_overrides"); field != nil && authz.Has(field) {
+		if !field.IsMap() || field.MapKey().Kind() != protoreflect.StringKind || field.MapValue().Message() == nil ||
+			field.MapValue().Message().FullName() != authz.Descriptor().FullName() {
+			return authzOptions{}, fmt.Errorf("authz field env_overrides must be a map from string to %s", authz.Descriptor().FullName())
+		}
+		var err error
+		options.EnvOverrides = make(map[string]authzOptions, authz.Get(field).Map().Len())
+		authz.Get(field).Map().Range(func(key protoreflect.MapKey, value protoreflect.Value) bool {
+			var override authzOptions
+			if override, err = p.authzFromMessage(value.Message()); err != nil {
+				err = fmt.Errorf("env_overrides of environment %s: %w", key.String(), err)
+				return false
+			}
+			options.EnvOverrides[key.String()] = override
+			return true
+		})
+		if err != nil {
+			return authzOptions{}, err
+		}
+		if err := options.checkEnvOverrides(); err != nil {
+			return authzOptions{}, err
+		}
+	}
+
+	noAuthRequired := false
+	if field := fields.ByName("no_auth_required"); field != nil {
+		if field.Kind() != protoreflect.BoolKind {
+			return authzOptions{}, fmt.Errorf("authz field no_auth_required must be a bool")
+		}
+		noAuthRequired = authz.Get(field).Bool()
+		// An explicit false is only told apart from an absent field when the field is optional
+		options.NoAuthRequiredSet = authz.Has(field)
+	}
+
+	var require *PermissionExpr
+	if field := fields.ByName("require"); field != nil && authz.Has(field) {
+		if field.Kind() != protoreflect.MessageKind || field.IsList() {
+			return authzOptions{}, fmt.Errorf("authz field require must be a message")
+		}
+		expr, err := permissionExprFromMessage(authz.Get(field).Message())
+		if err != nil {
+			return authzOptions{}, fmt.Errorf("invalid require: %w", err)
+		}
+		require = &expr
+	}
+
+	strategy := authzStrategyReplace
+	if field := fields.ByName("defaults_strategy"); field != nil {
+		if field.Kind() != protoreflect.EnumKind {
+			return authzOptions{}, fmt.Errorf("authz field defaults_strategy must be an enum")
+		}
+		value := field.Enum().Values().ByNumber(authz.Get(field).Enum())
+		if value == nil {
+			return authzOptions{}, fmt.Errorf("unknown defaults_strategy value %d", authz.Get(field).Enum())
+		}
+		var ok bool
+		if strategy, ok = authzStrategyFromEnum[string(value.Name())]; !ok {
+			return authzOptions{}, fmt.Errorf("unknown defaults_strategy value %s", value.Name())
+		}
+	}
+
+	options.NoAuthRequired, options.Require, options.Strategy = noAuthRequired, require, strategy
+	for _, permission := range append(options.allPermissions(), options.Denied...) {
+		if err := p.validatePermission(permission); err != nil {
+			return authzOptions{}, err
+		}
+	}
+	for _, role := range options.Roles {
+		if err := validateRole(role); err != nil {
+			return authzOptions{}, err
+		}
+	}
+
+	p.debugf("permissions: %v, denied: %v, roles: %v, noAuthRequired: %v, strategy: %s", options.Permissions, options.Denied, options.Roles, noAuthRequired, strategy)
+	return options, nil
+}
+
+// permissionsFromList returns the permissions of a repeated field, whose elements are either
+// strings or messages with a name and an optional effect, ALLOW by default.
+func permissionsFromList(field protoreflect.FieldDescriptor, value protoreflect.Value) ([]Permission, error) {
+	if !field.IsList() || (field.Kind() != protoreflect.StringKind && field.Kind() != protoreflect.MessageKind) {
+		return nil, fmt.Errorf("must be a repeated string or message")
+	}
+
+	list := value.List()
+	permissions := make([]Permission, 0, list.Len())
+	for i := range list.Len() {
+		if field.Kind() == protoreflect.StringKind {
+			permissions = append(permissions, Permission{Name: list.Get(i).String(), Effect: EffectAllow})
+			continue
+		}
+		permission, err := permissionFromMessage(list.Get(i).Message())
+		if err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, permission)
+	}
+	return permissions, nil
+}
+
+// permissionFromMessage converts a message with a name string field and an optional effect enum field.
+func permissionFromMessage(message protoreflect.Message) (Permission, error) {
+	fields := message.Descriptor().Fields()
+	nameField := fields.ByName("name")
+	if nameField == nil || nameField.Kind() != protoreflect.StringKind || nameField.IsList() {
+		return Permission{}, fmt.Errorf("permission message %s must have a name string field", message.Descriptor().FullName())
+	}
+	permission := Permission{Name: message.Get(nameField).String(), Effect: EffectAllow}
+
+	if field := fields.ByName("effect"); field != nil {
+		if field.Kind() != protoreflect.EnumKind {
+			return Permission{}, fmt.Errorf("permission field effect must be an enum")
+		}
+		value := field.Enum().Values().ByNumber(message.Get(field).Enum())
+		if value == nil {
+			return Permission{}, fmt.Errorf("unknown effect value %d", message.Get(field).Enum())
+		}
+		effect, ok := effectFromEnum[string(value.Name())]
+		if !ok {
+			return Permission{}, fmt.Errorf("unknown effect value %s", value.Name())
+		}
+		permission.Effect = effect
+	}
+
+	return permission, nil
+}
+
+// validatePermission checks a permission against the permission pattern.
+// A wildcard such as admin:* is checked with a placeholder in place of the wildcard segment.
+func (p *Parser) validatePermission(permission string) error {
+	// Wildcards and placeholders are checked as if they were replaced by a segment
+	candidate := permissionPlaceholderRegex.ReplaceAllString(permission, "x")
+	if isWildcardPermission(candidate) {
+		candidate = strings.TrimSuffix(candidate, "*") + "x"
+	}
+	if p.PermissionPattern != nil && !p.PermissionPattern.MatchString(candidate) {
+		return fmt.Errorf("%w %q: does not match %s", errInvalidPermission, permission, p.PermissionPattern)
+	}
+	return nil
+}
+
+// validateRole rejects empty and padded role names, which no caller can hold.
+func validateRole(role string) error {
+	if role == "" || strings.TrimSpace(role) != role {
+		return fmt.Errorf("%w %q: roles must be non-empty and not padded with spaces", errInvalidRole, role)
+	}
+	return nil
+}
+
+// permissionExprFromMessage reads a decoded requirement message, including its nested requirements.
+func permissionExprFromMessage(requirement protoreflect.Message) (PermissionExpr, error) {
+	var expr PermissionExpr
+	fields := requirement.Descriptor().Fields()
+
+	for name, target := range map[protoreflect.Name]*[]string{"any_of": &expr.AnyOf, "all_of": &expr.AllOf} {
+		field := fields.ByName(name)
+		if field == nil {
+			continue
+		}
+		if !field.IsList() || field.Kind() != protoreflect.StringKind {
+			return PermissionExpr{}, fmt.Errorf("requirement field %s must be a repeated string", name)
+		}
+		list := requirement.Get(field).List()
+		for i := range list.Len() {
+			*target = append(*target, list.Get(i).String())
+		}
+	}
+
+	for name, target := range map[protoreflect.Name]*[]PermissionExpr{"all": &expr.All, "any": &expr.Any} {
+		field := fields.ByName(name)
+		if field == nil {
+			continue
+		}
+		if !field.IsList() || field.Kind() != protoreflect.MessageKind {
+			return PermissionExpr{}, fmt.Errorf("requirement field %s must be a repeated message", name)
+		}
+		list := requirement.Get(field).List()
+		for i := range list.Len() {
+			nested, err := permissionExprFromMessage(list.Get(i).Message())
+			if err != nil {
+				return PermissionExpr{}, err
+			}
+			*target = append(*target, nested)
+		}
+	}
+
+	return expr, nil
+}
+
+// extractFromProtoSource extracts permissions and no_auth_required by examining the proto source.
+// The source read is the one of the file declaring the method, which is not necessarily the file being generated.
+func (p *Parser) extractFromProtoSource(method *protogen.Method) (authzOptions, error) {
+	// Get the path of the file declaring the method and read it
+	protoPath := method.Desc.ParentFile().Path()
+
+	// Parse the proto file content to find authz options
+	serviceName := string(method.Parent.Desc.Name())
+	methodName := string(method.Desc.Name())
+
+	// Extract from the proto file content for the service method
+	return p.extractAuthzFromProtoFile(protoPath, serviceName, methodName)
+}
+
+// extractAuthzFromProtoFile extracts permissions and no_auth_required by parsing the proto file for a service method.
+// The method is looked up within its service block since several services of a file can declare methods with the same name.
+func (p *Parser) extractAuthzFromProtoFile(protoPath, serviceName, methodName string) (authzOptions, error) {
+	p.debugf("extractAuthzFromProtoFile: %s, %s.%s", protoPath, serviceName, methodName)
+	// The file is scanned once, locating all its services and rpc blocks for the following methods
+	scannedFile := p.scanProtoFile(protoPath)
+	if scannedFile.err != nil {
+		return authzOptions{}, scannedFile.err
+	}
+	scanned, ok := scannedFile.services[serviceName]
+	if !ok {
+		return authzOptions{}, fmt.Errorf("service %s not found in proto file", serviceName)
+	}
+	if scanned.unmatchedBraces {
+		return authzOptions{}, fmt.Errorf("unmatched braces in service %s", serviceName)
+	}
+	if scanned.unmatched[methodName] {
+		return authzOptions{}, fmt.Errorf("unmatched braces in method %s", methodName)
+	}
+	methodBody, ok := scanned.methods[methodName]
+	if !ok {
+		return authzOptions{}, fmt.Errorf("method %s not found in service %s", methodName, serviceName)
+	}
+
+	return p.extractFromSourceBody(methodBody, p.extensionNames.Method, "method "+methodName)
+}
+
+// extractServiceFromProtoSource extracts the default authz option of a service by examining the proto source,
+// the options of its methods being left out.
+func (p *Parser) extractServiceFromProtoSource(service *protogen.Service) (authzOptions, error) {
+	serviceName := string(service.Desc.Name())
+	scannedFile := p.scanProtoFile(service.Desc.ParentFile().Path())
+	if scannedFile.err != nil {
+		return authzOptions{}, scannedFile.err
+	}
+	scanned, ok := scannedFile.services[serviceName]
+	if !ok {
+		return authzOptions{}, fmt.Errorf("service %s not found in proto file", serviceName)
+	}
+	if scanned.unmatchedBraces {
+		return authzOptions{}, fmt.Errorf("unmatched braces in service %s", serviceName)
+	}
+
+	return p.extractFromSourceBody(scanned.body, p.extensionNames.Service, "service "+serviceName)
+}
+
+// extractFileFromProtoSource extracts the default authz option of a file by examining the proto source,
+// the options of its services, methods and messages being left out.
+func (p *Parser) extractFileFromProtoSource(file *protogen.File) (authzOptions, error) {
+	scannedFile := p.scanProtoFile(file.Desc.Path())
+	if scannedFile.err != nil {
+		return authzOptions{}, scannedFile.err
+	}
+
+	return p.extractFromSourceBody(scannedFile.source, p.extensionNames.File, "file "+file.Desc.Path())
+}
+
+// sourceOptionPatterns match an authz option in the proto source, in the aggregate and the field syntax respectively,
+// e.g. option (proto.v1.authz) = { and option (proto.v1.authz).permissions = "users:read";
+type sourceOptionPatterns struct {
+	aggregate *regexp.Regexp
+	field     *regexp.Regexp
+}
+
+// newSourceOptionPatterns returns the patterns of the authz option named extensionName.
+func newSourceOptionPatterns(extensionName protoreflect.FullName) sourceOptionPatterns {
+	name := regexp.QuoteMeta(string(extensionName))
+	return sourceOptionPatterns{
+		aggregate: regexp.MustCompile(`option\s*\(\s*` + name + `\s*\)\s*=\s*\{`),
+		field: regexp.MustCompile(`option\s*\(\s*` + name +
+			`\s*\)\s*\.\s*(\w+)\s*=\s*("(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'|[\w.]+)\s*;`),
+	}
+}
+
+// extractFromSourceBody extracts the authz option named extensionName from the body of a method, service or file,
+// described by subject in errors. Only the options declared at the top level of body are considered, the ones of
+// nested blocks such as the rpcs of a service belonging to them.
+func (p *Parser) extractFromSourceBody(body string, extensionName protoreflect.FullName, subject string) (authzOptions, error) {
+	patterns := p.sourcePatterns[extensionName]
+	topLevel := maskNestedBlocks(maskStringLiterals(body))
+	aggregateMatch := patterns.aggregate.FindStringIndex(topLevel)
+	var fieldMatches [][]string
+	for _, match := range patterns.field.FindAllStringSubmatchIndex(topLevel, -1) {
+		// The values are read from body, their string literals being masked in topLevel
+		fieldMatches = append(fieldMatches, []string{body[match[0]:match[1]], body[match[2]:match[3]], body[match[4]:match[5]]})
+	}
+
+	switch {
+	case aggregateMatch != nil && len(fieldMatches) > 0:
+		return authzOptions{}, fmt.Errorf("%w: %s mixes the aggregate and the field syntax", errInvalidAuthzOption, subject)
+	case len(fieldMatches) > 0:
+		return p.parseAuthzFields(fieldMatches)
+	case aggregateMatch == nil:
+		return authzOptions{}, fmt.Errorf("%w for %s", errNoAuthzOption, subject)
+	}
+
+	// Extract the authz block content by counting braces
+	authzBody, _, ok := blockBody(body, aggregateMatch[1])
+	if !ok {
+		return authzOptions{}, fmt.Errorf("unmatched braces in authz block for %s", subject)
+	}
+
+	return p.parseAuthzBody(authzBody)
+}
+
+// maskNestedBlocks replaces the content of the blocks of text, whose string literals are masked, with spaces, keeping
+// their braces and line breaks so that positions in the result match the ones in text.
+func maskNestedBlocks(text string) string {
+	masked := []byte(text)
+	depth := 0
+	for i, c := range masked {
+		switch c {
+		case '{':
+			depth++
+			if depth == 1 {
+				continue
+			}
+		case '}':
+			depth--
+			if depth == 0 {
+				continue
+			}
+		}
+		if depth > 0 && c != '\n' {
+			masked[i] = ' '
+		}
+	}
+	return string(masked)
+}
+
+// Patterns of the rpc declarations. Declarations can span several lines, contain comments,
+// stream keywords and fully-qualified type names.
+const (
+	declarationGap = `(?:\s|//[^\n]*|/\*[\s\S]*?\*/)*`
+	rpcMessageType = `\(` + declarationGap + `(?:stream\s+` + declarationGap + `)?\.?[\w.]+` + declarationGap + `\)`
+)
+
+// rpcDeclarationRegex matches the declaration of an rpc up to its opening brace, capturing the method name.
+var rpcDeclarationRegex = regexp.MustCompile(`\brpc\s+` + declarationGap + `(\w+)` + declarationGap + rpcMessageType +
+	declarationGap + `returns` + declarationGap + rpcMessageType + declarationGap + `\{`)
+
+// serviceDeclarationRegex matches the declaration of a service up to its opening brace, capturing the service name.
+var serviceDeclarationRegex = regexp.MustCompile(`\bservice\s+(\w+)\s*\{`)
+
+// scannedFile holds the services of a proto file located by the source scanner.
+type scannedFile struct {
+	source   string                     // content of the file, comments masked
+	services map[string]*scannedService // keyed by service name
+	err      error                      // set when the file cannot be read
+}
+
+// scannedService holds the rpc blocks of a service located by the source scanner.
+type scannedService struct {
+	body            string            // content of the service block
+	methods         map[string]string // body of each rpc, keyed by method name
+	unmatched       map[string]bool   // rpcs whose braces are unmatched
+	unmatchedBraces bool              // set when the service block itself is not closed
+}
+
+// scanProtoFile returns the services of a proto file, reading and scanning it only the first time they are requested.
+func (p *Parser) scanProtoFile(protoPath string) *scannedFile {
+	if scanned, ok := p.fileCache[protoPath]; ok {
+		return scanned
+	}
+
+	scanned := scanProtoSource(protoPath)
+	if p.fileCache == nil {
+		p.fileCache = make(map[string]*scannedFile)
+	}
+	p.fileCache[protoPath] = scanned
+	return scanned
+}
+
+// scanProtoSource reads a proto file and indexes the body of every rpc of every service in a single pass.
+// Comments are blanked out first so that commented-out declarations are never matched, and the methods
+// are indexed per service since several services of a file can declare methods with the same name.
+//
+// The descriptors are the source of truth, the scanner only backs SourceFallback, off by default: when the authz
+// extension is declared in none of the files given to the parser, e.g. a descriptor set built without its imports,
+// the options are unknown fields which nothing can decode, and the proto source is the only place left to read them
+// from. Its values are parsed as the text format does, see parseTextBool and parseTextEnum.
+func scanProtoSource(protoPath string) *scannedFile {
+	content, err := os.ReadFile(protoPath)
+	if err != nil {
+		return &scannedFile{err: fmt.Errorf("failed to read proto file: %w", err)}
+	}
+	source := maskComments(string(content))
+
+	scanned := &scannedFile{source: source, services: make(map[string]*scannedService)}
+	for _, match := range serviceDeclarationRegex.FindAllStringSubmatchIndex(maskStringLiterals(source), -1) {
+		serviceName := source[match[2]:match[3]]
+		if _, ok := scanned.services[serviceName]; ok {
+			continue
+		}
+
+		serviceBody, _, ok := blockBody(source, match[1])
+		if !ok {
+			scanned.services[serviceName] = &scannedService{unmatchedBraces: true}
+			continue
+		}
+		scanned.services[serviceName] = scanServiceBody(serviceBody)
+	}
+
+	return scanned
+}
+
+// scanServiceBody indexes the body of every rpc declared in the body of a service.
+func scanServiceBody(serviceBody string) *scannedService {
+	scanned := &scannedService{body: serviceBody, methods: make(map[string]string), unmatched: make(map[string]bool)}
+	for _, match := range rpcDeclarationRegex.FindAllStringSubmatchIndex(maskStringLiterals(serviceBody), -1) {
+		methodName := serviceBody[match[2]:match[3]]
+		if _, ok := scanned.methods[methodName]; ok || scanned.unmatched[methodName] {
+			continue
+		}
+
+		// Extract content until the brace matching the opening one
+		methodBody, _, ok := blockBody(serviceBody, match[1])
+		if !ok {
+			scanned.unmatched[methodName] = true
+			continue
+		}
+		scanned.methods[methodName] = methodBody
+	}
+
+	return scanned
+}
+
+// Patterns of the scalar fields of an authz block.
+var (
+	noAuthRequiredRegex   = regexp.MustCompile(`\bno_auth_required\s*:\s*(\w+)`)
+	defaultsStrategyRegex = regexp.MustCompile(`\bdefaults_strategy\s*:\s*(\w+)`)
+)
+
+// parseAuthzBody extracts permissions and no_auth_required from the text inside an authz option block.
+func (p *Parser) parseAuthzBody(authzBody string) (authzOptions, error) {
+	// Remove all comments from authzBody, including the ones trailing list items.
+	// Quoted strings are left untouched so that a permission containing // is not mangled.
+	authzBody = maskComments(authzBody)
+
+	// Extract the environment overrides first so their fields are not mistaken for top level ones,
+	// each map entry being a block of its own, e.g. env_overrides { key: "staging" value { no_auth_required: true } }
+	var envOverrides map[string]authzOptions
+	for {
+		_, entryBody, rest, found, err := extractTextBlock(authzBody, envOverridesBlockRegex)
+		if err != nil {
+			return authzOptions{}, err
+		}
+		if !found {
+			break
+		}
+		authzBody = rest
+
+		env, override, err := p.parseEnvOverrideEntry(entryBody)
+		if err != nil {
+			return authzOptions{}, fmt.Errorf("failed to parse env_overrides: %w", err)
+		}
+		if envOverrides == nil {
+			envOverrides = make(map[string]authzOptions)
+		}
+		envOverrides[env] = override
+	}
+
+	// Extract the requirement first so its lists are not mistaken for top level fields
+	var require *PermissionExpr
+	_, requireBody, authzBody, found, err := extractTextBlock(authzBody, requireBlockRegex)
+	if err != nil {
+		return authzOptions{}, err
+	}
+	if found {
+		expr, err := p.parseRequirementBody(requireBody)
+		if err != nil {
+			return authzOptions{}, fmt.Errorf("failed to parse require: %w", err)
+		}
+		require = &expr
+	}
+
+	// Extract permissions from non-commented content
+	options := authzOptions{Permissions: []string{}, Require: require}
+	for _, field := range []permissionListField{permissionsField, permissionEffectsField} {
+		permissions, err := p.extractPermissionList(authzBody, field)
+		if err != nil {
+			return authzOptions{}, fmt.Errorf("failed to parse %s: %w", field.name, err)
+		}
+		options.addPermissions(permissions)
+	}
+
+	// Extract roles, which are names rather than permissions
+	roles, err := p.extractPermissionList(authzBody, rolesField)
+	if err != nil {
+		return authzOptions{}, fmt.Errorf("failed to parse roles: %w", err)
+	}
+	options.Roles = permissionNames(roles)
+
+	// Extract scopes, which grant access like permissions but are kept apart from them
+	scopes, err := p.extractPermissionList(authzBody, scopesField)
+	if err != nil {
+		return authzOptions{}, fmt.Errorf("failed to parse scopes: %w", err)
+	}
+	options.AllowedScopes = permissionNames(scopes)
+
+	// Extract no_auth_required
+	noAuthRequired := false
+	noAuthMatches := noAuthRequiredRegex.FindStringSubmatch(maskStringLiterals(authzBody))
+	if len(noAuthMatches) >= 2 {
+		var ok bool
+		if noAuthRequired, ok = parseTextBool(noAuthMatches[1]); !ok {
+			return authzOptions{}, fmt.Errorf("invalid no_auth_required value %s", noAuthMatches[1])
+		}
+		options.NoAuthRequiredSet = true
+	}
+
+	// Extract defaults_strategy
+	strategy := authzStrategyReplace
+	strategyMatches := defaultsStrategyRegex.FindStringSubmatch(maskStringLiterals(authzBody))
+	if len(strategyMatches) >= 2 {
+		var ok bool
+		if strategy, ok = authzStrategyFromEnum[parseTextEnum(strategyMatches[1], defaultsStrategyNames)]; !ok {
+			return authzOptions{}, fmt.Errorf("unknown defaults_strategy value %s", strategyMatches[1])
+		}
+	}
+
+	options.NoAuthRequired, options.Strategy, options.EnvOverrides = noAuthRequired, strategy, envOverrides
+	if err := options.checkEnvOverrides(); err != nil {
+		return authzOptions{}, err
+	}
+	p.debugf("permissions: %v, denied: %v, roles: %v, noAuthRequired: %v, strategy: %s", options.Permissions, options.Denied, options.Roles, noAuthRequired, strategy)
+	return options, nil
+}
+
+// envOverrideKeyRegex matches the key of an env_overrides map entry, e.g. key: "staging".
+var envOverrideKeyRegex = regexp.MustCompile(`\bkey\s*:\s*("(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*')`)
+
+// parseEnvOverrideEntry parses the text inside an env_overrides map entry, e.g. `key: "staging" value { ... }`.
+func (p *Parser) parseEnvOverrideEntry(body string) (string, authzOptions, error) {
+	_, valueBody, rest, found, err := extractTextBlock(body, envOverrideValueBlockRegex)
+	if err != nil {
+		return "", authzOptions{}, err
+	}
+	if !found {
+		return "", authzOptions{}, fmt.Errorf("entry without value")
+	}
+	keyMatch := findOutsideStrings(envOverrideKeyRegex, rest)
+	if keyMatch == nil {
+		return "", authzOptions{}, fmt.Errorf("entry without key")
+	}
+	env, err := unquoteTextString(rest[keyMatch[2]:keyMatch[3]])
+	if err != nil {
+		return "", authzOptions{}, err
+	}
+
+	override, err := p.parseAuthzBody(valueBody)
+	if err != nil {
+		return "", authzOptions{}, fmt.Errorf("environment %s: %w", env, err)
+	}
+	return env, override, nil
+}
+
+// parseAuthzFields builds authz options from field assignments, each match holding the field name and its value.
+// Assignments of the repeated permissions, scopes and roles fields accumulate.
+func (p *Parser) parseAuthzFields(matches [][]string) (authzOptions, error) {
+	options := authzOptions{Permissions: []string{}, Strategy: authzStrategyReplace}
+	for _, match := range matches {
+		field, value := match[1], match[2]
+		switch field {
+		case "permissions":
+			permission, err := unquoteTextString(value)
+			if err != nil {
+				return authzOptions{}, fmt.Errorf("failed to parse permissions: %w", err)
+			}
+			if err := p.validatePermission(permission); err != nil {
+				return authzOptions{}, err
+			}
+			options.Permissions = append(options.Permissions, permission)
+		case "scopes":
+			scope, err := unquoteTextString(value)
+			if err != nil {
+				return authzOptions{}, fmt.Errorf("failed to parse scopes: %w", err)
+			}
+			if err := p.validatePermission(scope); err != nil {
+				return authzOptions{}, err
+			}
+			options.AllowedScopes = append(options.AllowedScopes, scope)
+		case "roles":
+			role, err := unquoteTextString(value)
+			if err != nil {
+				return authzOptions{}, fmt.Errorf("failed to parse roles: %w", err)
+			}
+			if err := validateRole(role); err != nil {
+				return authzOptions{}, err
+			}
+			options.Roles = append(options.Roles, role)
+		case "no_auth_required":
+			if value != "true" && value != "false" {
+				return authzOptions{}, fmt.Errorf("invalid no_auth_required value %s", value)
+			}
+			options.NoAuthRequired = value == "true"
+			options.NoAuthRequiredSet = true
+		case "defaults_strategy":
+			strategy, ok := authzStrategyFromEnum[value]
+			if !ok {
+				return authzOptions{}, fmt.Errorf("unknown defaults_strategy value %s", value)
+			}
+			options.Strategy = strategy
+		default:
+			return authzOptions{}, fmt.Errorf("unsupported authz field %s in field syntax", field)
+		}
+	}
+
+	p.debugf("permissions: %v, roles: %v, noAuthRequired: %v, strategy: %s", options.Permissions, options.Roles, options.NoAuthRequired, options.Strategy)
+	return options, nil
+}
+
+// parseTextBool parses a bool literal of the text format, which accepts true, True, t and 1 along with their false
+// counterparts. It returns false as second value for any other literal.
+func parseTextBool(value string) (bool, bool) {
+	switch value {
+	case "true", "True", "t", "1":
+		return true, true
+	case "false", "False", "f", "0":
+		return false, true
+	}
+	return false, false
+}
+
+// Names of the enum values of the authz options, indexed by number.
+var (
+	defaultsStrategyNames = []string{"DEFAULTS_STRATEGY_UNSPECIFIED", "DEFAULTS_STRATEGY_REPLACE", "DEFAULTS_STRATEGY_MERGE"}
+	effectNames           = []string{"EFFECT_UNSPECIFIED", "EFFECT_ALLOW", "EFFECT_DENY"}
+)
+
+// parseTextEnum returns the name of an enum value written in the text format, which accepts the number of the value
+// in place of its name, e.g. DEFAULTS_STRATEGY_MERGE for 2. Names and unknown numbers are returned as is.
+func parseTextEnum(value string, names []string) string {
+	if number, err := strconv.Atoi(value); err == nil && number >= 0 && number < len(names) {
+		return names[number]
+	}
+	return value
+}
+
+// textEscapes maps the single character escape sequences of proto string literals to the byte they stand for.
+var textEscapes = map[byte]byte{
+	'a': '\a', 'b': '\b', 'f': '\f', 'n': '\n', 'r': '\r', 't': '\t', 'v': '\v',
+	'\\': '\\', '\'': '\'', '"': '"', '?': '?',
+}
+
+// unquoteTextString unquotes a double or single quoted proto string literal, decoding its escape sequences:
+// single characters such as \" or \', octal \NNN, hexadecimal \xHH and unicode \uXXXX or \UXXXXXXXX.
+func unquoteTextString(value string) (string, error) {
+	if len(value) < 2 || (value[0] != '"' && value[0] != '\'') || value[len(value)-1] != value[0] {
+		return "", fmt.Errorf("invalid string literal %s", value)
+	}
+
+	body := value[1 : len(value)-1]
+	var unquoted strings.Builder
+	for i := 0; i < len(body); i++ {
+		if body[i] != '\\' {
+			unquoted.WriteByte(body[i])
+			continue
+		}
+
+		i++
+		if i == len(body) {
+			return "", fmt.Errorf("invalid string literal %s: trailing backslash", value)
+		}
+		if unescaped, ok := textEscapes[body[i]]; ok {
+			unquoted.WriteByte(unescaped)
+			continue
+		}
+
+		// Numeric escapes, the digits count being bounded by the escape kind
+		base, maxDigits, start := 8, 3, i
+		switch body[i] {
+		case 'x', 'X':
+			base, maxDigits, start = 16, 2, i+1
+		case 'u':
+			base, maxDigits, start = 16, 4, i+1
+		case 'U':
+			base, maxDigits, start = 16, 8, i+1
+		default:
+			if body[i] < '0' || body[i] > '7' {
+				return "", fmt.Errorf("invalid string literal %s: unknown escape \\%c", value, body[i])
+			}
+		}
+		end := start
+		for end < len(body) && end-start < maxDigits && isDigitInBase(body[end], base) {
+			end++
+		}
+		if end == start || (body[i] == 'u' || body[i] == 'U') && end-start != maxDigits {
+			return "", fmt.Errorf("invalid string literal %s: malformed escape", value)
+		}
+		code, err := strconv.ParseUint(body[start:end], base, 32)
+		if err != nil {
+			return "", fmt.Errorf("invalid string literal %s: %w", value, err)
+		}
+		if body[i] == 'u' || body[i] == 'U' {
+			unquoted.WriteRune(rune(code))
+		} else {
+			unquoted.WriteByte(byte(code))
+		}
+		i = end - 1
+	}
+
+	return unquoted.String(), nil
+}
+
+// isDigitInBase reports whether c is a digit of base 8 or 16.
+func isDigitInBase(c byte, base int) bool {
+	switch {
+	case c >= '0' && c <= '7':
+		return true
+	case base == 8:
+		return false
+	default:
+		return c >= '8' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+	}
+}
+
+// parseRequirementBody parses the text inside a requirement block, e.g. `any_of: ["a", "b"] all { all_of: ["c"] }`.
+func (p *Parser) parseRequirementBody(body string) (PermissionExpr, error) {
+	var expr PermissionExpr
+
+	// Nested requirements are extracted in order of appearance since they can contain each other
+	for {
+		name, nestedBody, rest, found, err := extractTextBlock(body, nestedBlockRegex)
+		if err != nil {
+			return PermissionExpr{}, err
+		}
+		if !found {
+			break
+		}
+		body = rest
+
+		nested, err := p.parseRequirementBody(nestedBody)
+		if err != nil {
+			return PermissionExpr{}, err
+		}
+		if name == "all" {
+			expr.All = append(expr.All, nested)
+		} else {
+			expr.Any = append(expr.Any, nested)
+		}
+	}
+
+	anyOf, err := p.extractPermissionList(body, anyOfField)
+	if err != nil {
+		return PermissionExpr{}, fmt.Errorf("failed to parse any_of: %w", err)
+	}
+	allOf, err := p.extractPermissionList(body, allOfField)
+	if err != nil {
+		return PermissionExpr{}, fmt.Errorf("failed to parse all_of: %w", err)
+	}
+	expr.AnyOf, expr.AllOf = permissionNames(anyOf), permissionNames(allOf)
+
+	return expr, nil
+}
+
+// permissionListField holds the patterns matching the permission list assigned to a field of a text format body.
+type permissionListField struct {
+	name     string
+	start    *regexp.Regexp // the field name up to the first character of a value: an opening bracket, a quote or a brace
+	messages bool           // whether the elements can be messages with a name and an effect besides strings
+	roles    bool           // whether the elements are roles, checked with validateRole instead of the permission pattern
+}
+
+// newPermissionListField compiles the patterns of the permission list field name.
+func newPermissionListField(name string, messages bool) permissionListField {
+	return permissionListField{
+		name:     name,
+		start:    regexp.MustCompile(`\b` + regexp.QuoteMeta(name) + `\s*:?\s*["'\[{]`),
+		messages: messages,
+	}
+}
+
+// Patterns of the values of a permission list field, anchored at their start.
+var (
+	// permissionListRegex matches a bracketed list, capturing its content
+	permissionListRegex = regexp.MustCompile(`^\[((?:"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'|[^\]"'])*)\]`)
+	// permissionStringRegex matches a single string value, adjacent literals being concatenated
+	permissionStringRegex = regexp.MustCompile(`^(?:(?:"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*')\s*)+`)
+)
+
+// Permission list fields of the authz option and of its requirements.
+var (
+	permissionsField       = newPermissionListField("permissions", true)
+	permissionEffectsField = newPermissionListField("permission_effects", true)
+	anyOfField             = newPermissionListField("any_of", false)
+	allOfField             = newPermissionListField("all_of", false)
+	rolesField             = newRoleListField("roles")
+	scopesField            = newPermissionListField("scopes", false)
+)
+
+// newRoleListField compiles the patterns of the role list field name.
+func newRoleListField(name string) permissionListField {
+	field := newPermissionListField(name, false)
+	field.roles = true
+	return field
+}
+
+// permissionNames returns the names of permissions.
+func permissionNames(permissions []Permission) []string {
+	if permissions == nil {
+		return nil
+	}
+	names := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		names = append(names, permission.Name)
+	}
+	return names
+}
+
+// extractPermissionList extracts the values assigned to field in a text format body, in order of appearance. As
+// a repeated field, it can be assigned lists such as `field: ["a", "b"]` as well as single values repeating the
+// field, e.g. `field: "a" field: "b"`, and both forms can be mixed. A list can span several lines and end with a
+// trailing comma, brackets inside quoted strings are not delimiters.
+// Every name is checked against the permission pattern, or with validateRole for role lists.
+func (p *Parser) extractPermissionList(body string, field permissionListField) ([]Permission, error) {
+	var permissions []Permission
+	for _, startMatch := range field.start.FindAllStringIndex(maskStringLiterals(body), -1) {
+		value := body[startMatch[1]-1:]
+		var entries string
+		switch value[0] {
+		case '[':
+			matches := permissionListRegex.FindStringSubmatch(value)
+			if len(matches) < 2 {
+				return nil, fmt.Errorf("unterminated %s list", field.name)
+			}
+			entries = matches[1]
+		case '{':
+			if !field.messages {
+				return nil, fmt.Errorf("%s values must be quoted strings", field.name)
+			}
+			_, end, ok := blockBody(value, 1)
+			if !ok {
+				return nil, fmt.Errorf("unterminated %s message", field.name)
+			}
+			entries = value[:end]
+		default:
+			entries = permissionStringRegex.FindString(value)
+			if entries == "" {
+				return nil, fmt.Errorf("unterminated %s string", field.name)
+			}
+		}
+
+		values, err := p.parsePermissionsString(entries, field.messages)
+		if err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, values...)
+	}
+
+	check := p.validatePermission
+	if field.roles {
+		check = validateRole
+	}
+	for _, permission := range permissions {
+		if err := check(permission.Name); err != nil {
+			return nil, err
+		}
+	}
+	return permissions, nil
+}
+
+// Patterns of the message fields of the authz option and of its requirements.
+var (
+	requireBlockRegex          = textBlockRegex("require")
+	envOverridesBlockRegex     = textBlockRegex("env_overrides")
+	envOverrideValueBlockRegex = textBlockRegex("value")
+	nestedBlockRegex           = textBlockRegex("all", "any")
+)
+
+// textBlockRegex returns the pattern of a `name { ... }` or `name: { ... }` block opening, name being any of names.
+func textBlockRegex(names ...string) *regexp.Regexp {
+	quotedNames := make([]string, 0, len(names))
+	for _, name := range names {
+		quotedNames = append(quotedNames, regexp.QuoteMeta(name))
+	}
+	return regexp.MustCompile(`\b(` + strings.Join(quotedNames, "|") + `)\s*:?\s*\{`)
+}
+
+// extractTextBlock finds the first block of a text format body whose opening blockRegex matches.
+// It returns the matched name, the block content and the body without the block.
+func extractTextBlock(body string, blockRegex *regexp.Regexp) (string, string, string, bool, error) {
+	blockMatch := findOutsideStrings(blockRegex, body)
+	if blockMatch == nil {
+		return "", "", body, false, nil
+	}
+
+	name := body[blockMatch[2]:blockMatch[3]]
+	block, end, ok := blockBody(body, blockMatch[1])
+	if !ok {
+		return "", "", body, false, fmt.Errorf("unmatched braces in %s block", name)
+	}
+
+	return name, block, body[:blockMatch[0]] + body[end:], true, nil
+}
+
+// findOutsideStrings returns the submatch indexes of the first match of re in text that is not part of a string literal.
+func findOutsideStrings(re *regexp.Regexp, text string) []int {
+	return re.FindStringSubmatchIndex(maskStringLiterals(text))
+}
+
+// maskStringLiterals replaces the content of the string literals of text with underscores, keeping their quotes.
+// Positions in the result match the ones in text.
+func maskStringLiterals(text string) string {
+	masked := []byte(text)
+	pos := 0
+	for pos < len(text) {
+		if text[pos] != '"' && text[pos] != '\'' {
+			pos++
+			continue
+		}
+
+		end, terminated := skipStringLiteral(text, pos)
+		last := end
+		if terminated {
+			last = end - 1
+		}
+		for i := pos + 1; i < last; i++ {
+			masked[i] = '_'
+		}
+		pos = end
+	}
+	return string(masked)
+}
+
+// blockBody returns the content of the block whose opening brace is right before start,
+// along with the position following its closing brace. ok is false when the braces are unmatched.
+// Braces inside string literals and comments are not counted.
+func blockBody(text string, start int) (body string, end int, ok bool) {
+	braceCount := 1
+	pos := start
+	for pos < len(text) && braceCount > 0 {
+		switch {
+		case text[pos] == '"' || text[pos] == '\'':
+			pos, _ = skipStringLiteral(text, pos)
+			continue
+		case strings.HasPrefix(text[pos:], "//"):
+			pos = skipUntil(text, pos+2, "\n")
+			continue
+		case strings.HasPrefix(text[pos:], "/*"):
+			pos = skipUntil(text, pos+2, "*/")
+			continue
+		case text[pos] == '{':
+			braceCount++
+		case text[pos] == '}':
+			braceCount--
+		}
+		pos++
+	}
+
+	if braceCount != 0 {
+		return "", 0, false
+	}
+
+	return text[start : pos-1], pos, true
+}
+
+// maskComments replaces the comments of a proto source with spaces, keeping line breaks and string literals intact.
+func maskComments(text string) string {
+	masked := []byte(text)
+	pos := 0
+	for pos < len(text) {
+		var end int
+		switch {
+		case text[pos] == '"' || text[pos] == '\'':
+			pos, _ = skipStringLiteral(text, pos)
+			continue
+		case strings.HasPrefix(text[pos:], "//"):
+			end = skipUntil(text, pos+2, "\n")
+		case strings.HasPrefix(text[pos:], "/*"):
+			end = skipUntil(text, pos+2, "*/")
+		default:
+			pos++
+			continue
+		}
+
+		for ; pos < end; pos++ {
+			if masked[pos] != '\n' {
+				masked[pos] = ' '
+			}
+		}
+	}
+	return string(masked)
+}
+
+// skipStringLiteral returns the position following the string literal starting at start,
+// taking escaped quotes into account. An unterminated literal extends to the end of text.
+func skipStringLiteral(text string, start int) (end int, terminated bool) {
+	quote := text[start]
+	for pos := start + 1; pos < len(text); pos++ {
+		switch text[pos] {
+		case '\\':
+			pos++
+		case quote:
+			return pos + 1, true
+		}
+	}
+	return len(text), false
+}
+
+// skipUntil returns the position following the first terminator found from start, or the end of text.
+func skipUntil(text string, start int, terminator string) int {
+	if i := strings.Index(text[start:], terminator); i >= 0 {
+		return start + i + len(terminator)
+	}
+	return len(text)
+}
+
+// parsePermissionsString parses permissions from a string like "aaaa", "bbbb", 'cccc'.
+// Each entry is a quoted string, commas and quotes inside it included. As in the protobuf text
+// format, adjacent literals are concatenated so "read:" 'all' is the single permission read:all.
+// When messages is set, an entry can also be a message such as {name: "aaaa", effect: DENY}.
+// Anything else than whitespace and the commas separating entries is an error.
+func (p *Parser) parsePermissionsString(permissionsStr string, messages bool) ([]Permission, error) {
+	p.debugf("parsePermissionsString: %s", permissionsStr)
+	permissions := []Permission{}
+
+	// current holds the literals of the string entry being read, inEntry whether one was seen yet,
+	// and afterMessage whether the previous entry was a message still to be followed by a comma
+	var current strings.Builder
+	inEntry, afterMessage := false, false
+	endEntry := func() {
+		permissions = append(permissions, Permission{Name: current.String(), Effect: EffectAllow})
+		current.Reset()
+		inEntry = false
+	}
+
+	pos := 0
+	for pos < len(permissionsStr) {
+		switch c := permissionsStr[pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			pos++
+		case c == ',':
+			if !inEntry && !afterMessage {
+				return nil, fmt.Errorf("unexpected comma at offset %d", pos)
+			}
+			if inEntry {
+				endEntry()
+			}
+			afterMessage = false
+			pos++
+		case c == '"' || c == '\'':
+			if afterMessage {
+				return nil, fmt.Errorf("missing comma before offset %d", pos)
+			}
+			end, terminated := skipStringLiteral(permissionsStr, pos)
+			if !terminated {
+				return nil, fmt.Errorf("unterminated string at offset %d", pos)
+			}
+			literal, err := unquoteTextString(permissionsStr[pos:end])
+			if err != nil {
+				return nil, err
+			}
+			current.WriteString(literal)
+			inEntry = true
+			pos = end
+		case c == '{' && messages:
+			if inEntry || afterMessage {
+				return nil, fmt.Errorf("missing comma before offset %d", pos)
+			}
+			body, end, ok := blockBody(permissionsStr, pos+1)
+			if !ok {
+				return nil, fmt.Errorf("unterminated message at offset %d", pos)
+			}
+			permission, err := parsePermissionMessage(body)
+			if err != nil {
+				return nil, fmt.Errorf("invalid message at offset %d: %w", pos, err)
+			}
+			permissions = append(permissions, permission)
+			afterMessage = true
+			pos = end
+		default:
+			end := pos
+			for end < len(permissionsStr) && !strings.ContainsRune(" \t\n\r,\"'", rune(permissionsStr[end])) {
+				end++
+			}
+			return nil, fmt.Errorf("unquoted token %q at offset %d, permissions must be quoted strings", permissionsStr[pos:end], pos)
+		}
+	}
+
+	if inEntry {
+		endEntry()
+	}
+
+	p.debugf("permissions: %v", permissions)
+	return permissions, nil
+}
+
+// Patterns of the fields of a permission message.
+var (
+	permissionNameRegex   = regexp.MustCompile(`\bname\s*:\s*("(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*')`)
+	permissionEffectRegex = regexp.MustCompile(`\beffect\s*:\s*(\w+)`)
+)
+
+// parsePermissionMessage parses the body of a permission message such as {name: "aaaa", effect: DENY}.
+// The effect is ALLOW when omitted.
+func parsePermissionMessage(body string) (Permission, error) {
+	nameMatch := findOutsideStrings(permissionNameRegex, body)
+	if nameMatch == nil {
+		return Permission{}, fmt.Errorf("missing name")
+	}
+	name, err := unquoteTextString(body[nameMatch[2]:nameMatch[3]])
+	if err != nil {
+		return Permission{}, err
+	}
+	permission := Permission{Name: name, Effect: EffectAllow}
+
+	if effectMatch := findOutsideStrings(permissionEffectRegex, body); effectMatch != nil {
+		value := body[effectMatch[2]:effectMatch[3]]
+		effect, ok := effectFromEnum[parseTextEnum(value, effectNames)]
+		if !ok {
+			return Permission{}, fmt.Errorf("unknown effect value %s", value)
+		}
+		permission.Effect = effect
+	}
+
+	return permission, nil
+}
+
+// extractHTTPInfo extracts the HTTP bindings from google.api.http annotation.
+func (p *Parser) extractHTTPInfo(method *protogen.Method) ([]httpBinding, error) {
+
+	// Try to get HTTP info from the method options
+	methodOpts := method.Desc.Options().(*descriptorpb.MethodOptions)
+	p.debugf("methodOpts: %v", methodOpts)
+	// Check if google.api.http extension exists
+	if proto.HasExtension(methodOpts, annotations.E_Http) {
+		httpRule := proto.GetExtension(methodOpts, annotations.E_Http)
+		p.debugf("httpRule: %v", httpRule)
+		if httpRule != nil {
+			return p.extractHTTPInfoFromRule(httpRule)
+		}
+	}
+
+	// If no HTTP extension found, return error
+	return nil, errNoHTTPAnnotation
+}
+
+// extractHTTPInfoFromRule extracts the primary binding and every additional binding from HTTP rule.
+func (p *Parser) extractHTTPInfoFromRule(httpRule any) ([]httpBinding, error) {
+	// The HTTP rule should be a message containing HTTP info
+	msg, ok := httpRule.(protoreflect.ProtoMessage)
+	if !ok {
+		return nil, fmt.Errorf("HTTP rule is not a proto message")
+	}
+	p.debugf("extractHTTPInfoFromRule: %v", msg)
+
+	reflectMsg := msg.ProtoReflect()
+	binding, err := p.extractHTTPBinding(reflectMsg)
+	if err != nil {
+		return nil, err
+	}
+	bindings := []httpBinding{binding}
+
+	// Each additional binding maps the same method to another route
+	additionalField := reflectMsg.Descriptor().Fields().ByName("additional_bindings")
+	if additionalField != nil && reflectMsg.Has(additionalField) {
+		additionalBindings := reflectMsg.Get(additionalField).List()
+		for i := range additionalBindings.Len() {
+			additional, err := p.extractHTTPBinding(additionalBindings.Get(i).Message())
+			if err != nil {
+				return nil, fmt.Errorf("invalid additional binding %d: %w", i, err)
+			}
+			bindings = append(bindings, additional)
+		}
+	}
+
+	for i := range bindings {
+		if err := validatePathTemplate(bindings[i].Path); err != nil {
+			return nil, fmt.Errorf("invalid path template %q: %w", bindings[i].Path, err)
+		}
+		bindings[i].PathParams, bindings[i].PathParamPatterns, bindings[i].PathParamFields = pathParams(bindings[i].Path)
+	}
+
+	return bindings, nil
+}
+
+// PathParamName returns the flat name of the path variable bound to a field path, e.g. item_id for item.id.
+func PathParamName(fieldPath string) string {
+	return strings.NewReplacer(".", "_", "-", "_").Replace(fieldPath)
+}
+
+// pathParams returns the flat names of the variables of a path template in order, along with the pattern of
+// the ones declaring one and the field path of the nested ones. For instance /v1/{name=projects/*}/items/{item.id}
+// has the variables name and item_id, name having the pattern projects/* and item_id the field path item.id.
+func pathParams(path string) (names []string, patterns, fields map[string]string) {
+	for _, match := range templateVariableRegex.FindAllStringSubmatch(path, -1) {
+		fieldPath := strings.TrimSpace(match[1])
+		name := PathParamName(fieldPath)
+		names = append(names, name)
+		if match[2] != "" {
+			if patterns == nil {
+				patterns = make(map[string]string)
+			}
+			patterns[name] = match[2]
+		}
+		if fieldPath != name {
+			if fields == nil {
+				fields = make(map[string]string)
+			}
+			fields[name] = fieldPath
+		}
+	}
+	return names, patterns, fields
+}
+
+// validatePathParamFields checks that every variable of the path template of a binding is bound to a field of
+// the request message, nested field paths such as item.id included, and that the field is neither repeated nor a map.
+func validatePathParamFields(request protoreflect.MessageDescriptor, binding httpBinding) error {
+	for _, name := range binding.PathParams {
+		fieldPath, ok := binding.PathParamFields[name]
+		if !ok {
+			fieldPath = name
+		}
+		if err := resolveFieldPath(request, fieldPath); err != nil {
+			return fmt.Errorf("path variable %s: %w", fieldPath, err)
+		}
+	}
+	return nil
+}
+
+// resolveFieldPath checks that a field path such as item.id references a singular field of message.
+func resolveFieldPath(message protoreflect.MessageDescriptor, fieldPath string) error {
+	segments := strings.Split(fieldPath, ".")
+	for i, segment := range segments {
+		field := message.Fields().ByName(protoreflect.Name(segment))
+		switch {
+		case field == nil:
+			return fmt.Errorf("no field %s in %s", segment, message.FullName())
+		case field.IsMap():
+			return fmt.Errorf("field %s is a map", field.FullName())
+		case field.IsList():
+			return fmt.Errorf("field %s is repeated", field.FullName())
+		case i < len(segments)-1 && field.Kind() != protoreflect.MessageKind:
+			return fmt.Errorf("field %s is not a message", field.FullName())
+		}
+		message = field.Message()
+	}
+	return nil
+}
+
+// httpRuleVerbs lists the verb fields of a HTTP rule message in the order they are looked up, the pattern oneof
+// holding at most one of them. The other fields, selector, body, response_body and additional_bindings, never
+// declare a verb.
+var httpRuleVerbs = []struct {
+	field  protoreflect.Name
+	method string
+}{
+	{"get", "GET"},
+	{"post", "POST"},
+	{"put", "PUT"},
+	{"delete", "DELETE"},
+	{"patch", "PATCH"},
+}
+
+// extractHTTPBinding extracts path and method from a single HTTP rule message.
+func (p *Parser) extractHTTPBinding(reflectMsg protoreflect.Message) (httpBinding, error) {
+	fields := reflectMsg.Descriptor().Fields()
+
+	p.debugf("reflectMsg = %v", reflectMsg.Descriptor().FullName())
+	// Verbs are checked in a fixed order rather than in field declaration order
+	for _, verb := range httpRuleVerbs {
+		field := fields.ByName(verb.field)
+		if field == nil || !reflectMsg.Has(field) {
+			continue
+		}
+		path := reflectMsg.Get(field).String()
+		return withHTTPBodies(reflectMsg, httpBinding{Path: path, Method: verb.method}), nil
+	}
+
+	// Non-standard verbs such as OPTIONS or HEAD are declared in a nested CustomHttpPattern
+	if field := fields.ByName("custom"); field != nil && reflectMsg.Has(field) {
+		custom := reflectMsg.Get(field).Message()
+		customFields := custom.Descriptor().Fields()
+		kind := custom.Get(customFields.ByName("kind")).String()
+		path := custom.Get(customFields.ByName("path")).String()
+		if kind == "" {
+			return httpBinding{}, fmt.Errorf("custom HTTP pattern without kind for path %s", path)
+		}
+		return withHTTPBodies(reflectMsg, httpBinding{Path: path, Method: strings.ToUpper(kind)}), nil
+	}
+
+	if binding := withHTTPBodies(reflectMsg, httpBinding{}); binding.Body != "" || binding.ResponseBody != "" {
+		return httpBinding{}, fmt.Errorf("HTTP rule declares a body or response_body but no HTTP method")
+	}
+	return httpBinding{}, fmt.Errorf("no HTTP method found in rule")
+}
+
+// withHTTPBodies sets the body and response_body fields of a HTTP rule message on its binding.
+func withHTTPBodies(reflectMsg protoreflect.Message, binding httpBinding) httpBinding {
+	fields := reflectMsg.Descriptor().Fields()
+	if field := fields.ByName("body"); field != nil {
+		binding.Body = reflectMsg.Get(field).String()
+	}
+	if field := fields.ByName("response_body"); field != nil {
+		binding.ResponseBody = reflectMsg.Get(field).String()
+	}
+
+	return binding
+}
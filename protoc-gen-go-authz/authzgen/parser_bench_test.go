@@ -0,0 +1,83 @@
+package authzgen
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// benchProtoSource returns the source of a proto file declaring a service with the given number of methods, each
+// with an HTTP annotation and an authz option.
+func benchProtoSource(methods int) string {
+	var source strings.Builder
+	source.WriteString("syntax = \"proto3\";\n\npackage bench.v1;\n\nservice BenchService {\n")
+	for i := range methods {
+		fmt.Fprintf(&source, "  rpc Method%d(Request) returns (Response) {\n", i)
+		fmt.Fprintf(&source, "    option (google.api.http) = {get: \"/v1/method%d/{id}\"};\n", i)
+		fmt.Fprintf(&source, "    option (proto.v1.authz) = {permissions: [\"method%d:read\"]};\n", i)
+		source.WriteString("  }\n\n")
+	}
+	source.WriteString("}\n")
+	return source.String()
+}
+
+// BenchmarkExtractAuthzFromProtoFile extracts the options of every method of a file, with the file cache of a
+// single parser and, as without it, with a new parser reading and scanning the file again for each method.
+func BenchmarkExtractAuthzFromProtoFile(b *testing.B) {
+	for _, methods := range []int{40, 500} {
+		protoPath := writeTestProto(b, benchProtoSource(methods))
+		for _, cached := range []bool{true, false} {
+			b.Run(fmt.Sprintf("methods=%d/cached=%v", methods, cached), func(b *testing.B) {
+				reads := 0
+				for b.Loop() {
+					parser := newTestParser(nil)
+					for i := range methods {
+						if !cached {
+							parser = newTestParser(nil)
+						}
+						if _, err := parser.extractAuthzFromProtoFile(protoPath, "BenchService", fmt.Sprintf("Method%d", i)); err != nil {
+							b.Fatal(err)
+						}
+						if !cached || i == 0 {
+							reads++
+						}
+					}
+				}
+				b.ReportMetric(float64(reads)/float64(b.N), "reads/op")
+			})
+		}
+	}
+}
+
+// BenchmarkLocateMethods locates the body of every method of a file, with a pattern compiled per method as the
+// scanner used to, and with the single pass of scanServiceBody indexing every rpc.
+func BenchmarkLocateMethods(b *testing.B) {
+	const methods = 500
+	source := maskComments(benchProtoSource(methods))
+	b.Run("regexp per method", func(b *testing.B) {
+		for b.Loop() {
+			for i := range methods {
+				rpcRegex := regexp.MustCompile(`\brpc\s+Method` + strconv.Itoa(i) + `\s*\([^)]*\)\s*returns\s*\([^)]*\)\s*\{`)
+				match := rpcRegex.FindStringIndex(source)
+				if match == nil {
+					b.Fatalf("method %d not found", i)
+				}
+				if _, _, ok := blockBody(source, match[1]); !ok {
+					b.Fatalf("unmatched braces in method %d", i)
+				}
+			}
+		}
+	})
+	b.Run("indexed", func(b *testing.B) {
+		for b.Loop() {
+			scanned := scanServiceBody(source)
+			for i := range methods {
+				if _, ok := scanned.methods["Method"+strconv.Itoa(i)]; !ok {
+					b.Fatalf("method %d not found", i)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,794 @@
+package authzgen
+
+import (
+	"cmp"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestParserSourceFallback(t *testing.T) {
+	plugin := newTestPlugin(t, nil, "proto/v1/defaults.proto")
+	file := testFile(t, plugin, "proto/v1/defaults.proto")
+	want, err := NewParser(plugin.Files, DefaultExtensionNames, DefaultExtensionNumber).ParseFile(file)
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+
+	// Without the files declaring it, the extension of the options is unknown to the parser
+	parser := NewParser(nil, DefaultExtensionNames, DefaultExtensionNumber)
+	if _, err := parser.ParseFile(file); !errors.Is(err, errAuthzExtensionNotDeclared) {
+		t.Fatalf("ParseFile() without source fallback error = %v, want %v", err, errAuthzExtensionNotDeclared)
+	}
+
+	// The method, service and file options are all read from the source, relative to the working directory
+	t.Chdir(testProtoRoot)
+	parser = NewParser(nil, DefaultExtensionNames, DefaultExtensionNumber)
+	parser.SourceFallback = true
+	got, err := parser.ParseFile(file)
+	if err != nil {
+		t.Fatalf("ParseFile() with source fallback error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseFile() with source fallback = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseFileWarnings(t *testing.T) {
+	plugin := newTestPlugin(t, nil, "proto/v1/test.proto")
+	rules, warnings, err := ParseFile(testFile(t, plugin, "proto/v1/test.proto"))
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+	if len(rules) == 0 {
+		t.Fatal("ParseFile() returned no rule")
+	}
+	want := "skipping method proto.v1.TestService.TestWithNothing: no authz option"
+	if !slices.ContainsFunc(warnings, func(warning Warning) bool { return warning.Message == want }) {
+		t.Errorf("ParseFile() warnings = %v, want %q", warnings, want)
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	rules := parseTestFiles(t, nil, "proto/v1/test.proto")
+	rule := findRule(t, rules, "proto.v1.TestService.TestWithScopes")
+	if want := []string{"read:all"}; !slices.Equal(rule.Permissions, want) {
+		t.Errorf("Permissions = %v, want %v", rule.Permissions, want)
+	}
+	if want := []string{"read:test"}; !slices.Equal(rule.AllowedScopes, want) {
+		t.Errorf("AllowedScopes = %v, want %v", rule.AllowedScopes, want)
+	}
+}
+
+// newTestParser returns a parser of the default authz extensions declared in the files of plugin.
+func newTestParser(files []*protogen.File) *Parser {
+	return NewParser(files, DefaultExtensionNames, DefaultExtensionNumber)
+}
+
+func TestParseGRPCFallback(t *testing.T) {
+	plugin := newTestPlugin(t, nil, "proto/v1/test.proto")
+	file := testFile(t, plugin, "proto/v1/test.proto")
+
+	// Methods without HTTP annotation get a rule of the gRPC transport, keyed by their gRPC path
+	parser := newTestParser(plugin.Files)
+	rules, err := parser.ParseFile(file)
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+	grpcRules := make(map[string]Rule)
+	for _, rule := range rules {
+		if rule.Transport == TransportGRPC {
+			grpcRules[rule.GRPCMethod] = rule
+		}
+	}
+	rule, ok := grpcRules["/proto.v1.TestGRPCService/TestGRPCWithPermissions"]
+	if !ok {
+		t.Fatalf("ParseFile() returned no gRPC rule for TestGRPCWithPermissions: %+v", rules)
+	}
+	if rule.HTTPPath != "" || rule.HTTPMethod != "" {
+		t.Errorf("HTTP binding = %s %s, want none", rule.HTTPMethod, rule.HTTPPath)
+	}
+	if want := []string{"read:all"}; !slices.Equal(rule.Permissions, want) {
+		t.Errorf("Permissions = %v, want %v", rule.Permissions, want)
+	}
+	if rule := grpcRules["/proto.v1.TestGRPCService/TestGRPCNoPermissions"]; !rule.NoAuthRequired {
+		t.Errorf("NoAuthRequired = false, want true")
+	}
+
+	// Without the fallback, they are skipped
+	parser.GRPCFallback = false
+	rules, err = parser.ParseFile(file)
+	if err != nil {
+		t.Fatalf("ParseFile() without gRPC fallback error = %v", err)
+	}
+	for _, rule := range rules {
+		if rule.Transport != TransportHTTP {
+			t.Errorf("ParseFile() without gRPC fallback returned the rule of %s", rule.GRPCMethod)
+		}
+	}
+	// The rules of the HTTP bindings are named after their gRPC method as well
+	if rule := findRule(t, rules, "proto.v1.TestService.TestWithPermissions"); rule.GRPCMethod != "/proto.v1.TestService/TestWithPermissions" {
+		t.Errorf("GRPCMethod = %q, want /proto.v1.TestService/TestWithPermissions", rule.GRPCMethod)
+	}
+}
+
+func TestParseDefaults(t *testing.T) {
+	rules := parseTestFiles(t, nil, "proto/v1/defaults.proto")
+	tests := []struct {
+		method         protoreflect.FullName
+		permissions    []string
+		noAuthRequired bool
+		level          Level
+	}{
+		// The service default applies to the methods without authz option of their own
+		{"proto.v1.TestDefaultsService.TestDefaultOnly", []string{"admin:all"}, false, LevelService},
+		// A method option overrides the service default, no_auth_required included
+		{"proto.v1.TestDefaultsService.TestDefaultOverride", []string{"read:all"}, false, LevelMethod},
+		{"proto.v1.TestDefaultsService.TestDefaultOverrideNoAuth", []string{}, true, LevelMethod},
+		// The merge strategy adds the permissions of the method to the service default, but not to public methods
+		{"proto.v1.TestMergeDefaultsService.TestMergeDefault", []string{"admin:all", "read:all"}, false, LevelMethod},
+		{"proto.v1.TestMergeDefaultsService.TestMergeDefaultNoAuth", []string{}, true, LevelMethod},
+		// Services without default of their own fall back to the file default
+		{"proto.v1.TestWithoutDefaultsService.TestWithoutDefault", []string{"internal:all"}, false, LevelFile},
+		{"proto.v1.TestWithoutDefaultsService.TestWithoutDefaultWithPermissions", []string{"read:all"}, false, LevelMethod},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.method.Name()), func(t *testing.T) {
+			rule := findRule(t, rules, tt.method)
+			if !slices.Equal(rule.Permissions, tt.permissions) {
+				t.Errorf("Permissions = %v, want %v", rule.Permissions, tt.permissions)
+			}
+			if rule.NoAuthRequired != tt.noAuthRequired {
+				t.Errorf("NoAuthRequired = %v, want %v", rule.NoAuthRequired, tt.noAuthRequired)
+			}
+			if rule.Level != tt.level {
+				t.Errorf("Level = %q, want %q", rule.Level, tt.level)
+			}
+		})
+	}
+}
+
+// parseTestSources compiles the proto file as newTestPlugin does and returns the rules read from its source, relative
+// to testProtoRoot, by a parser unaware of the authz extensions.
+func parseTestSources(t *testing.T, path string) []Rule {
+	t.Helper()
+	file := testFile(t, newTestPlugin(t, nil, path), path)
+	t.Chdir(testProtoRoot)
+	parser := newTestParser(nil)
+	parser.SourceFallback = true
+	rules, err := parser.ParseFile(file)
+	if err != nil {
+		t.Fatalf("ParseFile(%s) with source fallback error = %v", path, err)
+	}
+	return rules
+}
+
+func TestParseStreamingSource(t *testing.T) {
+	// The declaration of the bidi streaming method spans several lines, with comments and fully-qualified types
+	rules := parseTestSources(t, "proto/v1/streaming.proto")
+	rule := findRule(t, rules, "proto.v1.TestStreamingService.TestBidiStreaming")
+	if want := []string{"stream:all"}; !slices.Equal(rule.Permissions, want) {
+		t.Errorf("Permissions = %v, want %v", rule.Permissions, want)
+	}
+	if rule.StreamingType != StreamingBidi {
+		t.Errorf("StreamingType = %q, want %q", rule.StreamingType, StreamingBidi)
+	}
+	if rule := findRule(t, rules, "proto.v1.TestStreamingService.TestServerStreaming"); !rule.NoAuthRequired || rule.StreamingType != StreamingServer {
+		t.Errorf("NoAuthRequired = %v, StreamingType = %q, want true, %q", rule.NoAuthRequired, rule.StreamingType, StreamingServer)
+	}
+}
+
+func TestScanServiceBodyDeclarations(t *testing.T) {
+	tests := []struct {
+		name        string
+		declaration string
+	}{
+		{"unary", `rpc Get(GetRequest) returns (GetResponse) {`},
+		{"client streaming", `rpc Get(stream GetRequest) returns (GetResponse) {`},
+		{"server streaming", `rpc Get(GetRequest) returns (stream GetResponse) {`},
+		{"multi-line", "rpc Get(\n  stream GetRequest\n)\n  returns (\n    stream GetResponse\n  )\n{"},
+		{"comments", "rpc /* v2 */ Get(GetRequest) // unary\n returns (GetResponse) {"},
+		{"fully-qualified types", `rpc Get(.proto.v1.GetRequest) returns (proto.v1.GetResponse) {`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanned := scanServiceBody("\n  " + tt.declaration + "\n    option deprecated = true;\n  }\n")
+			if body, ok := scanned.methods["Get"]; !ok || !strings.Contains(body, "option deprecated = true;") {
+				t.Errorf("scanServiceBody() methods = %q, want the body of Get", scanned.methods)
+			}
+		})
+	}
+}
+
+func TestExtractAuthzFromProtoFileSameMethodName(t *testing.T) {
+	source := `syntax = "proto3";
+
+service FirstService {
+  rpc Get(GetRequest) returns (GetResponse) {
+    option (proto.v1.authz) = {permissions: ["first:read"]};
+  }
+}
+
+service SecondService {
+  rpc Get(GetRequest) returns (GetResponse) {
+    option (proto.v1.authz) = {permissions: ["second:read"]};
+  }
+}
+`
+	protoPath := writeTestProto(t, source)
+
+	// Each service gets the options of its own method, rather than the ones of the first method of the file
+	parser := newTestParser(nil)
+	for service, want := range map[string]string{"FirstService": "first:read", "SecondService": "second:read"} {
+		options, err := parser.extractAuthzFromProtoFile(protoPath, service, "Get")
+		if err != nil {
+			t.Fatalf("extractAuthzFromProtoFile(%s) error = %v", service, err)
+		}
+		if !slices.Equal(options.Permissions, []string{want}) {
+			t.Errorf("extractAuthzFromProtoFile(%s) permissions = %v, want [%s]", service, options.Permissions, want)
+		}
+	}
+}
+
+func TestExtractAuthzFromProtoFileCommentedOut(t *testing.T) {
+	source := `syntax = "proto3";
+
+service UserService {
+  // rpc Delete(DeleteRequest) returns (Empty) {
+  //   option (proto.v1.authz) = {no_auth_required: true};
+  /* rpc Delete(DeleteRequest) returns (Empty) {
+    option (proto.v1.authz) = {permissions: ["users:legacy"]};
+  } */
+  rpc Delete(DeleteRequest) returns (Empty) {
+    // option (proto.v1.authz) = {no_auth_required: true};
+    option (proto.v1.authz) = {permissions: ["users:delete"]};
+  }
+}
+`
+	// The older versions of the method, commented out, are ignored whether their braces are balanced or not
+	parser := newTestParser(nil)
+	options, err := parser.extractAuthzFromProtoFile(writeTestProto(t, source), "UserService", "Delete")
+	if err != nil {
+		t.Fatalf("extractAuthzFromProtoFile() error = %v", err)
+	}
+	if want := []string{"users:delete"}; !slices.Equal(options.allPermissions(), want) || options.NoAuthRequired {
+		t.Errorf("extractAuthzFromProtoFile() = %+v, want permissions %v", options, want)
+	}
+}
+
+// writeTestProto writes the source of a proto file to a temporary directory and returns its path.
+func writeTestProto(t testing.TB, source string) string {
+	t.Helper()
+	protoPath := filepath.Join(t.TempDir(), "test.proto")
+	if err := os.WriteFile(protoPath, []byte(source), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return protoPath
+}
+
+func TestParseMultiServiceSource(t *testing.T) {
+	// Both services declare a List method, each rule gets the permissions of its own service. The commented-out
+	// versions of TestUsersService.List are ignored, and the } in the comment of TestGroupsService.List does not end
+	// its body, which would leave the method without HTTP binding
+	rules := parseTestSources(t, "proto/v1/multi_service.proto")
+	for method, want := range map[protoreflect.FullName][]string{
+		"proto.v1.TestUsersService.List":  {"users:list"},
+		"proto.v1.TestGroupsService.List": {"groups:list"},
+	} {
+		rule := findRule(t, rules, method)
+		if !slices.Equal(rule.Permissions, want) || rule.NoAuthRequired || rule.HTTPPath == "" {
+			t.Errorf("%s: Permissions = %v, NoAuthRequired = %v, HTTPPath = %q, want %v", method, rule.Permissions, rule.NoAuthRequired, rule.HTTPPath, want)
+		}
+	}
+}
+
+func TestBlockBodyStringLiterals(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"braces", `get: "/v1/{id}"} rest`, `get: "/v1/{id}"`},
+		{"unbalanced brace", `description: "use } alone"} rest`, `description: "use } alone"`},
+		{"escaped quote", `description: "say \"}\" {"} rest`, `description: "say \"}\" {"`},
+		{"single quotes", `description: '{ "}" '} rest`, `description: '{ "}" '`},
+		{"comments", "a: 1 // }\n /* } */ } rest", "a: 1 // }\n /* } */ "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, end, ok := blockBody(tt.text, 0)
+			if !ok || body != tt.want || tt.text[end:] != " rest" {
+				t.Errorf("blockBody() = %q, %d, %v, want %q", body, end, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractAuthzFromProtoFileStringLiterals(t *testing.T) {
+	source := `syntax = "proto3";
+
+service ItemService {
+  rpc Get(GetRequest) returns (GetResponse) {
+    option (google.api.http) = {get: "/v1/items/{item_id}"};
+    option (docs.v1.method) = {description: "returns the item, use } to close {item_id}"};
+    option (docs.v1.method) = {example: "option (proto.v1.authz) = {no_auth_required: true};"};
+    option (proto.v1.authz) = {permissions: ["items:{item_id}:read", "items:read"]};
+  }
+
+  rpc List(ListRequest) returns (ListResponse) {
+    option (proto.v1.authz) = {permissions: ["items:list"]};
+  }
+}
+`
+	// Neither the braces of the permissions nor the one of the description end the blocks they are in, and the
+	// option quoted in the example is not the one of the method
+	parser := newTestParser(nil)
+	parser.PermissionPattern = nil
+	protoPath := writeTestProto(t, source)
+	for method, want := range map[string][]string{"Get": {"items:{item_id}:read", "items:read"}, "List": {"items:list"}} {
+		options, err := parser.extractAuthzFromProtoFile(protoPath, "ItemService", method)
+		if err != nil {
+			t.Fatalf("extractAuthzFromProtoFile(%s) error = %v", method, err)
+		}
+		if got := options.allPermissions(); !slices.Equal(got, want) || options.NoAuthRequired {
+			t.Errorf("extractAuthzFromProtoFile(%s) = %+v, want permissions %v", method, options, want)
+		}
+	}
+}
+
+func TestParsePermissionsString(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"plain", `"users:read", "users:write"`, []string{"users:read", "users:write"}},
+		{"embedded commas", `"a,b", "c"`, []string{"a,b", "c"}},
+		{"escaped quotes", `"say:\"hi\"", "say:\"bye, now\""`, []string{`say:"hi"`, `say:"bye, now"`}},
+		{"escaped backslash", `"a\\", "b"`, []string{`a\`, "b"}},
+		{"escape sequences", `"a\x3ab", "a\072b", "a\u003ab"`, []string{"a:b", "a:b", "a:b"}},
+		{"proto escape sequences", `"a\72b", "a\X3Ab", "what\?"`, []string{"a:b", "a:b", "what?"}},
+		{"multi-line", "\n  \"users:read\",\n  \"users:write\"\n", []string{"users:read", "users:write"}},
+		{"trailing comma", `"users:read",`, []string{"users:read"}},
+		{"empty", ``, []string{}},
+	}
+	// The permissions are not validated, only tokenized
+	parser := newTestParser(nil)
+	parser.PermissionPattern = nil
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			permissions, err := parser.parsePermissionsString(tt.input, false)
+			if err != nil {
+				t.Fatalf("parsePermissionsString(%s) error = %v", tt.input, err)
+			}
+			if got := permissionNames(permissions); !slices.Equal(got, tt.want) {
+				t.Errorf("parsePermissionsString(%s) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePermissionsStringLiterals(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr string
+	}{
+		{name: "single quotes", input: `'users:read', "users:write"`, want: []string{"users:read", "users:write"}},
+		{name: "quotes of the other kind", input: `'"weird"', "'odd'"`, want: []string{`"weird"`, "'odd'"}},
+		{name: "adjacent literals", input: `"users:" 'read', "users:" "wri" "te"`, want: []string{"users:read", "users:write"}},
+		{name: "adjacent literals across lines", input: "\"users:\"\n  \"read\"", want: []string{"users:read"}},
+		{name: "unquoted token", input: `users:read`, wantErr: `unquoted token "users:read" at offset 0`},
+		{name: "unquoted token after a literal", input: `"users:read", users:write`, wantErr: `unquoted token "users:write" at offset 14`},
+		{name: "unterminated string", input: `"users:read`, wantErr: "unterminated string at offset 0"},
+		{name: "leading comma", input: `, "users:read"`, wantErr: "unexpected comma at offset 0"},
+		{name: "double comma", input: `"users:read",, "users:write"`, wantErr: "unexpected comma at offset 13"},
+		{name: "invalid escape", input: `"users\q"`, wantErr: "escape"},
+	}
+	parser := newTestParser(nil)
+	parser.PermissionPattern = nil
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			permissions, err := parser.parsePermissionsString(tt.input, false)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parsePermissionsString(%s) error = %v, want %q", tt.input, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePermissionsString(%s) error = %v", tt.input, err)
+			}
+			if got := permissionNames(permissions); !slices.Equal(got, tt.want) {
+				t.Errorf("parsePermissionsString(%s) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRoles(t *testing.T) {
+	rules := parseTestFiles(t, nil, "proto/v1/test.proto")
+	tests := []struct {
+		method      protoreflect.FullName
+		permissions []string
+		roles       []string
+	}{
+		{"proto.v1.TestService.TestWithRoles", []string{}, []string{"admin", "support"}},
+		{"proto.v1.TestService.TestWithPermissions", []string{"read:all"}, nil},
+		// Both are required when a method declares both
+		{"proto.v1.TestService.TestWithRolesAndPermissions", []string{"write:all"}, []string{"admin"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.method.Name()), func(t *testing.T) {
+			rule := findRule(t, rules, tt.method)
+			if !slices.Equal(rule.Permissions, tt.permissions) {
+				t.Errorf("Permissions = %v, want %v", rule.Permissions, tt.permissions)
+			}
+			if !slices.Equal(rule.Roles, tt.roles) {
+				t.Errorf("Roles = %v, want %v", rule.Roles, tt.roles)
+			}
+		})
+	}
+}
+
+// noAuthConflictSource declares a method listing permissions along with no_auth_required.
+const noAuthConflictSource = `
+syntax = "proto3";
+
+package items.v1;
+
+import "google/api/annotations.proto";
+import "proto/v1/option.proto";
+
+option go_package = "example.com/items/v1";
+
+service ItemService {
+  rpc Get(GetRequest) returns (GetResponse) {
+    option (google.api.http) = {get: "/v1/items/{id}"};
+    option (proto.v1.authz) = {permissions: ["items:read"], no_auth_required: true};
+  }
+}
+
+message GetRequest {
+  string id = 1;
+}
+
+message GetResponse {}
+`
+
+func TestParseNoAuthConflict(t *testing.T) {
+	plugin := newTestPlugin(t, map[string]string{"items.proto": noAuthConflictSource}, "items.proto")
+	file := testFile(t, plugin, "items.proto")
+	const want = "method items.v1.ItemService.Get declares permissions or roles along with no_auth_required"
+
+	parser := newTestParser(plugin.Files)
+	_, err := parser.ParseFile(file)
+	if !errors.Is(err, errInvalidAuthzOption) || !strings.Contains(err.Error(), want) {
+		t.Fatalf("ParseFile() error = %v, want %q", err, want)
+	}
+
+	// With NoAuthConflictWarning, the method is public and the conflict is reported as a warning
+	parser = newTestParser(plugin.Files)
+	parser.NoAuthConflictWarning = true
+	rules, err := parser.ParseFile(file)
+	if err != nil {
+		t.Fatalf("ParseFile() with NoAuthConflictWarning error = %v", err)
+	}
+	if rule := findRule(t, rules, "items.v1.ItemService.Get"); !rule.NoAuthRequired {
+		t.Errorf("NoAuthRequired = false, want true")
+	}
+	warnings := parser.Warnings()
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Message, "method items.v1.ItemService.Get") {
+		t.Errorf("Warnings() = %v, want a warning about method items.v1.ItemService.Get", warnings)
+	}
+}
+
+func TestPathParams(t *testing.T) {
+	tests := []struct {
+		path     string
+		names    []string
+		patterns map[string]string
+		fields   map[string]string
+	}{
+		{"/v1/items/{id}", []string{"id"}, nil, nil},
+		{"/v1/items/{item.id}", []string{"item_id"}, nil, map[string]string{"item_id": "item.id"}},
+		{"/v1/{item.owner.id}/items", []string{"item_owner_id"}, nil, map[string]string{"item_owner_id": "item.owner.id"}},
+		{
+			"/v1/{parent.name=projects/*}/items/{item.id}",
+			[]string{"parent_name", "item_id"},
+			map[string]string{"parent_name": "projects/*"},
+			map[string]string{"parent_name": "parent.name", "item_id": "item.id"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			names, patterns, fields := pathParams(tt.path)
+			if !slices.Equal(names, tt.names) || !reflect.DeepEqual(patterns, tt.patterns) || !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("pathParams() = %v, %v, %v, want %v, %v, %v", names, patterns, fields, tt.names, tt.patterns, tt.fields)
+			}
+		})
+	}
+}
+
+func TestParseNestedPathParams(t *testing.T) {
+	rules := parseTestFiles(t, nil, "proto/v1/test.proto")
+	rule := findRule(t, rules, "proto.v1.TestService.TestWithNestedField")
+	if want := []string{"item_owner_id"}; !slices.Equal(rule.PathParams, want) {
+		t.Errorf("PathParams = %v, want %v", rule.PathParams, want)
+	}
+	if want := map[string]string{"item_owner_id": "item.owner.id"}; !reflect.DeepEqual(rule.PathParamFields, want) {
+		t.Errorf("PathParamFields = %v, want %v", rule.PathParamFields, want)
+	}
+	// The template is kept as declared
+	if want := "/v1/test11/{item.owner.id}"; rule.HTTPPath != want {
+		t.Errorf("HTTPPath = %q, want %q", rule.HTTPPath, want)
+	}
+}
+
+func TestParserSourceFallbackImports(t *testing.T) {
+	// The API is split across two files, the second one importing the messages of the first one, and both
+	// importing the authz extensions from proto/v1/option.proto
+	sources := map[string]string{
+		"acme/v1/users.proto": `
+syntax = "proto3";
+
+package acme.v1;
+
+import "google/api/annotations.proto";
+import "proto/v1/option.proto";
+
+option go_package = "example.com/acme/v1";
+
+service UsersService {
+  rpc Get(GetRequest) returns (GetResponse) {
+    option (google.api.http) = {get: "/v1/users/{id}"};
+    option (proto.v1.authz) = {permissions: ["users:read"]};
+  }
+}
+
+message GetRequest {
+  string id = 1;
+}
+
+message GetResponse {}
+`,
+		"acme/v1/admin.proto": `
+syntax = "proto3";
+
+package acme.v1;
+
+import "acme/v1/users.proto";
+import "google/api/annotations.proto";
+import "proto/v1/option.proto";
+
+option go_package = "example.com/acme/v1";
+
+service AdminService {
+  rpc Get(GetRequest) returns (GetResponse) {
+    option (google.api.http) = {get: "/v1/admin/users/{id}"};
+    option (proto.v1.authz) = {permissions: ["admin:read"]};
+  }
+}
+`,
+	}
+	plugin := newTestPlugin(t, sources, "acme/v1/users.proto", "acme/v1/admin.proto")
+	root := t.TempDir()
+	for path, source := range sources {
+		if err := os.MkdirAll(filepath.Join(root, filepath.Dir(path)), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, path), []byte(source), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The extensions are declared in an imported file rather than the ones being parsed
+	descriptorParser := NewParser(plugin.Files, DefaultExtensionNames, DefaultExtensionNumber)
+	t.Chdir(root)
+	sourceParser := NewParser(nil, DefaultExtensionNames, DefaultExtensionNumber)
+	sourceParser.SourceFallback = true
+	for path, want := range map[string]string{"acme/v1/users.proto": "users:read", "acme/v1/admin.proto": "admin:read"} {
+		file := testFile(t, plugin, path)
+		rules, err := descriptorParser.ParseFile(file)
+		if err != nil {
+			t.Fatalf("ParseFile(%s) error = %v", path, err)
+		}
+		if len(rules) != 1 || !slices.Equal(rules[0].Permissions, []string{want}) || rules[0].SourceFile != path {
+			t.Fatalf("ParseFile(%s) = %+v, want one rule with permission %s", path, rules, want)
+		}
+
+		// The source read is the one of the file declaring the method
+		got, err := sourceParser.ParseFile(file)
+		if err != nil {
+			t.Fatalf("ParseFile(%s) with source fallback error = %v", path, err)
+		}
+		if !reflect.DeepEqual(got, rules) {
+			t.Errorf("ParseFile(%s) with source fallback = %+v, want %+v", path, got, rules)
+		}
+	}
+}
+
+func TestParseNestedPathParamsInvalid(t *testing.T) {
+	sources := map[string]string{"items.proto": `
+syntax = "proto3";
+
+package items.v1;
+
+import "google/api/annotations.proto";
+import "proto/v1/option.proto";
+
+option go_package = "example.com/items/v1";
+
+service ItemService {
+  rpc Get(GetRequest) returns (GetResponse) {
+    option (google.api.http) = {get: "/v1/items/{item.id.value}"};
+    option (proto.v1.authz) = {permissions: ["items:read"]};
+  }
+}
+
+message Item {
+  string id = 1;
+}
+
+message GetRequest {
+  Item item = 1;
+}
+
+message GetResponse {}
+`}
+	// grpc-gateway could not bind the variable, item.id is not a message
+	plugin := newTestPlugin(t, sources, "items.proto")
+	_, err := newTestParser(plugin.Files).ParseFile(testFile(t, plugin, "items.proto"))
+	if err == nil || !strings.Contains(err.Error(), "field items.v1.Item.id is not a message") {
+		t.Errorf("ParseFile() error = %v, want field items.v1.Item.id is not a message", err)
+	}
+}
+
+func TestParseNoAuthConflictDefaults(t *testing.T) {
+	tests := []struct {
+		name    string
+		options string // options of the file, then of the service, the method having none
+		want    string
+	}{
+		{
+			"service default",
+			`service ItemService {
+  option (proto.v1.service_authz) = {permissions: ["items:read"], no_auth_required: true};
+`,
+			"service items.v1.ItemService declares permissions or roles along with no_auth_required",
+		},
+		{
+			"file default",
+			`option (proto.v1.file_authz) = {roles: ["admin"], no_auth_required: true};
+
+service ItemService {
+`,
+			"file items.proto declares permissions or roles along with no_auth_required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := `
+syntax = "proto3";
+
+package items.v1;
+
+import "google/api/annotations.proto";
+import "proto/v1/option.proto";
+
+option go_package = "example.com/items/v1";
+
+` + tt.options + `  rpc Get(GetRequest) returns (GetResponse) {
+    option (google.api.http) = {get: "/v1/items/{id}"};
+  }
+}
+
+message GetRequest {
+  string id = 1;
+}
+
+message GetResponse {}
+`
+			plugin := newTestPlugin(t, map[string]string{"items.proto": source}, "items.proto")
+			_, err := newTestParser(plugin.Files).ParseFile(testFile(t, plugin, "items.proto"))
+			if !errors.Is(err, errInvalidAuthzOption) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseFile() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEnvOverrides(t *testing.T) {
+	rules := parseTestFiles(t, nil, "proto/v1/test.proto")
+	rule := findRule(t, rules, "proto.v1.TestService.TestWithPermissions")
+	want := map[string]RuleOverride{"staging": {Permissions: []string{}, NoAuthRequired: true}}
+	if !reflect.DeepEqual(rule.EnvOverrides, want) {
+		t.Errorf("EnvOverrides = %+v, want %+v", rule.EnvOverrides, want)
+	}
+	if rule := findRule(t, rules, "proto.v1.TestService.TestWithScopes"); rule.EnvOverrides != nil {
+		t.Errorf("EnvOverrides = %+v, want none", rule.EnvOverrides)
+	}
+}
+
+func TestParseAuthzBodyPermissionSyntaxes(t *testing.T) {
+	want := []string{"users:read", "users:write"}
+	bodies := map[string]string{
+		"list":            `permissions: ["users:read", "users:write"]`,
+		"repeated":        `permissions: "users:read" permissions: "users:write"`,
+		"repeated lines":  "permissions: \"users:read\"\n  permissions: 'users:write'",
+		"multi-line list": "permissions: [\n    \"users:read\",\n    // writes included\n    \"users:write\",\n  ]",
+		"mixed":           "permissions: \"users:read\"\n  permissions: [\"users:write\"]",
+	}
+	parser := NewParser(nil, DefaultExtensionNames, DefaultExtensionNumber)
+	for name, body := range bodies {
+		t.Run(name, func(t *testing.T) {
+			options, err := parser.parseAuthzBody(body + "\n  roles: \"admin\" roles: \"support\"")
+			if err != nil {
+				t.Fatalf("parseAuthzBody() error = %v", err)
+			}
+			if got := options.allPermissions(); !slices.Equal(got, want) {
+				t.Errorf("permissions = %v, want %v", got, want)
+			}
+			if want := []string{"admin", "support"}; !slices.Equal(options.Roles, want) {
+				t.Errorf("roles = %v, want %v", options.Roles, want)
+			}
+		})
+	}
+}
+
+func TestParseAuthzBodyTextLiterals(t *testing.T) {
+	// The authz blocks read from the source or from uninterpreted options parse bools and enums as the text format does
+	tests := []struct {
+		body     string
+		noAuth   bool
+		strategy authzStrategy
+		denied   []string
+		wantErr  string
+	}{
+		{body: `no_auth_required: true`, noAuth: true},
+		{body: `no_auth_required: True`, noAuth: true},
+		{body: `no_auth_required: t`, noAuth: true},
+		{body: `no_auth_required: 1`, noAuth: true},
+		{body: `no_auth_required: False permissions: "users:read"`},
+		{body: `no_auth_required: 0 permissions: "users:read"`},
+		{body: `no_auth_required: yes`, wantErr: "invalid no_auth_required value yes"},
+		{body: `defaults_strategy: DEFAULTS_STRATEGY_MERGE permissions: "users:read"`, strategy: authzStrategyMerge},
+		{body: `defaults_strategy: 2 permissions: "users:read"`, strategy: authzStrategyMerge},
+		{body: `defaults_strategy: 3`, wantErr: "unknown defaults_strategy value 3"},
+		{body: `permission_effects: {name: "users:delete", effect: 2}`, denied: []string{"users:delete"}},
+		{body: `permission_effects: {name: "users:delete", effect: EFFECT_DENY}`, denied: []string{"users:delete"}},
+		{body: `permission_effects: {name: "users:delete", effect: 5}`, wantErr: "unknown effect value 5"},
+	}
+	parser := NewParser(nil, DefaultExtensionNames, DefaultExtensionNumber)
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			options, err := parser.parseAuthzBody(tt.body)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseAuthzBody() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseAuthzBody() error = %v", err)
+			}
+			if options.NoAuthRequired != tt.noAuth {
+				t.Errorf("NoAuthRequired = %v, want %v", options.NoAuthRequired, tt.noAuth)
+			}
+			if want := cmp.Or(tt.strategy, authzStrategyReplace); options.Strategy != want {
+				t.Errorf("Strategy = %s, want %s", options.Strategy, want)
+			}
+			if !slices.Equal(options.Denied, tt.denied) {
+				t.Errorf("Denied = %v, want %v", options.Denied, tt.denied)
+			}
+		})
+	}
+}
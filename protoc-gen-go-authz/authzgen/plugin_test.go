@@ -0,0 +1,187 @@
+package authzgen
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// testProtoRoot is the directory the fixture protos, e.g. proto/v1/test.proto, are imported from.
+const testProtoRoot = "../.."
+
+// newTestPlugin compiles the proto files, read from sources by path or else from testProtoRoot, and returns the
+// plugin protoc would run to generate them. Their imports, such as google/api/annotations.proto, are resolved from
+// the sources, testProtoRoot or the descriptors linked in.
+func newTestPlugin(t testing.TB, sources map[string]string, files ...string) *protogen.Plugin {
+	t.Helper()
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(protocompile.CompositeResolver{
+			&protocompile.SourceResolver{Accessor: protocompile.SourceAccessorFromMap(sources)},
+			&protocompile.SourceResolver{ImportPaths: []string{testProtoRoot}},
+			protocompile.ResolverFunc(func(path string) (protocompile.SearchResult, error) {
+				file, err := protoregistry.GlobalFiles.FindFileByPath(path)
+				return protocompile.SearchResult{Desc: file}, err
+			}),
+		}),
+		SourceInfoMode: protocompile.SourceInfoStandard,
+	}
+	compiled, err := compiler.Compile(context.Background(), files...)
+	if err != nil {
+		t.Fatalf("failed to compile %v: %v", files, err)
+	}
+
+	// The request lists every file after its imports, as protoc does
+	request := &pluginpb.CodeGeneratorRequest{FileToGenerate: files}
+	seen := make(map[string]bool)
+	var addFile func(file protoreflect.FileDescriptor)
+	addFile = func(file protoreflect.FileDescriptor) {
+		if seen[file.Path()] {
+			return
+		}
+		seen[file.Path()] = true
+		imports := file.Imports()
+		for i := range imports.Len() {
+			addFile(imports.Get(i).FileDescriptor)
+		}
+		request.ProtoFile = append(request.ProtoFile, protodesc.ToFileDescriptorProto(file))
+	}
+	for _, file := range compiled {
+		addFile(file)
+	}
+
+	// The request is decoded from its wire form like the one read from stdin, the options of the extensions that are
+	// not linked in being kept as unknown fields
+	content, err := proto.Marshal(request)
+	if err != nil {
+		t.Fatalf("failed to encode the request: %v", err)
+	}
+	request = new(pluginpb.CodeGeneratorRequest)
+	if err := proto.Unmarshal(content, request); err != nil {
+		t.Fatalf("failed to decode the request: %v", err)
+	}
+	plugin, err := (protogen.Options{}).New(request)
+	if err != nil {
+		t.Fatalf("failed to create the plugin: %v", err)
+	}
+	return plugin
+}
+
+// parseTestFiles compiles the proto files as newTestPlugin does and returns the rules ParseRules extracts from them
+// with the default options.
+func parseTestFiles(t testing.TB, sources map[string]string, files ...string) []Rule {
+	t.Helper()
+	rules, err := ParseRules(newTestPlugin(t, sources, files...), DefaultOptions())
+	if err != nil {
+		t.Fatalf("failed to parse %v: %v", files, err)
+	}
+	return rules
+}
+
+// testFile returns the file of plugin at path.
+func testFile(t testing.TB, plugin *protogen.Plugin, path string) *protogen.File {
+	t.Helper()
+	file, ok := plugin.FilesByPath[path]
+	if !ok {
+		t.Fatalf("file %s not found", path)
+	}
+	return file
+}
+
+// findRule returns the first rule of the method, given by full name, e.g. proto.v1.TestService.TestWithPermissions.
+func findRule(t testing.TB, rules []Rule, fullMethodName protoreflect.FullName) Rule {
+	t.Helper()
+	for _, rule := range rules {
+		if rule.FullMethodName() == fullMethodName {
+			return rule
+		}
+	}
+	t.Fatalf("no rule for method %s", fullMethodName)
+	return Rule{}
+}
+
+// testModule is the module runGeneratedTests writes the generated Go packages to.
+const testModule = "example.com/authztest"
+
+// runGeneratedTests generates the outputs of plugin with opts, its output package belonging to testModule, writes the
+// Go files of the packages of testModule to a temporary module along with the test files of testdata named by tests,
+// e.g. authzmap/route_trie_test.go, and runs go test on the module with args, e.g. -bench=., returning its output.
+// The packages of the registry and http-middleware targets depend on the standard library only, no module needs to be
+// downloaded.
+func runGeneratedTests(t testing.TB, plugin *protogen.Plugin, opts Options, tests []string, args ...string) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping go test of the generated code in short mode")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skipf("skipping go test of the generated code: %v", err)
+	}
+	opts.OutputGoPackage = path.Join(testModule, opts.OutputDir)
+	if err := Generate(plugin, opts); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	response := plugin.Response()
+	if response.Error != nil {
+		t.Fatalf("Generate() response error = %s", response.GetError())
+	}
+
+	// The files of the output package are written to its directory, the registry files to the path of their package
+	files := map[string]string{"go.mod": "module " + testModule + "\n\ngo 1.24\n"}
+	for _, file := range response.File {
+		name := file.GetName()
+		switch {
+		case !strings.HasSuffix(name, ".go"):
+		case path.Dir(name) == opts.OutputDir:
+			files[name] = file.GetContent()
+		case strings.HasPrefix(name, testModule+"/"):
+			files[strings.TrimPrefix(name, testModule+"/")] = file.GetContent()
+		}
+	}
+	for _, test := range tests {
+		content, err := os.ReadFile(filepath.Join("testdata", filepath.FromSlash(test)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[test] = string(content)
+	}
+	root := t.TempDir()
+	for name, content := range files {
+		name = filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(goTool, append(append([]string{"test"}, args...), "./...")...)
+	cmd.Dir = root
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOWORK=off", "GOTOOLCHAIN=local")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("go test of the generated code failed: %v\n%s", err, output)
+	}
+	return string(output)
+}
+
+// middlewareOptions returns the default options with the http-middleware target, whose package runGeneratedTests
+// can test.
+func middlewareOptions() Options {
+	opts := DefaultOptions()
+	opts.Targets[TargetHTTPMiddleware] = true
+	return opts
+}
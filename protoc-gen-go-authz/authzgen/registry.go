@@ -0,0 +1,136 @@
+package authzgen
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// generateRegistryFiles generates, next to the pb files of every proto declaring rules, a <proto><suffix> file,
+// e.g. user_authz.pb.go, exposing the rules of each of its services so that a server can import only its own.
+// The rules are the ones of the authz map, written to out, along with their HTTP binding. The declarations shared
+// by the Go package, the rules of all its services included, are written in the file of its first proto by path,
+// and protos without rules get no file. The files follow the order of rules, sorted with SortRules, rather than
+// the order of the proto files, so that they do not change with the order protoc is given the files in.
+func generateRegistryFiles(plugin *protogen.Plugin, out outputPackage, rules []Rule, suffix string) error {
+	// Services are looked up by name, along with the file declaring them
+	services := make(map[protoreflect.FullName]*protogen.Service)
+	serviceFiles := make(map[protoreflect.FullName]*protogen.File)
+	for _, file := range plugin.Files {
+		if !file.Generate {
+			continue
+		}
+		for _, service := range file.Services {
+			services[service.Desc.FullName()] = service
+			serviceFiles[service.Desc.FullName()] = file
+		}
+	}
+
+	// Rules are grouped by service, the services by proto file and the files by Go package
+	var files []*protogen.File
+	serviceRules := make(map[protoreflect.FullName][]Rule)
+	fileServices := make(map[*protogen.File][]*protogen.Service)
+	packageTables := make(map[protogen.GoImportPath][]string)
+	for _, rule := range rules {
+		name := rule.ProtoPackage.Append(rule.ServiceName)
+		file, ok := serviceFiles[name]
+		if !ok {
+			continue
+		}
+		if len(serviceRules[name]) == 0 {
+			if len(fileServices[file]) == 0 {
+				files = append(files, file)
+			}
+			fileServices[file] = append(fileServices[file], services[name])
+			packageTables[file.GoImportPath] = append(packageTables[file.GoImportPath], services[name].GoName+"AuthzRules")
+		}
+		serviceRules[name] = append(serviceRules[name], rule)
+	}
+	slices.SortFunc(files, func(a, b *protogen.File) int {
+		return strings.Compare(a.Desc.Path(), b.Desc.Path())
+	})
+
+	// The registry embeds the AuthzRule of the authz map, whose name it shares
+	for _, file := range files {
+		if file.GoImportPath == out.importPath {
+			return fmt.Errorf("registry of %s must not belong to the Go package of the authz map %s", file.Desc.Path(), out.importPath)
+		}
+	}
+
+	declared := make(map[protogen.GoImportPath]bool)
+	for _, file := range files {
+		gen := plugin.NewGeneratedFile(file.GeneratedFilenamePrefix+suffix, file.GoImportPath)
+
+		// File header and package
+		gen.P("// Code generated by protoc-gen-go-authz. DO NOT EDIT.")
+		gen.P()
+		gen.P("package ", file.GoPackageName)
+		gen.P()
+
+		// The declarations of the package are written once, in its first file
+		if !declared[file.GoImportPath] {
+			declared[file.GoImportPath] = true
+			generateRegistryDeclarations(gen, out, packageTables[file.GoImportPath])
+		}
+
+		// Generate the rules of each service
+		for _, service := range fileServices[file] {
+			table := service.GoName + "AuthzRules"
+			gen.P("// ", table, " are the authorization rules of the ", service.Desc.FullName(), " service")
+			gen.P("var ", table, " = []AuthzRule{")
+			for _, rule := range serviceRules[service.Desc.FullName()] {
+				gen.P("	{")
+				if rule.HTTPPath != "" {
+					gen.P("		HTTPPath:   " + strconv.Quote(rule.HTTPPath) + ",")
+					gen.P("		HTTPMethod: " + strconv.Quote(rule.HTTPMethod) + ",")
+				}
+				gen.P("		AuthzRule: ", protogen.GoIdent{GoName: "AuthzRule", GoImportPath: out.importPath}, "{")
+				writeAuthzRuleFields(gen, rule, "			", out.importPath)
+				gen.P("		},")
+				gen.P("	},")
+			}
+			gen.P("}")
+			gen.P()
+		}
+	}
+	return nil
+}
+
+// generateRegistryDeclarations generates the rule type of a registry package, the concatenation of the rules of its
+// services, given by the name of their table, and their lookup by gRPC method.
+func generateRegistryDeclarations(gen *protogen.GeneratedFile, out outputPackage, tables []string) {
+	gen.P("// AuthzRule is the authorization rule of a route, the rule of the authz map along with its HTTP binding")
+	gen.P("// Callers are checked against its permissions, roles and requirement with Check")
+	gen.P("type AuthzRule struct {")
+	gen.P("	HTTPPath   string // empty for gRPC-only methods")
+	gen.P("	HTTPMethod string // empty for gRPC-only methods")
+	gen.P("	", protogen.GoIdent{GoName: "AuthzRule", GoImportPath: out.importPath})
+	gen.P("}")
+	gen.P()
+	gen.P("// AuthzRules are the authorization rules of the services of the package")
+	gen.P("var AuthzRules = ", protogen.GoIdent{GoName: "Concat", GoImportPath: "slices"}, "(", strings.Join(tables, ", "), ")")
+	gen.P()
+
+	// Generate the lookup, every binding of a method shares the same rule so the first one is kept
+	gen.P("// authzRulesByGRPCMethod indexes AuthzRules by gRPC full method name")
+	gen.P("var authzRulesByGRPCMethod = func() map[string]AuthzRule {")
+	gen.P("	rules := make(map[string]AuthzRule, len(AuthzRules))")
+	gen.P("	for _, rule := range AuthzRules {")
+	gen.P("		if _, exists := rules[rule.GRPCMethod]; !exists {")
+	gen.P("			rules[rule.GRPCMethod] = rule")
+	gen.P("		}")
+	gen.P("	}")
+	gen.P("	return rules")
+	gen.P("}()")
+	gen.P()
+	gen.P("// RuleForGRPCMethod returns the rule of a gRPC full method name, e.g. /proto.v1.TestService/TestWithPermissions")
+	gen.P("func RuleForGRPCMethod(fullMethod string) (AuthzRule, bool) {")
+	gen.P("	rule, exists := authzRulesByGRPCMethod[fullMethod]")
+	gen.P("	return rule, exists")
+	gen.P("}")
+	gen.P()
+}
@@ -0,0 +1,45 @@
+package authzgen
+
+import "testing"
+
+func TestGeneratedRegistry(t *testing.T) {
+	sources := map[string]string{"three.proto": `
+syntax = "proto3";
+
+package three.v1;
+
+import "google/api/annotations.proto";
+import "proto/v1/option.proto";
+
+option go_package = "example.com/authztest/registry";
+
+service FirstService {
+  rpc Get(Request) returns (Response) {
+    option (google.api.http) = {get: "/v1/first"};
+    option (proto.v1.authz) = {permissions: ["first:read"]};
+  }
+}
+
+service SecondService {
+  rpc Get(Request) returns (Response) {
+    option (google.api.http) = {get: "/v1/second"};
+    option (proto.v1.authz) = {permissions: ["second:read"]};
+  }
+}
+
+service ThirdService {
+  rpc Get(Request) returns (Response) {
+    option (google.api.http) = {get: "/v1/third"};
+    option (proto.v1.authz) = {permissions: ["third:read"]};
+  }
+}
+
+message Request {}
+
+message Response {}
+`}
+	// Each service gets its own table, in the registry file of the proto
+	opts := middlewareOptions()
+	opts.Targets[TargetRegistry] = true
+	runGeneratedTests(t, newTestPlugin(t, sources, "three.proto"), opts, []string{"registry/registry_test.go"})
+}
@@ -0,0 +1,290 @@
+package authzgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// generateRegoFiles writes, for every proto package, an OPA policy with an allow rule per route under
+// authzmap/rego/<package>/authz.rego, along with authz_test.rego testing that every route allows the callers
+// satisfying its rule and denies the others, for opa test. Rules are expected sorted with SortRules.
+func generateRegoFiles(plugin *protogen.Plugin, out outputPackage, rules []Rule) error {
+	var packages []protoreflect.FullName
+	packageRules := make(map[protoreflect.FullName][]Rule)
+	for _, rule := range rules {
+		if _, ok := packageRules[rule.ProtoPackage]; !ok {
+			packages = append(packages, rule.ProtoPackage)
+		}
+		packageRules[rule.ProtoPackage] = append(packageRules[rule.ProtoPackage], rule)
+	}
+
+	for _, protoPackage := range packages {
+		dir := "rego/" + strings.ReplaceAll(string(protoPackage), ".", "/")
+		header := func(gen *protogen.GeneratedFile) {
+			gen.P("# Code generated by protoc-gen-go-authz. DO NOT EDIT.")
+			gen.P()
+			gen.P("package authz.", protoPackage)
+			gen.P()
+			gen.P("import rego.v1")
+		}
+
+		policy := out.newFile(plugin, dir+"/authz.rego")
+		header(policy)
+		policy.P()
+		policy.P("# Requests are described by input.method and input.path, the HTTP method and path of the request or POST and")
+		policy.P("# the gRPC full method name, input.permissions and input.roles, held by the caller and absent for unauthenticated")
+		policy.P("# callers, and input.request, the request message resolving the placeholders of templated permissions.")
+		policy.P("default allow := false")
+
+		tests := out.newFile(plugin, dir+"/authz_test.rego")
+		header(tests)
+		testNames := make(map[string]int)
+
+		for _, rule := range packageRules[protoPackage] {
+			match := newRegoRoute(rule)
+			for _, body := range regoBodies(rule, match) {
+				policy.P()
+				policy.P("# ", rule.FullMethodName(), " ", rule.Key())
+				policy.P("allow if {")
+				for _, line := range body {
+					policy.P("	", line)
+				}
+				policy.P("}")
+			}
+
+			name := regoSnakeCase(string(rule.ServiceName)) + "_" + regoSnakeCase(string(rule.MethodName))
+			if testNames[name]++; testNames[name] > 1 {
+				name += "_" + strconv.Itoa(testNames[name])
+			}
+			allowed, denied, err := regoTestInputs(rule, match)
+			if err != nil {
+				return fmt.Errorf("failed to generate the Rego tests of %s: %w", rule.FullMethodName(), err)
+			}
+			tests.P()
+			tests.P("test_", name, "_allowed if {")
+			tests.P("	allow with input as ", allowed)
+			tests.P("}")
+			// Public routes deny no caller
+			if denied != "" {
+				tests.P()
+				tests.P("test_", name, "_denied if {")
+				tests.P("	not allow with input as ", denied)
+				tests.P("}")
+			}
+		}
+	}
+	return nil
+}
+
+// regoRoute is the matcher of the path of a rule, along with a path matching it for the tests.
+type regoRoute struct {
+	regex     string            // anchored regular expression matching the path, empty for gRPC rules
+	variables []string          // flat names of the path variables, in the order of their capturing group
+	sample    string            // path matching the rule, every wildcard segment being 1
+	values    map[string]string // values of the path variables in sample
+}
+
+// newRegoRoute returns the matcher of the path of a rule.
+func newRegoRoute(rule Rule) regoRoute {
+	if rule.Transport != TransportHTTP {
+		return regoRoute{sample: rule.GRPCMethod}
+	}
+
+	regex, variables := pathTemplateRegex(rule.HTTPPath)
+	route := regoRoute{regex: "^" + regex + "$", variables: variables, values: make(map[string]string)}
+	wildcards := strings.NewReplacer("**", "1", "*", "1")
+	i := 0
+	route.sample = templateVariableRegex.ReplaceAllStringFunc(rule.HTTPPath, func(variable string) string {
+		pattern := templateVariableRegex.FindStringSubmatch(variable)[2]
+		if pattern == "" {
+			pattern = "*"
+		}
+		value := wildcards.Replace(pattern)
+		route.values[variables[i]] = value
+		i++
+		return value
+	})
+	route.sample = wildcards.Replace(route.sample)
+	return route
+}
+
+// regoBodies returns the bodies of the allow rules of a rule, one per alternative set of permissions.
+func regoBodies(rule Rule, route regoRoute) [][]string {
+	method, path := "POST", "input.path == "+strconv.Quote(rule.GRPCMethod)
+	if route.regex != "" {
+		method, path = rule.HTTPMethod, "regex.match(`"+route.regex+"`, input.path)"
+	}
+	common := []string{"input.method == " + strconv.Quote(method), path}
+	if rule.NoAuthRequired {
+		return [][]string{common}
+	}
+
+	for _, permission := range rule.DeniedPermissions() {
+		common = append(common, "not "+strconv.Quote(permission)+" in input.permissions")
+	}
+	if len(rule.Roles) > 0 {
+		common = append(common, "some role in "+regoArray(rule.Roles, strconv.Quote), "role in input.roles")
+	}
+
+	// Templated permissions are resolved from the path variables captured by the path regex
+	alternatives := securityAlternatives(rule)
+	resolve := func(permission string) string { return regoPermission(permission, route) }
+	withPermissions := func(lines ...string) []string {
+		body := slices.Clone(common)
+		if slices.ContainsFunc(lines, func(line string) bool { return strings.Contains(line, "path_params[") }) {
+			body = append(body, "path_params := regex.find_all_string_submatch_n(`"+route.regex+"`, input.path, 1)[0]")
+		}
+		return append(body, lines...)
+	}
+
+	if !slices.ContainsFunc(alternatives, func(set []string) bool { return len(set) != 1 }) {
+		// Any one of the permissions is enough
+		permissions := make([]string, 0, len(alternatives))
+		for _, set := range alternatives {
+			permissions = append(permissions, set[0])
+		}
+		if len(permissions) == 1 {
+			return [][]string{withPermissions(resolve(permissions[0]) + " in input.permissions")}
+		}
+		return [][]string{withPermissions("some permission in "+regoArray(permissions, resolve), "permission in input.permissions")}
+	}
+
+	bodies := make([][]string, 0, len(alternatives))
+	for _, set := range alternatives {
+		var lines []string
+		if len(set) == 0 {
+			// Any authenticated caller is granted access
+			lines = append(lines, "input.permissions")
+		}
+		for _, permission := range set {
+			lines = append(lines, resolve(permission)+" in input.permissions")
+		}
+		bodies = append(bodies, withPermissions(lines...))
+	}
+	return bodies
+}
+
+// regoPermissionPlaceholderRegex matches the placeholders of a templated permission, e.g. {project_id}.
+var regoPermissionPlaceholderRegex = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// regoPermission returns the Rego expression of a permission, templated permissions being resolved from the path
+// variables or the fields of input.request, e.g. sprintf("project:%v:read", [path_params[1]]).
+func regoPermission(permission string, route regoRoute) string {
+	if !IsTemplatedPermission(permission) {
+		return strconv.Quote(permission)
+	}
+
+	var args []string
+	format := regoPermissionPlaceholderRegex.ReplaceAllStringFunc(strings.ReplaceAll(permission, "%", "%%"), func(placeholder string) string {
+		reference := placeholder[1 : len(placeholder)-1]
+		if i := slices.Index(route.variables, PathParamName(reference)); i >= 0 {
+			args = append(args, "path_params["+strconv.Itoa(i+1)+"]")
+		} else {
+			args = append(args, "object.get(input.request, "+regoArray(strings.Split(reference, "."), strconv.Quote)+", \"\")")
+		}
+		return "%v"
+	})
+	return "sprintf(" + strconv.Quote(format) + ", [" + strings.Join(args, ", ") + "])"
+}
+
+// regoArray formats values as a Rego array, every value being formatted with format.
+func regoArray(values []string, format func(string) string) string {
+	formatted := make([]string, 0, len(values))
+	for _, value := range values {
+		formatted = append(formatted, format(value))
+	}
+	return "[" + strings.Join(formatted, ", ") + "]"
+}
+
+// regoTestInputs returns the inputs of the tests of a rule as JSON objects: a request allowed by the rule, holding
+// the permissions of one of its alternatives and its first role, and a request denied by the rule, of a caller holding
+// no permission or unauthenticated when authentication is enough. denied is empty for public rules.
+func regoTestInputs(rule Rule, route regoRoute) (allowed, denied string, err error) {
+	method := "POST"
+	if rule.Transport == TransportHTTP {
+		method = rule.HTTPMethod
+	}
+	input := map[string]any{"method": method, "path": route.sample}
+	if rule.NoAuthRequired {
+		allowed, err = regoJSON(input)
+		return allowed, "", err
+	}
+
+	// The first alternative with templated permissions is preferred to test their resolution, placeholders being
+	// resolved with the values of the path variables in the sample path, request fields being 1
+	alternatives := securityAlternatives(rule)
+	alternative := alternatives[max(0, slices.IndexFunc(alternatives, func(set []string) bool {
+		return slices.ContainsFunc(set, IsTemplatedPermission)
+	}))]
+	request := make(map[string]any)
+	permissions := []string{}
+	for _, permission := range alternative {
+		permissions = append(permissions, regoPermissionPlaceholderRegex.ReplaceAllStringFunc(permission, func(placeholder string) string {
+			reference := placeholder[1 : len(placeholder)-1]
+			if value, ok := route.values[PathParamName(reference)]; ok {
+				return value
+			}
+			fields := strings.Split(reference, ".")
+			parent := request
+			for _, field := range fields[:len(fields)-1] {
+				if _, ok := parent[field].(map[string]any); !ok {
+					parent[field] = make(map[string]any)
+				}
+				parent = parent[field].(map[string]any)
+			}
+			parent[fields[len(fields)-1]] = "1"
+			return "1"
+		}))
+	}
+
+	allowedInput := map[string]any{"method": method, "path": route.sample, "permissions": permissions}
+	if len(rule.Roles) > 0 {
+		allowedInput["roles"] = rule.Roles[:1]
+	}
+	if len(request) > 0 {
+		allowedInput["request"] = request
+	}
+	if allowed, err = regoJSON(allowedInput); err != nil {
+		return "", "", err
+	}
+
+	if len(permissions) > 0 {
+		input["permissions"] = []string{}
+	}
+	denied, err = regoJSON(input)
+	return allowed, denied, err
+}
+
+// regoJSON formats a value as a JSON literal, which Rego accepts as is.
+func regoJSON(value any) (string, error) {
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(out.String(), "\n"), nil
+}
+
+// regoSnakeCase converts a proto name to a Rego identifier, e.g. test_grpc_service for TestGRPCService.
+func regoSnakeCase(name string) string {
+	runes := []rune(name)
+	var out strings.Builder
+	for i, r := range runes {
+		// Words start at an upper case letter following a lower case one, or preceding one in an acronym
+		if i > 0 && unicode.IsUpper(r) && (!unicode.IsUpper(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			out.WriteByte('_')
+		}
+		out.WriteRune(unicode.ToLower(r))
+	}
+	return out.String()
+}
@@ -0,0 +1,191 @@
+package authzgen
+
+import "google.golang.org/protobuf/compiler/protogen"
+
+// generateRouteTrie generates the trie matching request paths against the path templates of an authz map,
+// in time proportional to the number of path segments rather than to the number of routes.
+func generateRouteTrie(gen *protogen.GeneratedFile) {
+	gen.P("// routeNode is a node of the route trie, each level matching one path segment")
+	gen.P("type routeNode struct {")
+	gen.P("	literals map[string]*routeNode // children matching a literal segment")
+	gen.P("	param    *routeNode            // child matching any single segment, e.g. {id}")
+	gen.P("	routes   map[string]string     // templates ending at this node, keyed by custom verb")
+	gen.P("	globs    map[string]string     // templates matching the remaining segments with **, keyed by custom verb")
+	gen.P("}")
+	gen.P()
+
+	gen.P("// routeTrie matches request paths against the path templates of an authz map, by HTTP method")
+	gen.P("type routeTrie map[string]*routeNode")
+	gen.P()
+
+	gen.P("// generatedRouteTrie is the route trie of the default authz map")
+	gen.P("var generatedRouteTrie = newRouteTrie(generatedAuthzMap)")
+	gen.P()
+
+	gen.P("// newRouteTrie builds the route trie of the HTTP rules of an authz map")
+	gen.P("func newRouteTrie(authzMap map[string]AuthzRule) routeTrie {")
+	gen.P("	trie := make(routeTrie)")
+	gen.P("	for key := range authzMap {")
+	gen.P("		// gRPC rules are keyed by their full method name, without method")
+	gen.P("		template, method, ok := strings.Cut(key, \"|\")")
+	gen.P("		if !ok {")
+	gen.P("			continue")
+	gen.P("		}")
+	gen.P("		if trie[method] == nil {")
+	gen.P("			trie[method] = &routeNode{}")
+	gen.P("		}")
+	gen.P("		trie[method].insert(template)")
+	gen.P("	}")
+	gen.P("	return trie")
+	gen.P("}")
+	gen.P()
+
+	gen.P("// insert adds a path template below the node")
+	gen.P("func (n *routeNode) insert(template string) {")
+	gen.P("	segments, verb := templateSegments(template)")
+	gen.P("	node := n")
+	gen.P("	for _, segment := range segments {")
+	gen.P("		switch segment {")
+	gen.P("		case \"**\":")
+	gen.P("			if node.globs == nil {")
+	gen.P("				node.globs = make(map[string]string)")
+	gen.P("			}")
+	gen.P("			node.globs[verb] = template")
+	gen.P("			return")
+	gen.P("		case \"*\":")
+	gen.P("			if node.param == nil {")
+	gen.P("				node.param = &routeNode{}")
+	gen.P("			}")
+	gen.P("			node = node.param")
+	gen.P("		default:")
+	gen.P("			if node.literals == nil {")
+	gen.P("				node.literals = make(map[string]*routeNode)")
+	gen.P("			}")
+	gen.P("			if node.literals[segment] == nil {")
+	gen.P("				node.literals[segment] = &routeNode{}")
+	gen.P("			}")
+	gen.P("			node = node.literals[segment]")
+	gen.P("		}")
+	gen.P("	}")
+	gen.P("	if node.routes == nil {")
+	gen.P("		node.routes = make(map[string]string)")
+	gen.P("	}")
+	gen.P("	node.routes[verb] = template")
+	gen.P("}")
+	gen.P()
+
+	gen.P("// templateSegments returns the segments of a path template, variables being replaced by their pattern,")
+	gen.P("// * for a single segment and ** for any number of them, along with its custom verb")
+	gen.P("// e.g. /v1/{name=projects/*}/jobs/{id}:cancel is [v1 projects * jobs *] and cancel")
+	gen.P("func templateSegments(template string) ([]string, string) {")
+	gen.P("	var expanded strings.Builder")
+	gen.P("	for len(template) > 0 {")
+	gen.P("		start := strings.IndexByte(template, '{')")
+	gen.P("		end := strings.IndexByte(template, '}')")
+	gen.P("		if start < 0 || end < start {")
+	gen.P("			expanded.WriteString(template)")
+	gen.P("			break")
+	gen.P("		}")
+	gen.P("		expanded.WriteString(template[:start])")
+	gen.P("		if _, pattern, ok := strings.Cut(template[start+1:end], \"=\"); ok {")
+	gen.P("			expanded.WriteString(pattern)")
+	gen.P("		} else {")
+	gen.P("			expanded.WriteString(\"*\")")
+	gen.P("		}")
+	gen.P("		template = template[end+1:]")
+	gen.P("	}")
+	gen.P("	")
+	gen.P("	segments := strings.Split(strings.Trim(expanded.String(), \"/\"), \"/\")")
+	gen.P("	last := segments[len(segments)-1]")
+	gen.P("	if i := strings.LastIndexByte(last, ':'); i >= 0 {")
+	gen.P("		segments[len(segments)-1] = last[:i]")
+	gen.P("		return segments, last[i+1:]")
+	gen.P("	}")
+	gen.P("	return segments, \"\"")
+	gen.P("}")
+	gen.P()
+
+	gen.P("// match returns the template matching a request path and method")
+	gen.P("// Literal segments win over parameters and parameters over **, /v1/users/me over /v1/users/{id}")
+	gen.P("func (t routeTrie) match(path, method string) (string, bool) {")
+	gen.P("	root := t[method]")
+	gen.P("	if root == nil {")
+	gen.P("		return \"\", false")
+	gen.P("	}")
+	gen.P("	segments := strings.Split(strings.Trim(path, \"/\"), \"/\")")
+	gen.P("	")
+	gen.P("	// The last segment may end with a custom verb, e.g. /v1/jobs/123:cancel")
+	gen.P("	last := len(segments) - 1")
+	gen.P("	if i := strings.LastIndexByte(segments[last], ':'); i >= 0 {")
+	gen.P("		withoutVerb := append(segments[:last:last], segments[last][:i])")
+	gen.P("		if template, ok := root.match(withoutVerb, segments[last][i+1:]); ok {")
+	gen.P("			return template, true")
+	gen.P("		}")
+	gen.P("	}")
+	gen.P("	return root.match(segments, \"\")")
+	gen.P("}")
+	gen.P()
+
+	gen.P("// match returns the template matching the remaining segments of a path below the node")
+	gen.P("func (n *routeNode) match(segments []string, verb string) (string, bool) {")
+	gen.P("	if len(segments) == 0 {")
+	gen.P("		if template, ok := n.routes[verb]; ok {")
+	gen.P("			return template, true")
+	gen.P("		}")
+	gen.P("		template, ok := n.globs[verb]")
+	gen.P("		return template, ok")
+	gen.P("	}")
+	gen.P("	if child := n.literals[segments[0]]; child != nil {")
+	gen.P("		if template, ok := child.match(segments[1:], verb); ok {")
+	gen.P("			return template, true")
+	gen.P("		}")
+	gen.P("	}")
+	gen.P("	// Parameters accept any non-empty value")
+	gen.P("	if n.param != nil && segments[0] != \"\" {")
+	gen.P("		if template, ok := n.param.match(segments[1:], verb); ok {")
+	gen.P("			return template, true")
+	gen.P("		}")
+	gen.P("	}")
+	gen.P("	template, ok := n.globs[verb]")
+	gen.P("	return template, ok")
+	gen.P("}")
+	gen.P()
+
+	gen.P("// pathValues returns the values of the variables of a path template in a request path it matches, in order")
+	gen.P("// A variable spans as many segments as its pattern, e.g. projects/p1 for {name=projects/*}, and the remaining")
+	gen.P("// ones with **")
+	gen.P("func pathValues(template, path string) []string {")
+	gen.P("	if _, verb := templateSegments(template); verb != \"\" {")
+	gen.P("		template = strings.TrimSuffix(template, \":\"+verb)")
+	gen.P("		path = strings.TrimSuffix(path, \":\"+verb)")
+	gen.P("	}")
+	gen.P("	segments := strings.Split(strings.Trim(path, \"/\"), \"/\")")
+	gen.P("	var values []string")
+	gen.P("	position := 0")
+	gen.P("	for {")
+	gen.P("		start := strings.IndexByte(template, '{')")
+	gen.P("		end := strings.IndexByte(template, '}')")
+	gen.P("		if start < 0 || end < start {")
+	gen.P("			return values")
+	gen.P("		}")
+	gen.P("		// Skip the literal segments preceding the variable")
+	gen.P("		for _, literal := range strings.Split(template[:start], \"/\") {")
+	gen.P("			if literal != \"\" {")
+	gen.P("				position++")
+	gen.P("			}")
+	gen.P("		}")
+	gen.P("		pattern := \"*\"")
+	gen.P("		if _, declared, ok := strings.Cut(template[start+1:end], \"=\"); ok {")
+	gen.P("			pattern = declared")
+	gen.P("		}")
+	gen.P("		next := min(position+strings.Count(pattern, \"/\")+1, len(segments))")
+	gen.P("		if strings.Contains(pattern, \"**\") {")
+	gen.P("			next = len(segments)")
+	gen.P("		}")
+	gen.P("		values = append(values, strings.Join(segments[min(position, next):next], \"/\"))")
+	gen.P("		position = next")
+	gen.P("		template = template[end+1:]")
+	gen.P("	}")
+	gen.P("}")
+	gen.P()
+}
@@ -0,0 +1,62 @@
+package authzgen
+
+import "testing"
+
+func TestGeneratedRouteTrie(t *testing.T) {
+	runGeneratedTests(t, newTestPlugin(t, nil, testProtoFiles...), middlewareOptions(), []string{"authzmap/route_trie_test.go"})
+}
+
+func TestGeneratedRoutePrecedence(t *testing.T) {
+	sources := map[string]string{"users.proto": `
+syntax = "proto3";
+
+package users.v1;
+
+import "google/api/annotations.proto";
+import "proto/v1/option.proto";
+
+option go_package = "example.com/users/v1";
+
+service UsersService {
+  rpc GetFiles(GetFilesRequest) returns (Response) {
+    option (google.api.http) = {get: "/v1/users/{path=**}"};
+    option (proto.v1.authz) = {permissions: ["users:read_files"]};
+  }
+
+  rpc Get(GetRequest) returns (Response) {
+    option (google.api.http) = {get: "/v1/users/{id}"};
+    option (proto.v1.authz) = {permissions: ["users:read"]};
+  }
+
+  rpc GetSelf(GetSelfRequest) returns (Response) {
+    option (google.api.http) = {get: "/v1/users/me"};
+    option (proto.v1.authz) = {permissions: ["users:read_self"]};
+  }
+}
+
+message GetFilesRequest {
+  string path = 1;
+}
+
+message GetRequest {
+  string id = 1;
+}
+
+message GetSelfRequest {}
+
+message Response {}
+`}
+	// None of the three overlapping routes is ambiguous, each one being more specific than the next one
+	rules := parseTestFiles(t, sources, "users.proto")
+	overlaps := FindOverlaps(rules)
+	if len(overlaps) != 3 {
+		t.Errorf("FindOverlaps() = %d overlaps, want 3", len(overlaps))
+	}
+	for _, overlap := range overlaps {
+		if overlap.Ambiguous() {
+			t.Errorf("%s and %s overlap ambiguously", overlap.A.HTTPPath, overlap.B.HTTPPath)
+		}
+	}
+
+	runGeneratedTests(t, newTestPlugin(t, sources, "users.proto"), middlewareOptions(), []string{"authzmap/route_precedence_test.go"})
+}
@@ -0,0 +1,267 @@
+package authzgen
+
+import (
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Rule represents a single authorization rule.
+// The JSON tags define the document written by the json target.
+type Rule struct {
+	HTTPPath          string                  `json:"http_path"`
+	HTTPMethod        string                  `json:"http_method"`
+	PathParams        []string                `json:"path_params,omitempty"`         // flat names of the variables of the HTTP path template in order, e.g. item_id for /v1/{item.id}
+	PathParamPatterns map[string]string       `json:"path_param_patterns,omitempty"` // pattern of the variables declaring one, e.g. ** for {name=**}
+	PathParamFields   map[string]string       `json:"path_param_fields,omitempty"`   // request field path of the nested variables, e.g. item.id for item_id
+	Body              string                  `json:"body,omitempty"`                // request field mapped to the HTTP body, * for the whole request
+	ResponseBody      string                  `json:"response_body,omitempty"`       // response field mapped to the HTTP body, empty for the whole response
+	GRPCMethod        string                  `json:"grpc_method"`                   // gRPC full method name, e.g. /package.Service/Method
+	Transport         string                  `json:"transport"`                     // http, or grpc for rules of methods without HTTP annotation
+	Permissions       []string                `json:"permissions"`                   // every permission the rule references, including the ones of Require
+	RawPermissions    []string                `json:"raw_permissions,omitempty"`     // permissions as declared, set when wildcards were expanded
+	PermissionEffects []Permission            `json:"permission_effects,omitempty"`  // declared permissions along with their effect, set when some are denied
+	Roles             []string                `json:"roles,omitempty"`               // roles the caller must hold one of, on top of satisfying the permissions
+	AllowedScopes     []string                `json:"allowed_scopes,omitempty"`      // OAuth scopes granting access like Permissions, kept apart from them
+	Require           *PermissionExpr         `json:"require,omitempty"`             // boolean requirement, when set it supersedes the any-of semantics of Permissions and AllowedScopes
+	NoAuthRequired    bool                    `json:"no_auth_required"`
+	EnvOverrides      map[string]RuleOverride `json:"env_overrides,omitempty"` // requirement replacing the one of the rule per environment, e.g. staging
+	Deprecated        bool                    `json:"deprecated,omitempty"`    // whether the method is marked with option deprecated = true
+	Description       string                  `json:"description,omitempty"`   // leading comment of the rpc declaration, without comment markers
+	Level             Level                   `json:"-"`                       // level the authz option was declared at: file, service or method
+	StreamingType     StreamingType           `json:"-"`                       // none, client, server or bidi
+	ProtoPackage      protoreflect.FullName   `json:"proto_package"`           // proto package of the service, e.g. proto.v1
+	ServiceName       protoreflect.Name       `json:"service_name"`            // service of the method the rule was extracted from, e.g. TestService
+	MethodName        protoreflect.Name       `json:"method_name"`             // method the rule was extracted from
+	SourceFile        string                  `json:"source_file,omitempty"`   // proto file declaring the method, empty without source info
+	SourceLine        int                     `json:"source_line,omitempty"`   // 1-based line of the rpc declaration, 0 without source info
+}
+
+// Transports of the rules.
+const (
+	TransportHTTP = "http"
+	TransportGRPC = "grpc"
+)
+
+// Key returns the key of the rule in the generated authorization map,
+// path|METHOD for HTTP rules and the gRPC full method name for gRPC rules.
+func (r Rule) Key() string {
+	if r.Transport == TransportGRPC {
+		return r.GRPCMethod
+	}
+	return r.HTTPPath + "|" + strings.ToUpper(r.HTTPMethod)
+}
+
+// FullMethodName returns the full name of the method the rule was extracted from, e.g. package.Service.Method.
+func (r Rule) FullMethodName() protoreflect.FullName {
+	return r.ProtoPackage.Append(r.ServiceName).Append(r.MethodName)
+}
+
+// SortRules sorts rules by HTTP path and method, then by proto package, service and method for the rules sharing a
+// route or without HTTP binding, so that the order of the generated outputs does not depend on the order the files
+// and bindings were parsed in.
+func SortRules(rules []Rule) {
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].HTTPPath != rules[j].HTTPPath {
+			return rules[i].HTTPPath < rules[j].HTTPPath
+		}
+		if rules[i].HTTPMethod != rules[j].HTTPMethod {
+			return rules[i].HTTPMethod < rules[j].HTTPMethod
+		}
+		if rules[i].ProtoPackage != rules[j].ProtoPackage {
+			return rules[i].ProtoPackage < rules[j].ProtoPackage
+		}
+		if rules[i].ServiceName != rules[j].ServiceName {
+			return rules[i].ServiceName < rules[j].ServiceName
+		}
+		return rules[i].MethodName < rules[j].MethodName
+	})
+}
+
+// DedupeRules removes the rules repeating the route of an earlier rule of the same method, keeping the first one.
+// They come from bindings declared twice, e.g. an additional binding repeating the primary one,
+// and would be duplicate keys of the authorization map.
+func DedupeRules(rules []Rule) []Rule {
+	type ruleID struct {
+		key        string
+		grpcMethod string
+	}
+	seen := make(map[ruleID]bool, len(rules))
+	deduped := rules[:0]
+	for _, rule := range rules {
+		id := ruleID{key: rule.Key(), grpcMethod: rule.GRPCMethod}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		deduped = append(deduped, rule)
+	}
+	return deduped
+}
+
+// RuleOverride is the requirement of a rule in an environment, replacing the one of the rule.
+type RuleOverride struct {
+	Permissions       []string        `json:"permissions"`
+	RawPermissions    []string        `json:"raw_permissions,omitempty"`
+	PermissionEffects []Permission    `json:"permission_effects,omitempty"`
+	Roles             []string        `json:"roles,omitempty"`
+	AllowedScopes     []string        `json:"allowed_scopes,omitempty"`
+	Require           *PermissionExpr `json:"require,omitempty"`
+	NoAuthRequired    bool            `json:"no_auth_required"`
+}
+
+// ForEnv returns the rule in effect in env, the rule itself when env has no override.
+// The returned rule has no override of its own.
+func (r Rule) ForEnv(env string) Rule {
+	override, ok := r.EnvOverrides[env]
+	r.EnvOverrides = nil
+	if !ok {
+		return r
+	}
+	r.Permissions, r.RawPermissions, r.PermissionEffects = override.Permissions, override.RawPermissions, override.PermissionEffects
+	r.Roles, r.AllowedScopes, r.Require, r.NoAuthRequired = override.Roles, override.AllowedScopes, override.Require, override.NoAuthRequired
+	return r
+}
+
+// Envs returns the environments the rule is overridden in, sorted.
+func (r Rule) Envs() []string {
+	return slices.Sorted(maps.Keys(r.EnvOverrides))
+}
+
+// overrideOf returns the requirement of a rule as an override.
+func overrideOf(r Rule) RuleOverride {
+	return RuleOverride{
+		Permissions:       r.Permissions,
+		RawPermissions:    r.RawPermissions,
+		PermissionEffects: r.PermissionEffects,
+		Roles:             r.Roles,
+		AllowedScopes:     r.AllowedScopes,
+		Require:           r.Require,
+		NoAuthRequired:    r.NoAuthRequired,
+	}
+}
+
+// Effects of the permissions.
+const (
+	EffectAllow = "ALLOW"
+	EffectDeny  = "DENY"
+)
+
+// Permission is a permission along with its effect.
+// A caller holding a permission whose effect is DENY is rejected, whatever the other permissions it holds.
+type Permission struct {
+	Name   string `json:"name"`
+	Effect string `json:"effect"` // ALLOW or DENY
+}
+
+// HasTemplatedPermissions reports whether some permissions of the rule reference path variables or request fields.
+func (r Rule) HasTemplatedPermissions() bool {
+	return slices.ContainsFunc(r.Permissions, IsTemplatedPermission)
+}
+
+// PermissionParams returns the path variables or request fields referenced by the placeholders of the permissions
+// of the rule, once each in order of appearance, e.g. project_id for project:{project_id}:read.
+func (r Rule) PermissionParams() []string {
+	var params []string
+	for _, permission := range r.Permissions {
+		for _, placeholder := range PermissionPlaceholders(permission) {
+			if !slices.Contains(params, placeholder) {
+				params = append(params, placeholder)
+			}
+		}
+	}
+	return params
+}
+
+// DeniedPermissions returns the names of the permissions of the rule whose effect is DENY.
+func (r Rule) DeniedPermissions() []string {
+	var denied []string
+	for _, permission := range r.PermissionEffects {
+		if permission.Effect == EffectDeny {
+			denied = append(denied, permission.Name)
+		}
+	}
+	return denied
+}
+
+// PermissionExpr is a boolean combination of permissions.
+// It is satisfied when every non-empty clause is satisfied.
+type PermissionExpr struct {
+	AnyOf []string         `json:"any_of,omitempty"` // at least one of these permissions
+	AllOf []string         `json:"all_of,omitempty"` // every one of these permissions
+	All   []PermissionExpr `json:"all,omitempty"`    // every nested expression
+	Any   []PermissionExpr `json:"any,omitempty"`    // at least one nested expression
+}
+
+// Walk calls fn with every permission referenced by the expression.
+func (e PermissionExpr) Walk(fn func(permission string)) {
+	for _, permission := range e.AnyOf {
+		fn(permission)
+	}
+	for _, permission := range e.AllOf {
+		fn(permission)
+	}
+	for _, nested := range e.All {
+		nested.Walk(fn)
+	}
+	for _, nested := range e.Any {
+		nested.Walk(fn)
+	}
+}
+
+// Level is the level of the proto definition an authz option was declared at.
+type Level string
+
+const (
+	LevelFile    Level = "file"
+	LevelService Level = "service"
+	LevelMethod  Level = "method"
+)
+
+// StreamingType is the streaming kind of a method.
+type StreamingType string
+
+const (
+	StreamingNone   StreamingType = "none"
+	StreamingClient StreamingType = "client"
+	StreamingServer StreamingType = "server"
+	StreamingBidi   StreamingType = "bidi"
+)
+
+// streamingTypeOf returns the streaming kind of a method.
+func streamingTypeOf(method protoreflect.MethodDescriptor) StreamingType {
+	switch {
+	case method.IsStreamingClient() && method.IsStreamingServer():
+		return StreamingBidi
+	case method.IsStreamingClient():
+		return StreamingClient
+	case method.IsStreamingServer():
+		return StreamingServer
+	default:
+		return StreamingNone
+	}
+}
+
+// IsStreamingClient reports whether the client sends a stream of messages, for client and bidi streaming methods.
+func (r Rule) IsStreamingClient() bool {
+	return r.StreamingType == StreamingClient || r.StreamingType == StreamingBidi
+}
+
+// IsStreamingServer reports whether the server sends a stream of messages, for server and bidi streaming methods.
+func (r Rule) IsStreamingServer() bool {
+	return r.StreamingType == StreamingServer || r.StreamingType == StreamingBidi
+}
+
+// httpBinding represents a single HTTP route a method is exposed on.
+type httpBinding struct {
+	Path              string
+	Method            string
+	Body              string
+	ResponseBody      string
+	PathParams        []string
+	PathParamPatterns map[string]string
+	PathParamFields   map[string]string
+}
@@ -0,0 +1,90 @@
+package authzgen
+
+import (
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSortRules(t *testing.T) {
+	// The rules without HTTP binding come first, and the methods sharing a route are ordered by name
+	want := []Rule{
+		{ProtoPackage: "a.v1", ServiceName: "Service", MethodName: "Stream"},
+		{ProtoPackage: "b.v1", ServiceName: "Service", MethodName: "Stream"},
+		{ProtoPackage: "b.v1", ServiceName: "Service", MethodName: "Get", HTTPPath: "/v1/a", HTTPMethod: "GET"},
+		{ProtoPackage: "a.v1", ServiceName: "Service", MethodName: "Get", HTTPPath: "/v1/a", HTTPMethod: "POST"},
+		{ProtoPackage: "a.v1", ServiceName: "Service", MethodName: "List", HTTPPath: "/v1/a", HTTPMethod: "POST"},
+		{ProtoPackage: "a.v1", ServiceName: "Service2", MethodName: "Get", HTTPPath: "/v1/a", HTTPMethod: "POST"},
+		{ProtoPackage: "a.v1", ServiceName: "Service", MethodName: "Get", HTTPPath: "/v1/b", HTTPMethod: "GET"},
+	}
+	random := rand.New(rand.NewPCG(1, 2))
+	for range 5 {
+		rules := slices.Clone(want)
+		random.Shuffle(len(rules), func(i, j int) { rules[i], rules[j] = rules[j], rules[i] })
+		SortRules(rules)
+		if !reflect.DeepEqual(rules, want) {
+			t.Errorf("SortRules() = %+v, want %+v", rules, want)
+		}
+	}
+}
+
+func TestDedupeRules(t *testing.T) {
+	get := Rule{HTTPPath: "/v1/status", HTTPMethod: "GET", GRPCMethod: "/a.v1.Service/Status", Transport: TransportHTTP}
+	other := get
+	other.GRPCMethod = "/a.v1.Service2/Status"
+	post := get
+	post.HTTPMethod = "POST"
+
+	// Only the route repeated by the same method is removed, the one claimed by another method is left to ValidateRules
+	got := DedupeRules([]Rule{get, post, get, other})
+	if want := []Rule{get, post, other}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DedupeRules() = %+v, want %+v", got, want)
+	}
+}
+
+func TestValidateRulesDuplicateRoute(t *testing.T) {
+	sources := map[string]string{"status.proto": `
+syntax = "proto3";
+
+package status.v1;
+
+import "google/api/annotations.proto";
+import "proto/v1/option.proto";
+
+option go_package = "example.com/status/v1";
+
+service HealthService {
+  rpc Status(StatusRequest) returns (StatusResponse) {
+    option (google.api.http) = {get: "/v1/status"};
+    option (proto.v1.authz) = {no_auth_required: true};
+  }
+}
+
+service AdminService {
+  rpc Status(StatusRequest) returns (StatusResponse) {
+    option (google.api.http) = {get: "/v1/status"};
+    option (proto.v1.authz) = {permissions: ["admin:read"]};
+  }
+}
+
+message StatusRequest {}
+
+message StatusResponse {}
+`}
+	_, err := ParseRules(newTestPlugin(t, sources, "status.proto"), DefaultOptions())
+	if err == nil {
+		t.Fatal("ParseRules() error = nil, want a duplicate route")
+	}
+	// The error tells both methods along with the permissions they require
+	for _, want := range []string{
+		"duplicate route GET /v1/status",
+		"status.v1.HealthService.Status (GET /v1/status, no auth required)",
+		"status.v1.AdminService.Status (GET /v1/status, permissions [admin:read])",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseRules() error = %v, want %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package authzgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+// spicedbNameRegex matches the valid SpiceDB definition, relation and permission names.
+var spicedbNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_]{1,62}[a-z0-9]$`)
+
+// spicedbPlaceholderRegex matches a placeholder segment of a templated permission, capturing the path variable or
+// request field holding the ID of the resource, e.g. {project_id}.
+var spicedbPlaceholderRegex = regexp.MustCompile(`^\{([A-Za-z_]\w*(?:\.[A-Za-z_]\w*)*)\}$`)
+
+// spicedbCheck is a SpiceDB permission check, on the resource whose ID is read from ResourceIDParam when set.
+type spicedbCheck struct {
+	ResourceType    string `json:"resource_type"`
+	Permission      string `json:"permission"`
+	ResourceIDParam string `json:"resource_id_param,omitempty"`
+}
+
+// spicedbEndpoint maps a route to the checks granting access to it: every check of any alternative, an empty
+// alternative being satisfied by any authenticated caller, and none of the denied ones.
+type spicedbEndpoint struct {
+	Endpoint       string           `json:"endpoint"`
+	GRPCMethod     string           `json:"grpc_method"`
+	NoAuthRequired bool             `json:"no_auth_required"`
+	Alternatives   [][]spicedbCheck `json:"alternatives"`
+	Denied         []spicedbCheck   `json:"denied,omitempty"`
+	Roles          []string         `json:"roles,omitempty"`
+}
+
+// spicedbUnmapped reports a permission not of the resource:action shape, along with the endpoints referencing it.
+type spicedbUnmapped struct {
+	Permission string   `json:"permission"`
+	Endpoints  []string `json:"endpoints"`
+}
+
+// generateSpiceDBFiles writes the permissions of the rules as a SpiceDB schema, one definition per resource type
+// with a relation and a permission per action granted to subjectType, and the checks granting access to every
+// endpoint. Permissions are split on separator into a resource type and an action, e.g. project:read, the templated
+// ones reading the ID of the resource from the request, e.g. project:{project_id}:read. The other permissions are
+// reported in the checks file and left out of the schema, their alternatives denying access.
+func generateSpiceDBFiles(plugin *protogen.Plugin, out outputPackage, rules []Rule, separator, subjectType string, logger *slog.Logger) error {
+	if separator == "" {
+		return fmt.Errorf("spicedb permission separator cannot be empty")
+	}
+	if !spicedbNameRegex.MatchString(subjectType) {
+		return fmt.Errorf("spicedb subject type %q is not a valid definition name", subjectType)
+	}
+
+	actions := make(map[string]map[string]bool)
+	unmapped := make(map[string]*spicedbUnmapped)
+	// check returns the check of a permission, recording its action or reporting it for endpoint
+	check := func(permission, endpoint string) (spicedbCheck, bool) {
+		check, ok := spicedbPermissionCheck(permission, separator)
+		if !ok {
+			report, ok := unmapped[permission]
+			if !ok {
+				report = &spicedbUnmapped{Permission: permission}
+				unmapped[permission] = report
+			}
+			if !slices.Contains(report.Endpoints, endpoint) {
+				report.Endpoints = append(report.Endpoints, endpoint)
+			}
+			return check, false
+		}
+		if actions[check.ResourceType] == nil {
+			actions[check.ResourceType] = make(map[string]bool)
+		}
+		actions[check.ResourceType][check.Permission] = true
+		return check, true
+	}
+
+	endpoints := make([]spicedbEndpoint, 0, len(rules))
+	for _, rule := range rules {
+		endpoint := spicedbEndpoint{
+			Endpoint:       rule.Key(),
+			GRPCMethod:     rule.GRPCMethod,
+			NoAuthRequired: rule.NoAuthRequired,
+			Alternatives:   [][]spicedbCheck{},
+			Roles:          rule.Roles,
+		}
+		if !rule.NoAuthRequired {
+		alternatives:
+			for _, set := range securityAlternatives(rule) {
+				checks := []spicedbCheck{}
+				for _, permission := range set {
+					c, ok := check(permission, endpoint.Endpoint)
+					if !ok {
+						continue alternatives
+					}
+					checks = append(checks, c)
+				}
+				endpoint.Alternatives = append(endpoint.Alternatives, checks)
+			}
+			for _, permission := range rule.DeniedPermissions() {
+				if c, ok := check(permission, endpoint.Endpoint); ok {
+					endpoint.Denied = append(endpoint.Denied, c)
+				}
+			}
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+
+	schema := out.newFile(plugin, "spicedb/schema.zed")
+	schema.P("// Code generated by protoc-gen-go-authz. DO NOT EDIT.")
+	if actions[subjectType] == nil {
+		schema.P()
+		schema.P("definition ", subjectType, " {}")
+	}
+	for _, resourceType := range slices.Sorted(maps.Keys(actions)) {
+		schema.P()
+		schema.P("definition ", resourceType, " {")
+		for _, action := range slices.Sorted(maps.Keys(actions[resourceType])) {
+			schema.P("	relation ", action, "_grant: ", subjectType)
+			schema.P("	permission ", action, " = ", action, "_grant")
+		}
+		schema.P("}")
+	}
+
+	reports := make([]spicedbUnmapped, 0, len(unmapped))
+	for _, permission := range slices.Sorted(maps.Keys(unmapped)) {
+		reports = append(reports, *unmapped[permission])
+		logger.Warn(fmt.Sprintf("skipping SpiceDB mapping of permission %s: not of the resource%saction shape", permission, separator))
+	}
+	document := struct {
+		Endpoints           []spicedbEndpoint `json:"endpoints"`
+		UnmappedPermissions []spicedbUnmapped `json:"unmapped_permissions"`
+	}{Endpoints: endpoints, UnmappedPermissions: reports}
+	content, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal SpiceDB checks: %w", err)
+	}
+	gen := out.newFile(plugin, "spicedb/checks.json")
+	_, err = gen.Write(append(content, '\n'))
+	return err
+}
+
+// spicedbPermissionCheck returns the check of a permission split on separator, either resource and action or
+// resource, ID placeholder and action. It returns false when the permission has another shape, or when its resource
+// or action, along with the relation granting it, are not valid SpiceDB names.
+func spicedbPermissionCheck(permission, separator string) (spicedbCheck, bool) {
+	var check spicedbCheck
+	parts := strings.Split(permission, separator)
+	switch len(parts) {
+	case 2:
+		check.ResourceType, check.Permission = parts[0], parts[1]
+	case 3:
+		match := spicedbPlaceholderRegex.FindStringSubmatch(parts[1])
+		if match == nil {
+			return check, false
+		}
+		check.ResourceType, check.ResourceIDParam, check.Permission = parts[0], match[1], parts[2]
+	default:
+		return check, false
+	}
+	ok := spicedbNameRegex.MatchString(check.ResourceType) && spicedbNameRegex.MatchString(check.Permission) &&
+		spicedbNameRegex.MatchString(check.Permission+"_grant")
+	return check, ok
+}
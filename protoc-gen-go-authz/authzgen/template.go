@@ -0,0 +1,222 @@
+package authzgen
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// templateVariableRegex matches the variables of an HTTP path template and captures their field path and pattern,
+// e.g. {id} or {name=projects/*}.
+var templateVariableRegex = regexp.MustCompile(`\{([^}=]+)(?:=([^}]*))?\}`)
+
+// validatePathTemplate checks an HTTP path template against the google.api.http grammar:
+//
+//	Template = "/" Segments [ Verb ] ;
+//	Segments = Segment { "/" Segment } ;
+//	Segment  = "*" | "**" | LITERAL | Variable ;
+//	Variable = "{" FieldPath [ "=" Segments ] "}" ;
+//	Verb     = ":" LITERAL ;
+//
+// Variables cannot be nested, and a ** segment, within a variable or not, must be the last segment.
+func validatePathTemplate(template string) error {
+	if !strings.HasPrefix(template, "/") {
+		return errors.New("must start with /")
+	}
+
+	parser := templateParser{input: template, pos: 1}
+	if err := parser.segments(false); err != nil {
+		return err
+	}
+	if parser.peek() == ':' {
+		parser.pos++
+		if verb := parser.literal(); verb == "" {
+			return fmt.Errorf("empty custom verb at offset %d", parser.pos)
+		}
+	}
+	if parser.pos < len(parser.input) {
+		return fmt.Errorf("unexpected %q at offset %d", parser.input[parser.pos], parser.pos)
+	}
+	return nil
+}
+
+// templateParser is a recursive descent parser of the google.api.http path templates.
+type templateParser struct {
+	input string
+	pos   int
+	glob  bool // whether a ** segment was read, no segment can follow it
+}
+
+// peek returns the next byte of the input, 0 at its end.
+func (t *templateParser) peek() byte {
+	if t.pos < len(t.input) {
+		return t.input[t.pos]
+	}
+	return 0
+}
+
+// literal reads the characters up to the next delimiter.
+func (t *templateParser) literal() string {
+	start := t.pos
+	for t.pos < len(t.input) && !strings.ContainsRune("/{}=:", rune(t.input[t.pos])) {
+		t.pos++
+	}
+	return t.input[start:t.pos]
+}
+
+// segments reads segments separated by /, inVariable telling whether they are the pattern of a variable.
+func (t *templateParser) segments(inVariable bool) error {
+	for {
+		if err := t.segment(inVariable); err != nil {
+			return err
+		}
+		if t.peek() != '/' {
+			return nil
+		}
+		t.pos++
+	}
+}
+
+// segment reads a single segment, a variable included.
+func (t *templateParser) segment(inVariable bool) error {
+	if t.glob {
+		return fmt.Errorf("** must be the last segment, found a segment at offset %d", t.pos)
+	}
+
+	if t.peek() == '{' {
+		if inVariable {
+			return fmt.Errorf("nested variable at offset %d", t.pos)
+		}
+		return t.variable()
+	}
+
+	start := t.pos
+	switch segment := t.literal(); {
+	case segment == "":
+		return fmt.Errorf("empty segment at offset %d", start)
+	case segment == "**":
+		t.glob = true
+	case segment != "*" && strings.Contains(segment, "*"):
+		return fmt.Errorf("wildcard %q must span a whole segment", segment)
+	}
+	return nil
+}
+
+// variable reads a variable, its field path and optional pattern.
+func (t *templateParser) variable() error {
+	start := t.pos
+	t.pos++
+	fieldPath := t.literal()
+	for _, ident := range strings.Split(fieldPath, ".") {
+		if !isIdent(ident) {
+			return fmt.Errorf("invalid field path %q in variable at offset %d", fieldPath, start)
+		}
+	}
+	if t.peek() == '=' {
+		t.pos++
+		if err := t.segments(true); err != nil {
+			return err
+		}
+	}
+	if t.peek() != '}' {
+		return fmt.Errorf("unterminated variable at offset %d", start)
+	}
+	t.pos++
+	return nil
+}
+
+// isIdent reports whether s is a proto identifier.
+func isIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// permissionPlaceholderRegex matches the placeholders of a templated permission, capturing the path variable
+// or request field they reference, e.g. {project_id} in project:{project_id}:read.
+var permissionPlaceholderRegex = regexp.MustCompile(`\{([A-Za-z_]\w*(?:\.[A-Za-z_]\w*)*)\}`)
+
+// IsTemplatedPermission reports whether a permission references path variables or request fields,
+// e.g. project:{project_id}:read, resolved at runtime from the request.
+func IsTemplatedPermission(permission string) bool {
+	return permissionPlaceholderRegex.MatchString(permission)
+}
+
+// PermissionPlaceholders returns the path variables or request fields referenced by the placeholders of a templated
+// permission, once each in order of appearance, e.g. project_id for project:{project_id}:read.
+func PermissionPlaceholders(permission string) []string {
+	var placeholders []string
+	for _, match := range permissionPlaceholderRegex.FindAllStringSubmatch(permission, -1) {
+		if !slices.Contains(placeholders, match[1]) {
+			placeholders = append(placeholders, match[1])
+		}
+	}
+	return placeholders
+}
+
+// validatePermissionPlaceholders checks that every placeholder of the permissions of an option references a variable
+// of the path template of binding, resolved from the matched route, or a singular field of the request for methods
+// without HTTP binding. Placeholders are only resolved in the listed permissions, they are rejected in the denied
+// ones, the scopes and the requirement, and in streaming methods whose request the gRPC interceptor cannot read
+// before the handler. The environment overrides are checked as well.
+func validatePermissionPlaceholders(options authzOptions, method protoreflect.MethodDescriptor, binding *httpBinding) error {
+	for _, env := range slices.Sorted(maps.Keys(options.EnvOverrides)) {
+		if err := validatePermissionPlaceholders(options.EnvOverrides[env], method, binding); err != nil {
+			return fmt.Errorf("env_overrides of environment %s: %w", env, err)
+		}
+	}
+
+	var unresolved []string
+	for _, permission := range slices.Concat(options.Denied, options.AllowedScopes) {
+		if IsTemplatedPermission(permission) {
+			unresolved = append(unresolved, permission)
+		}
+	}
+	if options.Require != nil {
+		options.Require.Walk(func(permission string) {
+			if IsTemplatedPermission(permission) {
+				unresolved = append(unresolved, permission)
+			}
+		})
+	}
+	if len(unresolved) > 0 {
+		return fmt.Errorf("%w: placeholders are only supported in permissions, found %s", errInvalidAuthzOption, strings.Join(unresolved, ", "))
+	}
+	if streaming := streamingTypeOf(method); streaming != StreamingNone {
+		for _, permission := range options.Permissions {
+			if IsTemplatedPermission(permission) {
+				return fmt.Errorf("%w %q: placeholders are not supported in %s streaming methods", errInvalidPermission, permission, streaming)
+			}
+		}
+	}
+
+	for _, permission := range options.Permissions {
+		for _, placeholder := range PermissionPlaceholders(permission) {
+			if binding != nil {
+				// Nested variables can be referenced by field path or flat name, e.g. {item.id} or {item_id}
+				if !slices.Contains(binding.PathParams, PathParamName(placeholder)) {
+					return fmt.Errorf("%w %q: {%s} references no variable of the path template", errInvalidPermission, permission, placeholder)
+				}
+				continue
+			}
+			if err := resolveFieldPath(method.Input(), placeholder); err != nil {
+				return fmt.Errorf("%w %q: references no request field: %w", errInvalidPermission, permission, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package authzgen
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTemplatedPermissionsStreaming(t *testing.T) {
+	sources := map[string]string{"streaming.proto": `
+syntax = "proto3";
+
+package streaming.v1;
+
+import "proto/v1/option.proto";
+
+option go_package = "example.com/streaming/v1";
+
+service StreamingService {
+  rpc Watch(WatchRequest) returns (stream WatchResponse) {
+    option (proto.v1.authz) = {permissions: ["foo:{foo_id}:read"]};
+  }
+}
+
+message WatchRequest {
+  string foo_id = 1;
+}
+
+message WatchResponse {}
+`}
+	// The stream interceptor runs before the handler reads the request, the placeholder could not be resolved
+	_, err := ParseRules(newTestPlugin(t, sources, "streaming.proto"), DefaultOptions())
+	if !errors.Is(err, errInvalidPermission) {
+		t.Errorf("ParseRules() error = %v, want %v", err, errInvalidPermission)
+	}
+}
+
+func TestGeneratedTemplatedPermissions(t *testing.T) {
+	sources := map[string]string{"projects.proto": `
+syntax = "proto3";
+
+package projects.v1;
+
+import "google/api/annotations.proto";
+import "proto/v1/option.proto";
+
+option go_package = "example.com/authztest/projects";
+
+service ProjectService {
+  rpc Get(GetRequest) returns (Response) {
+    option (google.api.http) = {get: "/v1/projects/{project_id}"};
+    option (proto.v1.authz) = {permissions: ["project:{project_id}:read"]};
+  }
+
+  rpc GetFile(GetFileRequest) returns (Response) {
+    option (google.api.http) = {get: "/v1/projects/{project_id}/files/{path=**}"};
+    option (proto.v1.authz) = {permissions: ["project:{project_id}:file:{path}:read"]};
+  }
+}
+
+message GetRequest {
+  string project_id = 1;
+}
+
+message GetFileRequest {
+  string project_id = 1;
+  string path = 2;
+}
+
+message Response {}
+`}
+	tests := []string{"authzmap/checker_test.go", "authzmap/templated_permissions_test.go"}
+	runGeneratedTests(t, newTestPlugin(t, sources, "projects.proto"), middlewareOptions(), tests)
+}
@@ -0,0 +1,82 @@
+package authzgen
+
+import "google.golang.org/protobuf/compiler/protogen"
+
+// generateTestHelperFile generates the test helper asserting that the routes registered by a server
+// and the HTTP routes of the authorization map are the same.
+func generateTestHelperFile(plugin *protogen.Plugin, out outputPackage) {
+	gen := out.newGoFile(plugin, "generated_authz_testing.go")
+
+	gen.P("import (")
+	gen.P("	\"slices\"")
+	gen.P("	\"strings\"")
+	gen.P("	\"testing\"")
+	gen.P(")")
+	gen.P()
+
+	gen.P("// AssertAuthzCoverage fails the test when a route of the authorization map is not among registeredRoutes,")
+	gen.P("// e.g. a new RPC without handler, or when a registered route has no rule, e.g. a handler without authz")
+	gen.P("// Routes are written as \"METHOD /path\" with proto path templates or http.ServeMux patterns, e.g. \"GET /v1/users/{id}\"")
+	gen.P("// or \"GET /v1/files/{path...}\", and are compared regardless of the names of their variables")
+	gen.P("// The health check, which needs no rule, is ignored")
+	gen.P("func AssertAuthzCoverage(t testing.TB, registeredRoutes []string) {")
+	gen.P("	t.Helper()")
+	gen.P("	")
+	gen.P("	registered := make(map[string]bool, len(registeredRoutes))")
+	gen.P("	for _, route := range registeredRoutes {")
+	gen.P("		method, path, ok := strings.Cut(route, \" \")")
+	gen.P("		if !ok {")
+	gen.P("			t.Errorf(\"invalid route %q: must be written as \\\"METHOD /path\\\"\", route)")
+	gen.P("			continue")
+	gen.P("		}")
+	gen.P("		registered[normalizeRoute(method, path)] = true")
+	gen.P("	}")
+	gen.P("	")
+	gen.P("	ruled := make(map[string]bool, len(generatedAuthzMap))")
+	gen.P("	for key := range generatedAuthzMap {")
+	gen.P("		// gRPC rules are keyed by their full method name, without method")
+	gen.P("		if path, method, ok := strings.Cut(key, \"|\"); ok {")
+	gen.P("			ruled[normalizeRoute(method, path)] = true")
+	gen.P("		}")
+	gen.P("	}")
+	gen.P("	")
+	gen.P("	var missing, unruled []string")
+	gen.P("	for route := range ruled {")
+	gen.P("		if !registered[route] {")
+	gen.P("			missing = append(missing, route)")
+	gen.P("		}")
+	gen.P("	}")
+	gen.P("	for route := range registered {")
+	gen.P("		if !ruled[route] && route != \"GET /v1/health\" {")
+	gen.P("			unruled = append(unruled, route)")
+	gen.P("		}")
+	gen.P("	}")
+	gen.P("	slices.Sort(missing)")
+	gen.P("	slices.Sort(unruled)")
+	gen.P("	for _, route := range missing {")
+	gen.P("		t.Errorf(\"route %s has an authz rule but is not registered\", route)")
+	gen.P("	}")
+	gen.P("	for _, route := range unruled {")
+	gen.P("		t.Errorf(\"route %s is registered without authz rule\", route)")
+	gen.P("	}")
+	gen.P("}")
+	gen.P()
+
+	gen.P("// normalizeRoute returns the form of a route compared by AssertAuthzCoverage, variables being replaced by")
+	gen.P("// their pattern, e.g. GET /v1/users/* for GET /v1/users/{id} and GET /v1/files/** for GET /v1/files/{path...}")
+	gen.P("func normalizeRoute(method, path string) string {")
+	gen.P("	// http.ServeMux wildcards matching the remaining segments are the ** of path templates")
+	gen.P("	parts := strings.Split(path, \"/\")")
+	gen.P("	for i, part := range parts {")
+	gen.P("		if strings.HasPrefix(part, \"{\") && strings.HasSuffix(part, \"...}\") {")
+	gen.P("			parts[i] = \"**\"")
+	gen.P("		}")
+	gen.P("	}")
+	gen.P("	segments, verb := templateSegments(strings.Join(parts, \"/\"))")
+	gen.P("	normalized := strings.ToUpper(method) + \" /\" + strings.Join(segments, \"/\")")
+	gen.P("	if verb != \"\" {")
+	gen.P("		normalized += \":\" + verb")
+	gen.P("	}")
+	gen.P("	return normalized")
+	gen.P("}")
+}
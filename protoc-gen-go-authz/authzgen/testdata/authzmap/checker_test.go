@@ -0,0 +1,24 @@
+package authzmap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+)
+
+// staticChecker is a caller holding the permissions it lists.
+type staticChecker []string
+
+func (c staticChecker) Permissions(context.Context) ([]string, error) {
+	return c, nil
+}
+
+// okHandler serves every request with a 200.
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+// serve returns the status handler responds to a request with method and path.
+func serve(handler http.Handler, method, path string) int {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+	return recorder.Code
+}
@@ -0,0 +1,61 @@
+package authzmap
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// The routes are the ones of the fixture protos, see TestGeneratedDefaultDeny.
+
+func TestNewMiddlewareUnmatched(t *testing.T) {
+	tests := []struct {
+		name                   string
+		auditOnly, defaultDeny bool
+		path                   string
+		want                   int
+	}{
+		{"default deny", false, true, "/v1/unknown", http.StatusForbidden},
+		{"pass through", false, false, "/v1/unknown", http.StatusOK},
+		{"audit", true, true, "/v1/unknown", http.StatusOK},
+		// The health check is served in both modes
+		{"default deny health check", false, true, "/v1/health", http.StatusOK},
+		{"pass through health check", false, false, "/v1/health", http.StatusOK},
+		// Routes matching a rule are checked in both modes
+		{"default deny matched", false, true, "/v1/test3/42", http.StatusForbidden},
+		{"pass through matched", false, false, "/v1/test3/42", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewMiddleware(okHandler, staticChecker{}, "", tt.auditOnly, tt.defaultDeny)
+			if got := serve(handler, http.MethodGet, tt.path); got != tt.want {
+				t.Errorf("GET %s = %d, want %d", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewMiddlewareUnmatchedAudit(t *testing.T) {
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	// The request is passed through, but logged as the one default deny would deny
+	serve(NewMiddleware(okHandler, staticChecker{}, "", true, true), http.MethodGet, "/v1/unknown")
+	if !strings.Contains(logs.String(), `reason="no authz rule"`) || !strings.Contains(logs.String(), "status=403") {
+		t.Errorf("audit log = %q, want the denial of GET /v1/unknown", logs.String())
+	}
+}
+
+func TestMiddlewareUnmatched(t *testing.T) {
+	want := http.StatusOK
+	if httpDefaultDeny {
+		want = http.StatusForbidden
+	}
+	if got := serve(Middleware(okHandler, staticChecker{}), http.MethodGet, "/v1/unknown"); got != want {
+		t.Errorf("GET /v1/unknown with httpDefaultDeny %v = %d, want %d", httpDefaultDeny, got, want)
+	}
+}
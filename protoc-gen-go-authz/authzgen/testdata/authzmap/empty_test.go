@@ -0,0 +1,20 @@
+package authzmap
+
+import (
+	"net/http"
+	"testing"
+)
+
+// The services declare no annotated method, see TestGeneratedEmptyServices.
+
+func TestEmptyAuthzMap(t *testing.T) {
+	if len(generatedAuthzMap) != 0 || len(generatedRouteTrie) != 0 {
+		t.Fatalf("authz map = %v, want none", generatedAuthzMap)
+	}
+	if !IsAuthRequired("/v1/anything", http.MethodGet) || HasPermission("/v1/anything", http.MethodGet, []string{"admin:all"}) {
+		t.Errorf("IsAuthRequired() and HasPermission() grant access to a route without rule")
+	}
+	if got := serve(Middleware(okHandler, staticChecker{}), http.MethodGet, "/v1/anything"); got != http.StatusForbidden {
+		t.Errorf("GET /v1/anything = %d, want %d", got, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,38 @@
+package authzmap
+
+import (
+	"net/http"
+	"testing"
+)
+
+// The routes are the ones of the fixture protos, see TestGeneratedEnvMiddleware. POST /v1/test2/{foo_id} requires
+// read:all, and is public in staging.
+
+func TestEnvMiddleware(t *testing.T) {
+	tests := []struct {
+		env, method, path string
+		want              int
+	}{
+		{"staging", http.MethodPost, "/v1/test2/42", http.StatusOK},
+		// Without override, the env falls back to the rule as declared
+		{"production", http.MethodPost, "/v1/test2/42", http.StatusForbidden},
+		{"", http.MethodPost, "/v1/test2/42", http.StatusForbidden},
+		{"staging", http.MethodGet, "/v1/test3/42", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		handler := EnvMiddleware(okHandler, staticChecker{}, tt.env)
+		if got := serve(handler, tt.method, tt.path); got != tt.want {
+			t.Errorf("%s %s in env %q = %d, want %d", tt.method, tt.path, tt.env, got, tt.want)
+		}
+	}
+}
+
+func TestForEnv(t *testing.T) {
+	rule := generatedAuthzMap["/v1/test2/{foo_id}|POST"]
+	if staging := rule.ForEnv("staging"); !staging.NoAuthRequired {
+		t.Errorf("ForEnv(staging).NoAuthRequired = false, want true")
+	}
+	if production := rule.ForEnv("production"); production.NoAuthRequired || len(production.Permissions) != 1 || production.Permissions[0] != "read:all" {
+		t.Errorf("ForEnv(production) = %+v, want the rule as declared", production)
+	}
+}
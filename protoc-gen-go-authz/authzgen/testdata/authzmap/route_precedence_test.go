@@ -0,0 +1,30 @@
+package authzmap
+
+import "testing"
+
+// The routes are GET /v1/users/me, /v1/users/{id} and /v1/users/{path=**}, see TestGeneratedRoutePrecedence.
+
+func TestRoutePrecedence(t *testing.T) {
+	tests := []struct {
+		path, template, permission string
+	}{
+		// Literal segments win over variables, which win over **
+		{"/v1/users/me", "/v1/users/me", "users:read_self"},
+		{"/v1/users/42", "/v1/users/{id}", "users:read"},
+		{"/v1/users/42/avatar", "/v1/users/{path=**}", "users:read_files"},
+		{"/v1/users/me/avatar", "/v1/users/{path=**}", "users:read_files"},
+	}
+	for _, tt := range tests {
+		if template, _ := generatedRouteTrie.match(tt.path, "GET"); template != tt.template {
+			t.Errorf("match(GET %s) = %q, want %q", tt.path, template, tt.template)
+		}
+		if !HasPermission(tt.path, "GET", []string{tt.permission}) {
+			t.Errorf("HasPermission(GET %s, %s) = false, want true", tt.path, tt.permission)
+		}
+		for _, other := range tests {
+			if other.permission != tt.permission && HasPermission(tt.path, "GET", []string{other.permission}) {
+				t.Errorf("HasPermission(GET %s, %s) = true, want false", tt.path, other.permission)
+			}
+		}
+	}
+}
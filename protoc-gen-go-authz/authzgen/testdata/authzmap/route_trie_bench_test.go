@@ -0,0 +1,97 @@
+package authzmap
+
+import (
+	"maps"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// benchRoute is a route of the authz map along with a request path it matches.
+type benchRoute struct {
+	method, template, path string
+	trie                   routeTrie // trie of the route alone, for the linear scan
+}
+
+// benchRoutes returns the HTTP routes of the authz map, their variables being given the value x.
+func benchRoutes() []benchRoute {
+	variable := regexp.MustCompile(`\{[^}]*\}`)
+	var routes []benchRoute
+	for key, rule := range generatedAuthzMap {
+		template, method, ok := strings.Cut(key, "|")
+		if !ok {
+			continue
+		}
+		routes = append(routes, benchRoute{
+			method:   method,
+			template: template,
+			path:     variable.ReplaceAllString(template, "x"),
+			trie:     newRouteTrie(map[string]AuthzRule{key: rule}),
+		})
+	}
+	return routes
+}
+
+// BenchmarkRouteMatch matches the request paths of every route, with the route trie and with a linear scan of the
+// routes, each matched on its own. The scan goes through every route since a later one can be more specific.
+func BenchmarkRouteMatch(b *testing.B) {
+	routes := benchRoutes()
+	b.Run("trie", func(b *testing.B) {
+		i := 0
+		for b.Loop() {
+			route := routes[i%len(routes)]
+			if _, ok := generatedRouteTrie.match(route.path, route.method); !ok {
+				b.Fatalf("no match for %s %s", route.method, route.path)
+			}
+			i++
+		}
+	})
+	b.Run("linear", func(b *testing.B) {
+		i := 0
+		for b.Loop() {
+			route := routes[i%len(routes)]
+			matched := false
+			for _, candidate := range routes {
+				if candidate.method != route.method {
+					continue
+				}
+				if _, ok := candidate.trie.match(route.path, route.method); ok {
+					matched = true
+				}
+			}
+			if !matched {
+				b.Fatalf("no match for %s %s", route.method, route.path)
+			}
+			i++
+		}
+	})
+}
+
+// BenchmarkHasPermissionWithMap checks the request paths of every route against a copy of the authz map, with
+// HasPermissionWithMap building the route trie of the map on every call and with the Authorizer of the map.
+func BenchmarkHasPermissionWithMap(b *testing.B) {
+	routes := benchRoutes()
+	authzMap := maps.Clone(generatedAuthzMap)
+	permissions := []string{"bench:read"}
+	b.Run("with_map", func(b *testing.B) {
+		i := 0
+		for b.Loop() {
+			route := routes[i%len(routes)]
+			if !HasPermissionWithMap(authzMap, route.path, route.method, permissions) {
+				b.Fatalf("permission denied for %s %s", route.method, route.path)
+			}
+			i++
+		}
+	})
+	b.Run("authorizer", func(b *testing.B) {
+		authorizer := NewAuthorizer(authzMap)
+		i := 0
+		for b.Loop() {
+			route := routes[i%len(routes)]
+			if !authorizer.HasPermission(route.path, route.method, permissions) {
+				b.Fatalf("permission denied for %s %s", route.method, route.path)
+			}
+			i++
+		}
+	})
+}
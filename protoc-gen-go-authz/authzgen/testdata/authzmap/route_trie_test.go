@@ -0,0 +1,81 @@
+package authzmap
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+// The routes are the ones of the fixture protos, see TestGeneratedRouteTrie.
+
+func TestRouteTrieMatch(t *testing.T) {
+	tests := []struct {
+		method, path string
+		want         string
+	}{
+		{"GET", "/v1/users", "/v1/users"},
+		{"POST", "/v1/test2/42", "/v1/test2/{foo_id}"},
+		{"GET", "/v1/test2/42", ""},
+		{"GET", "/v1/foos/42/test3", "/v1/foos/{foo_id}/test3"},
+		{"PATCH", "/v1/test11/42", "/v1/test11/{item.owner.id}"},
+		{"REPORT", "/v1/metrics:report", "/v1/metrics:report"},
+		// ** matches the remaining segments, at least the literal ones of its pattern
+		{"GET", "/v1/test10/42/files/a/b/c", "/v1/test10/{foo_id}/{path=files/**}"},
+		{"GET", "/v1/test10/42/files", "/v1/test10/{foo_id}/{path=files/**}"},
+		// Parameters match a single non-empty segment
+		{"POST", "/v1/test2/42/43", ""},
+		{"POST", "/v1/test2/", ""},
+		{"GET", "/v1/unknown", ""},
+	}
+	for _, tt := range tests {
+		template, ok := generatedRouteTrie.match(tt.path, tt.method)
+		if template != tt.want || ok != (tt.want != "") {
+			t.Errorf("match(%s %s) = %q, %v, want %q", tt.method, tt.path, template, ok, tt.want)
+		}
+	}
+}
+
+func TestPathValues(t *testing.T) {
+	tests := []struct {
+		template, path string
+		want           []string
+	}{
+		{"/v1/test2/{foo_id}", "/v1/test2/42", []string{"42"}},
+		{"/v1/foos/{foo_id}/test3", "/v1/foos/42/test3", []string{"42"}},
+		{"/v1/test10/{foo_id}/{path=files/**}", "/v1/test10/42/files/a/b", []string{"42", "files/a/b"}},
+		{"/v1/{name=projects/*}/jobs/{id}:cancel", "/v1/projects/p1/jobs/7:cancel", []string{"projects/p1", "7"}},
+	}
+	for _, tt := range tests {
+		if got := pathValues(tt.template, tt.path); !slices.Equal(got, tt.want) {
+			t.Errorf("pathValues(%s, %s) = %q, want %q", tt.template, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestAuthorizer(t *testing.T) {
+	// The authorizer of a map agrees with the functions building its route trie on every call
+	authzMap := maps.Clone(generatedAuthzMap)
+	authorizer := NewAuthorizer(authzMap)
+	for _, request := range []struct{ method, path string }{
+		{"POST", "/v1/test2/42"},
+		{"GET", "/v1/test2/42"},
+		{"GET", "/v1/test10/42/files/a/b/c"},
+		{"GET", "/v1/health"},
+		{"GET", "/v1/unknown"},
+	} {
+		method, path := request.method, request.path
+		if got, want := authorizer.IsAuthRequired(path, method), IsAuthRequiredWithMap(authzMap, path, method); got != want {
+			t.Errorf("IsAuthRequired(%s %s) = %v, want %v", method, path, got, want)
+		}
+		permissions := []string{"read:all"}
+		if got, want := authorizer.HasPermission(path, method, permissions), HasPermissionWithMap(authzMap, path, method, permissions); got != want {
+			t.Errorf("HasPermission(%s %s) = %v, want %v", method, path, got, want)
+		}
+		if got, want := authorizer.RoutePathParams(path, method), RoutePathParamsWithMap(authzMap, path, method); !slices.Equal(got, want) {
+			t.Errorf("RoutePathParams(%s %s) = %v, want %v", method, path, got, want)
+		}
+		if got, want := authorizer.NormalizePath(path, method), normalizePathForAuthzWithMap(authzMap, path, method); got != want {
+			t.Errorf("NormalizePath(%s %s) = %q, want %q", method, path, got, want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package authzmap
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+// The routes are GET /v1/projects/{project_id}, requiring project:{project_id}:read, and
+// /v1/projects/{project_id}/files/{path=**}, requiring project:{project_id}:file:{path}:read, see
+// TestGeneratedTemplatedPermissions.
+
+func TestResolvePermissions(t *testing.T) {
+	rule := generatedAuthzMap["/v1/projects/{project_id}|GET"]
+	if got, ok := ResolvePermissions(rule, map[string]string{"project_id": "p1"}); !ok || !slices.Equal(got, []string{"project:p1:read"}) {
+		t.Errorf("ResolvePermissions(p1) = %q, %v, want [project:p1:read], true", got, ok)
+	}
+
+	// Values naming another resource or a wildcard, and unresolved placeholders, are refused
+	for _, params := range []map[string]string{
+		{"project_id": ""},
+		{"project_id": "p1:admin"},
+		{"project_id": "*"},
+		{"project_id": "{project_id}"},
+		{"project_id": "p1/p2"},
+		{"other_id": "p1"},
+		nil,
+	} {
+		if got, ok := ResolvePermissions(rule, params); ok {
+			t.Errorf("ResolvePermissions(%v) = %q, true, want false", params, got)
+		}
+	}
+}
+
+func TestHasPermissionTemplated(t *testing.T) {
+	tests := []struct {
+		path        string
+		permissions []string
+		want        bool
+	}{
+		{"/v1/projects/p1", []string{"project:p1:read"}, true},
+		{"/v1/projects/p2", []string{"project:p1:read"}, false},
+		{"/v1/projects/p1/files/readme", []string{"project:p1:file:readme:read"}, true},
+		// The request path spelling the template does not select its rule as is
+		{"/v1/projects/{project_id}", []string{"project:{project_id}:read"}, false},
+		{"/v1/projects/*", []string{"project:*:read"}, false},
+		{"/v1/projects/p1:admin/files/readme", []string{"project:p1:admin:file:readme:read"}, false},
+		{"/v1/projects/p1/files/docs/readme", []string{"project:p1:file:docs/readme:read"}, false},
+	}
+	for _, tt := range tests {
+		if got := HasPermission(tt.path, http.MethodGet, tt.permissions); got != tt.want {
+			t.Errorf("HasPermission(%s, %v) = %v, want %v", tt.path, tt.permissions, got, tt.want)
+		}
+		want := http.StatusForbidden
+		if tt.want {
+			want = http.StatusOK
+		}
+		if got := serve(Middleware(okHandler, staticChecker(tt.permissions)), http.MethodGet, tt.path); got != want {
+			t.Errorf("GET %s with %v = %d, want %d", tt.path, tt.permissions, got, want)
+		}
+	}
+}
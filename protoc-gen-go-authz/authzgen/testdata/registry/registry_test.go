@@ -0,0 +1,68 @@
+package registry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"example.com/authztest/authzmap"
+)
+
+// The services are the ones of three.proto, see TestGeneratedRegistry.
+
+// staticChecker is a caller holding the permissions it lists.
+type staticChecker []string
+
+func (c staticChecker) Permissions(context.Context) ([]string, error) {
+	return c, nil
+}
+
+func TestServiceTables(t *testing.T) {
+	tables := map[string][]AuthzRule{
+		"FirstService":  FirstServiceAuthzRules,
+		"SecondService": SecondServiceAuthzRules,
+		"ThirdService":  ThirdServiceAuthzRules,
+	}
+	total := 0
+	for service, table := range tables {
+		if len(table) != 1 || table[0].ServiceName != service {
+			t.Errorf("%sAuthzRules = %+v, want the rule of %s.Get", service, table, service)
+		}
+		total += len(table)
+	}
+	if len(AuthzRules) != total {
+		t.Errorf("AuthzRules has %d rules, want %d", len(AuthzRules), total)
+	}
+
+	rule, ok := RuleForGRPCMethod("/three.v1.SecondService/Get")
+	if !ok || rule.HTTPPath != "/v1/second" || len(rule.Permissions) != 1 || rule.Permissions[0] != "second:read" {
+		t.Errorf("RuleForGRPCMethod() = %+v, %v, want the rule of GET /v1/second", rule, ok)
+	}
+}
+
+func TestServiceMiddleware(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	tests := []struct {
+		name       string
+		handler    http.Handler
+		path       string
+		permission string
+		want       int
+	}{
+		{"own service", authzmap.ServiceMiddleware(next, staticChecker{"first:read"}, "three.v1.FirstService"), "/v1/first", "first:read", http.StatusOK},
+		{"own service without permission", authzmap.ServiceMiddleware(next, staticChecker{"second:read"}, "three.v1.FirstService"), "/v1/first", "second:read", http.StatusForbidden},
+		// The routes of the other services match no rule, and are denied
+		{"other service", authzmap.ServiceMiddleware(next, staticChecker{"second:read"}, "three.v1.FirstService"), "/v1/second", "second:read", http.StatusForbidden},
+		{"every service", authzmap.Middleware(next, staticChecker{"second:read"}), "/v1/second", "second:read", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			tt.handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if recorder.Code != tt.want {
+				t.Errorf("GET %s with %s = %d, want %d", tt.path, tt.permission, recorder.Code, tt.want)
+			}
+		})
+	}
+}
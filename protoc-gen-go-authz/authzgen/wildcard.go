@@ -0,0 +1,132 @@
+package authzgen
+
+import (
+	"strings"
+)
+
+// wildcardSuffix ends the permissions standing for every permission sharing their prefix, e.g. admin:*.
+const wildcardSuffix = ":*"
+
+// isWildcardPermission reports whether permission is a wildcard, e.g. admin:*.
+func isWildcardPermission(permission string) bool {
+	return strings.HasSuffix(permission, wildcardSuffix)
+}
+
+// wildcardExpander rewrites wildcard permissions into the known permissions they match.
+type wildcardExpander struct {
+	known []string
+}
+
+// newWildcardExpander creates an expander matching wildcards against the concrete permissions of rules.
+func newWildcardExpander(rules []Rule) *wildcardExpander {
+	seen := make(map[string]bool)
+	var known []string
+	for _, rule := range rules {
+		for _, permission := range rule.Permissions {
+			// Templated permissions only resolve at runtime
+			if !isWildcardPermission(permission) && !IsTemplatedPermission(permission) && !seen[permission] {
+				seen[permission] = true
+				known = append(known, permission)
+			}
+		}
+	}
+
+	return &wildcardExpander{known: known}
+}
+
+// expandRule expands the wildcards of a rule and of its environment overrides, keeping the declared permissions in
+// RawPermissions.
+func (e *wildcardExpander) expandRule(rule Rule) Rule {
+	if rule.EnvOverrides != nil {
+		overrides := make(map[string]RuleOverride, len(rule.EnvOverrides))
+		for env := range rule.EnvOverrides {
+			overrides[env] = overrideOf(e.expandRule(rule.ForEnv(env)))
+		}
+		rule.EnvOverrides = overrides
+	}
+
+	hasWildcard := false
+	for _, permission := range rule.Permissions {
+		hasWildcard = hasWildcard || isWildcardPermission(permission)
+	}
+	for _, permission := range rule.PermissionEffects {
+		hasWildcard = hasWildcard || isWildcardPermission(permission.Name)
+	}
+	if !hasWildcard {
+		return rule
+	}
+
+	rule.RawPermissions = rule.Permissions
+	rule.Permissions = e.expand(rule.Permissions)
+	if rule.Require != nil {
+		expr := e.expandExpr(*rule.Require)
+		rule.Require = &expr
+	}
+	if rule.PermissionEffects != nil {
+		rule.PermissionEffects = e.expandEffects(rule.PermissionEffects)
+	}
+	return rule
+}
+
+// expandEffects expands the wildcards of permissions declared with an effect, the matches keeping that effect.
+func (e *wildcardExpander) expandEffects(permissions []Permission) []Permission {
+	expanded := make([]Permission, 0, len(permissions))
+	for _, permission := range permissions {
+		for _, name := range e.expand([]string{permission.Name}) {
+			expanded = append(expanded, Permission{Name: name, Effect: permission.Effect})
+		}
+	}
+	return expanded
+}
+
+// expand replaces the wildcards of permissions by the known permissions they match.
+// A wildcard matching nothing is kept as is so that it can still be granted literally.
+func (e *wildcardExpander) expand(permissions []string) []string {
+	expanded := make([]string, 0, len(permissions))
+	seen := make(map[string]bool, len(permissions))
+	add := func(permission string) {
+		if !seen[permission] {
+			seen[permission] = true
+			expanded = append(expanded, permission)
+		}
+	}
+
+	for _, permission := range permissions {
+		if !isWildcardPermission(permission) {
+			add(permission)
+			continue
+		}
+
+		prefix := strings.TrimSuffix(permission, "*")
+		matched := false
+		for _, known := range e.known {
+			if strings.HasPrefix(known, prefix) {
+				matched = true
+				add(known)
+			}
+		}
+		if !matched {
+			add(permission)
+		}
+	}
+
+	return expanded
+}
+
+// expandExpr expands the wildcards of every permission list of the expression.
+func (e *wildcardExpander) expandExpr(expr PermissionExpr) PermissionExpr {
+	expanded := PermissionExpr{}
+	if len(expr.AnyOf) > 0 {
+		expanded.AnyOf = e.expand(expr.AnyOf)
+	}
+	if len(expr.AllOf) > 0 {
+		expanded.AllOf = e.expand(expr.AllOf)
+	}
+	for _, nested := range expr.All {
+		expanded.All = append(expanded.All, e.expandExpr(nested))
+	}
+	for _, nested := range expr.Any {
+		expanded.Any = append(expanded.Any, e.expandExpr(nested))
+	}
+	return expanded
+}
@@ -0,0 +1,130 @@
+package authzgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+// generateYAMLFile writes the authorization rules as a YAML document, holding the same fields in the same order
+// as the JSON document of the json target, for configuration repositories written in YAML.
+// Rules are expected sorted with SortRules so that the output can be committed and diffed.
+func generateYAMLFile(plugin *protogen.Plugin, out outputPackage, rules []Rule) error {
+	document := struct {
+		Rules []Rule `json:"rules"`
+	}{Rules: rules}
+	content, err := json.Marshal(document)
+	if err != nil {
+		return fmt.Errorf("failed to marshal authz rules: %w", err)
+	}
+
+	// The document goes through JSON so that the YAML keys are the JSON ones, in the order of the fields
+	node, err := decodeYAMLNode(json.NewDecoder(bytes.NewReader(content)))
+	if err != nil {
+		return fmt.Errorf("failed to convert authz rules to YAML: %w", err)
+	}
+	var manifest strings.Builder
+	manifest.WriteString("# Code generated by protoc-gen-go-authz. DO NOT EDIT.\n")
+	node.write(&manifest, "", "")
+
+	gen := out.newFile(plugin, "authz_manifest.yaml")
+	_, err = gen.Write([]byte(manifest.String()))
+	return err
+}
+
+// yamlNode is a JSON value to be written as YAML, objects keeping the order of their keys.
+type yamlNode struct {
+	scalar   string      // YAML representation of a scalar, empty for objects and arrays
+	keys     []string    // keys of an object, in order
+	children []*yamlNode // values of an object or items of an array
+	object   bool
+}
+
+// decodeYAMLNode reads the next JSON value of dec.
+func decodeYAMLNode(dec *json.Decoder) (*yamlNode, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token := token.(type) {
+	case json.Delim:
+		node := &yamlNode{object: token == '{'}
+		for dec.More() {
+			if node.object {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.keys = append(node.keys, key.(string))
+			}
+			child, err := decodeYAMLNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			node.children = append(node.children, child)
+		}
+		// Closing delimiter
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case string:
+		// JSON strings are valid YAML double-quoted scalars, which never get mistaken for numbers or booleans
+		return &yamlNode{scalar: strconv.Quote(token)}, nil
+	case nil:
+		return &yamlNode{scalar: "null"}, nil
+	default:
+		return &yamlNode{scalar: fmt.Sprint(token)}, nil
+	}
+}
+
+// write writes the node as a YAML block, one line per scalar so that long permission lists stay readable.
+// Lines are indented with indent, except the first one which starts with first, e.g. "- " for array items.
+func (n *yamlNode) write(out *strings.Builder, indent, first string) {
+	prefix := first
+	for i, child := range n.children {
+		if n.object {
+			out.WriteString(prefix + yamlKey(n.keys[i]) + ":")
+			child.writeValue(out, indent+"  ", indent+"  ")
+		} else {
+			out.WriteString(prefix + "-")
+			child.writeValue(out, indent+"  ", " ")
+		}
+		prefix = indent
+	}
+}
+
+// yamlKey returns a key as a plain scalar when it is an identifier, e.g. http_path, and double-quoted otherwise.
+func yamlKey(key string) string {
+	if yamlPlainKeyRegex.MatchString(key) {
+		return key
+	}
+	return strconv.Quote(key)
+}
+
+// yamlPlainKeyRegex matches the keys written as plain scalars.
+var yamlPlainKeyRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// writeValue writes the node as the value of a key or array item, inline for scalars and empty collections.
+func (n *yamlNode) writeValue(out *strings.Builder, indent, first string) {
+	switch {
+	case n.scalar != "":
+		out.WriteString(" " + n.scalar + "\n")
+	case len(n.children) == 0 && n.object:
+		out.WriteString(" {}\n")
+	case len(n.children) == 0:
+		out.WriteString(" []\n")
+	case n.object && first == " ":
+		// Objects in arrays start on the line of their dash
+		n.write(out, indent, first)
+	default:
+		out.WriteString("\n")
+		n.write(out, indent, indent)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// logHandler is a slog.Handler writing the records at or above its level as single lines prefixed with the name of
+// the plugin and the level, e.g. protoc-gen-go-authz: warning: ..., followed by their attributes. It writes to
+// stderr, protoc reading the CodeGeneratorResponse on stdout.
+type logHandler struct {
+	w     io.Writer
+	mu    *sync.Mutex
+	level slog.Leveler
+	attrs string // attributes added with WithAttrs, formatted
+	group string // prefix of the attribute keys, ending with a dot
+}
+
+// newLogHandler returns a handler writing the records at or above level to w.
+func newLogHandler(w io.Writer, level slog.Leveler) *logHandler {
+	return &logHandler{w: w, mu: new(sync.Mutex), level: level}
+}
+
+func (h *logHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *logHandler) Handle(_ context.Context, record slog.Record) error {
+	var line strings.Builder
+	fmt.Fprintf(&line, "protoc-gen-go-authz: %s: %s%s", levelName(record.Level), record.Message, h.attrs)
+	record.Attrs(func(attr slog.Attr) bool {
+		writeAttr(&line, h.group, attr)
+		return true
+	})
+	line.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, line.String())
+	return err
+}
+
+func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var formatted strings.Builder
+	for _, attr := range attrs {
+		writeAttr(&formatted, h.group, attr)
+	}
+	clone := *h
+	clone.attrs += formatted.String()
+	return &clone
+}
+
+func (h *logHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.group += name + "."
+	return &clone
+}
+
+// writeAttr writes an attribute as key=value, qualifying its key with group, and the attributes of a group one by one.
+func writeAttr(w *strings.Builder, group string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			group += attr.Key + "."
+		}
+		for _, nested := range attr.Value.Group() {
+			writeAttr(w, group, nested)
+		}
+		return
+	}
+	fmt.Fprintf(w, " %s%s=%v", group, attr.Key, attr.Value)
+}
+
+// levelName returns the name of a level, e.g. warning for slog.LevelWarn.
+func levelName(level slog.Level) string {
+	if level == slog.LevelWarn {
+		return "warning"
+	}
+	return strings.ToLower(level.String())
+}
+
+// logLevels are the levels accepted by the log parameter, by name.
+var logLevels = map[string]slog.Level{
+	"debug":   slog.LevelDebug,
+	"info":    slog.LevelInfo,
+	"warn":    slog.LevelWarn,
+	"warning": slog.LevelWarn,
+	"error":   slog.LevelError,
+}
+
+// levelFlag is a flag setting the level of a slog.LevelVar by name.
+type levelFlag struct {
+	level *slog.LevelVar
+}
+
+func (l levelFlag) String() string {
+	if l.level == nil {
+		return ""
+	}
+	return levelName(l.level.Level())
+}
+
+func (l levelFlag) Set(value string) error {
+	level, ok := logLevels[value]
+	if !ok {
+		return fmt.Errorf("unknown level %q, expected debug, info, warn or error", value)
+	}
+	l.level.Set(level)
+	return nil
+}
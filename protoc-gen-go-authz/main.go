@@ -11,6 +11,62 @@
 //	    opt:
 //	      - paths=source_relative
 //
+// Outside of protoc, the plugin reads a FileDescriptorSet built with its imports and writes the rules of its files,
+// or of the files given as arguments, as the JSON document of the json target:
+//
+//	buf build -o image.binpb
+//	protoc-gen-go-authz --descriptor_set_in=image.binpb --param=strict=true --output=rules.json
+//
+// Supported plugin parameters:
+//
+//	authz_extension=proto.v1.authz     full name of the authz method option extension
+//	service_authz_extension=proto.v1.service_authz
+//	                                   full name of the authz service option extension
+//	file_authz_extension=proto.v1.file_authz
+//	                                   full name of the authz file option extension
+//	authz_extension_number=50001       field number of the authz method, service and file option extensions
+//	grpc_fallback=true                 emit rules keyed by the gRPC path for methods without google.api.http
+//	permission_pattern=^[a-z][a-z0-9_]*(:[a-z][a-z0-9_]*)+$
+//	                                   regular expression every permission must match
+//	target=http-middleware             additional output to generate next to the authz map, can be repeated:
+//	                                   http-middleware, grpc-interceptor, json, yaml, openapi, openapi-overlay,
+//	                                   constants, registry, test-helper, markdown, envoy-rbac, rego, casbin,
+//	                                   grpc-authz, istio or spicedb
+//	openapi_security_scheme=bearerAuth name of the security scheme listing the permissions in the openapi and
+//	                                   openapi-overlay targets
+//	envoy_permissions_claim=permissions
+//	                                   JWT claim listing the permissions of the caller in the envoy-rbac target
+//	envoy_roles_claim=roles            JWT claim listing the roles of the caller in the envoy-rbac target
+//	envoy_permissions_header=          request header listing the permissions of the caller in the envoy-rbac target,
+//	                                   instead of the JWT claim
+//	envoy_roles_header=                request header listing the roles of the caller in the envoy-rbac target,
+//	                                   instead of the JWT claim
+//	grpc_authz_metadata_prefix=x-authz-
+//	                                   prefix of the metadata carrying the permissions and roles of the caller in the
+//	                                   grpc-authz target
+//	istio_namespace=                   namespace of the policies in the istio target, omitted when empty
+//	istio_selector=app=api             label selecting the workloads of the policies in the istio target, can be
+//	                                   repeated, every workload of the namespace being selected when unset
+//	istio_permissions_claim=permissions
+//	                                   JWT claim listing the permissions of the caller in the istio target
+//	istio_roles_claim=roles            JWT claim listing the roles of the caller in the istio target
+//	spicedb_permission_separator=:     separator of the resource type and the action of the permissions in the
+//	                                   spicedb target
+//	spicedb_subject_type=user          definition of the subjects granted the permissions in the spicedb target
+//	output_dir=authzmap                directory of the authz map and of the target files, relative to the output
+//	                                   directory
+//	output_go_package=github.com/aymenworks/public-medium-protocgen/gen/authzmap
+//	                                   Go package of the authz map and of the Go targets, as import path[;name]
+//	registry_suffix=_authz.pb.go       suffix of the registry files in the registry target
+//	http_allow_unmatched=false         pass through the requests matching no rule in the http-middleware target
+//	log=warn                           level of the diagnostics logged to stderr: debug, info, warn or error
+//	verbose=false                      log the parser debug diagnostics to stderr, same as log=debug
+//	strict=false                       fail when a method has no authz option instead of skipping it
+//	strict_well_known=false            apply the strict mode to grpc.health and grpc.reflection services as well
+//	no_auth_conflict_warning=false     only warn when an authz option declares permissions along with no_auth_required
+//	allow_empty_permissions=false      accept methods with neither permissions nor no_auth_required
+//	check=false                        only validate, in strict mode, reporting every violation and generating nothing
+//
 // The plugin reads proto files with authz options like:
 //
 //	option (proto.v1.authz) = { permissions: ["permission1", "permission2"] };
@@ -19,228 +75,186 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
 
+	"github.com/aymenworks/public-medium-protocgen/protoc-gen-go-authz/authzgen"
 	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/pluginpb"
 )
 
-// authzRule represents a single authorization rule.
-type authzRule struct {
-	HTTPPath       string
-	HTTPMethod     string
-	Permissions    []string
-	NoAuthRequired bool
+// targetsFlag is a repeatable flag collecting the selected targets.
+type targetsFlag map[authzgen.Target]bool
+
+func (t targetsFlag) String() string {
+	targets := make([]string, 0, len(t))
+	for target := range t {
+		targets = append(targets, string(target))
+	}
+	sort.Strings(targets)
+	return strings.Join(targets, ",")
 }
 
-func main() {
-	protogen.Options{}.Run(func(plugin *protogen.Plugin) error {
-		plugin.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+func (t targetsFlag) Set(value string) error {
+	if !slices.Contains(authzgen.Targets, authzgen.Target(value)) {
+		return fmt.Errorf("unknown target %q", value)
+	}
+	t[authzgen.Target(value)] = true
+	return nil
+}
 
-		parser := newProtoAuthzParser()
-		var allAuthzRules []authzRule
+// labelsFlag is a repeatable flag collecting key=value labels.
+type labelsFlag map[string]string
 
-		// Process each proto file
-		for _, file := range plugin.Files {
-			if !file.Generate {
-				continue
-			}
+func (l labelsFlag) String() string {
+	labels := make([]string, 0, len(l))
+	for key, value := range l {
+		labels = append(labels, key+"="+value)
+	}
+	sort.Strings(labels)
+	return strings.Join(labels, ",")
+}
 
-			rules := parser.parseFile(file)
-			allAuthzRules = append(allAuthzRules, rules...)
-		}
+func (l labelsFlag) Set(value string) error {
+	key, value, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("label %q is not in the key=value form", key+value)
+	}
+	l[key] = value
+	return nil
+}
 
-		// Always generate the authz map file, even if empty
-		// This ensures the package exists for imports
-		generateAuthzMapFile(plugin, allAuthzRules)
+// pluginParams holds the plugin parameters, set one at a time and turned into the options of the generation.
+type pluginParams struct {
+	flags flag.FlagSet
+	opts  authzgen.Options
+	errs  []error
+	level slog.LevelVar // level of the logger of opts, set with the log parameter
 
-		return nil
-	})
+	// Parameters converted and validated by options
+	authzExtension        *string
+	serviceAuthzExtension *string
+	fileAuthzExtension    *string
+	authzExtensionNumber  *int
+	permissionPattern     *string
+	verbose               *bool
+}
+
+// newPluginParams returns the plugin parameters, set to their defaults.
+func newPluginParams() *pluginParams {
+	p := &pluginParams{opts: authzgen.DefaultOptions()}
+	// Warnings, such as skipped methods, are reported by default
+	p.level.Set(slog.LevelWarn)
+	p.opts.Logger = slog.New(newLogHandler(os.Stderr, &p.level))
+	p.authzExtension = p.flags.String("authz_extension", string(p.opts.ExtensionNames.Method), "full name of the authz method option extension")
+	p.serviceAuthzExtension = p.flags.String("service_authz_extension", string(p.opts.ExtensionNames.Service), "full name of the authz service option extension")
+	p.fileAuthzExtension = p.flags.String("file_authz_extension", string(p.opts.ExtensionNames.File), "full name of the authz file option extension")
+	p.authzExtensionNumber = p.flags.Int("authz_extension_number", int(p.opts.ExtensionNumber), "field number of the authz method, service and file option extensions")
+	p.flags.BoolVar(&p.opts.GRPCFallback, "grpc_fallback", p.opts.GRPCFallback, "emit rules keyed by the gRPC path for methods without google.api.http")
+	p.permissionPattern = p.flags.String("permission_pattern", p.opts.PermissionPattern.String(), "regular expression every permission must match")
+	p.flags.Var(targetsFlag(p.opts.Targets), "target", "additional output to generate next to the authz map, can be repeated")
+	p.flags.StringVar(&p.opts.OpenAPISecurityScheme, "openapi_security_scheme", p.opts.OpenAPISecurityScheme, "name of the security scheme listing the permissions in the openapi and openapi-overlay targets")
+	p.flags.StringVar(&p.opts.EnvoyPermissionsClaim, "envoy_permissions_claim", p.opts.EnvoyPermissionsClaim, "JWT claim listing the permissions of the caller in the envoy-rbac target")
+	p.flags.StringVar(&p.opts.EnvoyRolesClaim, "envoy_roles_claim", p.opts.EnvoyRolesClaim, "JWT claim listing the roles of the caller in the envoy-rbac target")
+	p.flags.StringVar(&p.opts.EnvoyPermissionsHeader, "envoy_permissions_header", p.opts.EnvoyPermissionsHeader, "request header listing the permissions of the caller in the envoy-rbac target, instead of the JWT claim")
+	p.flags.StringVar(&p.opts.EnvoyRolesHeader, "envoy_roles_header", p.opts.EnvoyRolesHeader, "request header listing the roles of the caller in the envoy-rbac target, instead of the JWT claim")
+	p.flags.StringVar(&p.opts.GRPCAuthzMetadataPrefix, "grpc_authz_metadata_prefix", p.opts.GRPCAuthzMetadataPrefix, "prefix of the metadata carrying the permissions and roles of the caller in the grpc-authz target")
+	p.flags.StringVar(&p.opts.IstioNamespace, "istio_namespace", p.opts.IstioNamespace, "namespace of the policies in the istio target, omitted when empty")
+	p.flags.Var(labelsFlag(p.opts.IstioSelector), "istio_selector", "label selecting the workloads of the policies in the istio target, can be repeated")
+	p.flags.StringVar(&p.opts.IstioPermissionsClaim, "istio_permissions_claim", p.opts.IstioPermissionsClaim, "JWT claim listing the permissions of the caller in the istio target")
+	p.flags.StringVar(&p.opts.IstioRolesClaim, "istio_roles_claim", p.opts.IstioRolesClaim, "JWT claim listing the roles of the caller in the istio target")
+	p.flags.StringVar(&p.opts.SpiceDBPermissionSeparator, "spicedb_permission_separator", p.opts.SpiceDBPermissionSeparator, "separator of the resource type and the action of the permissions in the spicedb target")
+	p.flags.StringVar(&p.opts.SpiceDBSubjectType, "spicedb_subject_type", p.opts.SpiceDBSubjectType, "definition of the subjects granted the permissions in the spicedb target")
+	p.flags.StringVar(&p.opts.OutputDir, "output_dir", p.opts.OutputDir, "directory of the authz map and of the target files, relative to the output directory")
+	p.flags.StringVar(&p.opts.OutputGoPackage, "output_go_package", p.opts.OutputGoPackage, "Go package of the authz map and of the generated Go targets, as import path[;name]")
+	p.flags.StringVar(&p.opts.RegistrySuffix, "registry_suffix", p.opts.RegistrySuffix, "suffix of the registry files, appended to the name of the first proto file of the package")
+	p.flags.BoolVar(&p.opts.HTTPAllowUnmatched, "http_allow_unmatched", p.opts.HTTPAllowUnmatched, "pass through the requests matching no rule in the http-middleware target")
+	p.flags.Var(levelFlag{&p.level}, "log", "level of the diagnostics logged to stderr: debug, info, warn or error")
+	p.verbose = p.flags.Bool("verbose", false, "log the parser debug diagnostics to stderr, same as log=debug")
+	p.flags.BoolVar(&p.opts.Strict, "strict", p.opts.Strict, "fail when a method has no authz option instead of skipping it")
+	p.flags.BoolVar(&p.opts.StrictWellKnown, "strict_well_known", p.opts.StrictWellKnown, "apply the strict mode to grpc.health and grpc.reflection services as well")
+	p.flags.BoolVar(&p.opts.NoAuthConflictWarning, "no_auth_conflict_warning", p.opts.NoAuthConflictWarning, "only warn when an authz option declares permissions along with no_auth_required")
+	p.flags.BoolVar(&p.opts.Check, "check", p.opts.Check, "only validate, in strict mode, reporting every violation and generating nothing")
+	p.flags.BoolVar(&p.opts.AllowEmptyPermissions, "allow_empty_permissions", p.opts.AllowEmptyPermissions, "accept methods with neither permissions nor no_auth_required")
+	return p
 }
 
-// generateAuthzMapFile generates the Go file containing the authorization map.
-func generateAuthzMapFile(plugin *protogen.Plugin, rules []authzRule) {
-	// Generate in a separate package to avoid circular imports
-	filename := "authzmap/generated_authz_map.go"
-	gen := plugin.NewGeneratedFile(filename, "github.com/aymenworks/public-medium-protocgen/gen/authzmap")
-
-	// File header and package
-	gen.P("// Code generated by protoc-gen-go-authz. DO NOT EDIT.")
-	gen.P()
-	gen.P("package authzmap")
-	gen.P()
-	gen.P("import \"strings\"")
-	gen.P()
-
-	// Generate the AuthzRule struct
-	gen.P("// AuthzRule represents authorization rules for a method")
-	gen.P("type AuthzRule struct {")
-	gen.P("	Permissions    []string")
-	gen.P("	NoAuthRequired bool")
-	gen.P("}")
-	gen.P()
-
-	// Generate the authorization map
-	gen.P("// generatedAuthzMap contains authorization rules extracted from proto definitions")
-	gen.P("// This map is automatically generated during go tool buf generate")
-	gen.P("var generatedAuthzMap = map[string]AuthzRule{")
-
-	for _, rule := range rules {
-		key := rule.HTTPPath + "|" + strings.ToUpper(rule.HTTPMethod)
-		permissionsStr := "[]string{"
-		for i, permission := range rule.Permissions {
-			if i > 0 {
-				permissionsStr += ", "
-			}
-			permissionsStr += `"` + permission + `"`
+// set sets a plugin parameter. Errors are collected and reported by options, in the CodeGeneratorResponse, instead
+// of aborting the plugin.
+func (p *pluginParams) set(name, value string) error {
+	if err := p.flags.Set(name, value); err != nil {
+		p.errs = append(p.errs, fmt.Errorf("invalid plugin parameter %s=%s: %w", name, value, err))
+	}
+	return nil
+}
+
+// options returns the options of the generation set by the parameters, or the errors of the invalid ones.
+func (p *pluginParams) options() (authzgen.Options, error) {
+	if err := errors.Join(p.errs...); err != nil {
+		return authzgen.Options{}, err
+	}
+	p.opts.ExtensionNames = authzgen.ExtensionNames{
+		Method:  protoreflect.FullName(*p.authzExtension),
+		Service: protoreflect.FullName(*p.serviceAuthzExtension),
+		File:    protoreflect.FullName(*p.fileAuthzExtension),
+	}
+	for param, name := range map[string]protoreflect.FullName{
+		"authz_extension":         p.opts.ExtensionNames.Method,
+		"service_authz_extension": p.opts.ExtensionNames.Service,
+		"file_authz_extension":    p.opts.ExtensionNames.File,
+	} {
+		if !name.IsValid() {
+			return authzgen.Options{}, fmt.Errorf("invalid plugin parameter %s=%s: not a valid full name", param, name)
+		}
+	}
+	// The options messages declare extensions 1000 to max, the numbers reserved to the implementation excluded
+	if number := protowire.Number(*p.authzExtensionNumber); number < 1000 || !number.IsValid() || (number >= protowire.FirstReservedNumber && number <= protowire.LastReservedNumber) {
+		return authzgen.Options{}, fmt.Errorf("invalid plugin parameter authz_extension_number=%d: not in the extension range of the options messages", *p.authzExtensionNumber)
+	}
+	p.opts.ExtensionNumber = protoreflect.FieldNumber(*p.authzExtensionNumber)
+	permissionRegexp, err := regexp.Compile(*p.permissionPattern)
+	if err != nil {
+		return authzgen.Options{}, fmt.Errorf("invalid plugin parameter permission_pattern=%s: %w", *p.permissionPattern, err)
+	}
+	p.opts.PermissionPattern = permissionRegexp
+	if *p.verbose {
+		p.level.Set(slog.LevelDebug)
+	}
+	return p.opts, nil
+}
+
+func main() {
+	// stderr is the only channel protoc surfaces besides the generated files, warnings are written there
+	log.SetFlags(0)
+	log.SetPrefix("protoc-gen-go-authz: ")
+
+	// protoc runs the plugin without arguments, they select the standalone mode
+	if len(os.Args) > 1 {
+		if err := runStandalone(os.Args[1:]); err != nil {
+			log.Fatal(err)
 		}
-		permissionsStr += "}"
-		gen.P("	" + `"` + key + `"` + ": {")
-		gen.P("		Permissions:    " + permissionsStr + ",")
-		gen.P("		NoAuthRequired: " + fmt.Sprintf("%t", rule.NoAuthRequired) + ",")
-		gen.P("	},")
+		return
 	}
 
-	gen.P("}")
-	gen.P()
-
-	gen.P("// normalizePathForAuthzWithMap converts a path with actual values to its template form")
-	gen.P("// by matching against all known parameterized paths in the provided authz map for the given method")
-	gen.P("// e.g., \"/v1/foo/123\" -> \"/v1/foo/{foo_id}\"")
-	gen.P("func normalizePathForAuthzWithMap(authzMap map[string]AuthzRule, actualPath, method string) string {")
-	gen.P("	actualParts := strings.Split(strings.Trim(actualPath, \"/\"), \"/\")")
-	gen.P("	")
-	gen.P("	// Try to match against all known parameterized paths in the map for this specific method")
-	gen.P("	for templatePath := range authzMap {")
-	gen.P("		// Only consider templates with matching HTTP method")
-	gen.P("		pathAndMethod := strings.Split(templatePath, \"|\")")
-	gen.P("		if len(pathAndMethod) != 2 || pathAndMethod[1] != method {")
-	gen.P("			continue")
-	gen.P("		}")
-	gen.P("		path := pathAndMethod[0]")
-	gen.P("		templateParts := strings.Split(strings.Trim(path, \"/\"), \"/\")")
-	gen.P("		")
-	gen.P("		// Skip if different number of path segments")
-	gen.P("		if len(actualParts) != len(templateParts) {")
-	gen.P("			continue")
-	gen.P("		}")
-	gen.P("		")
-	gen.P("		// Check if the path matches the template")
-	gen.P("		matches := true")
-	gen.P("		for i := 0; i < len(actualParts); i++ {")
-	gen.P("			templatePart := templateParts[i]")
-	gen.P("			actualPart := actualParts[i]")
-	gen.P("			")
-	gen.P("			// If template part is a parameter (enclosed in {})")
-	gen.P("			if strings.HasPrefix(templatePart, \"{\") && strings.HasSuffix(templatePart, \"}\") {")
-	gen.P("				// Accept any non-empty value for parameter")
-	gen.P("				if actualPart == \"\" {")
-	gen.P("					matches = false")
-	gen.P("					break")
-	gen.P("				}")
-	gen.P("				// Parameter matches, continue")
-	gen.P("				continue")
-	gen.P("			}")
-	gen.P("			")
-	gen.P("			// If template part is literal, it must match exactly")
-	gen.P("			if templatePart != actualPart {")
-	gen.P("				matches = false")
-	gen.P("				break")
-	gen.P("			}")
-	gen.P("		}")
-	gen.P("		")
-	gen.P("		// If we found a matching template, return it")
-	gen.P("		if matches {")
-	gen.P("			return path")
-	gen.P("		}")
-	gen.P("	}")
-	gen.P("	")
-	gen.P("	// No template found, return original path")
-	gen.P("	return actualPath")
-	gen.P("}")
-	gen.P()
-
-	gen.P("// normalizePathForAuthz converts a path with actual values to its template form using the default authz map")
-	gen.P("func normalizePathForAuthz(actualPath, method string) string {")
-	gen.P("	return normalizePathForAuthzWithMap(generatedAuthzMap, actualPath, method)")
-	gen.P("}")
-	gen.P()
-
-	gen.P("// IsAuthRequiredWithMap returns whether authentication is required for a given path and method using provided authz map")
-	gen.P("func IsAuthRequiredWithMap(authzMap map[string]AuthzRule, path, method string) bool {")
-	gen.P("	// Health check endpoints do not require authentication")
-	gen.P("	if path == \"/v1/health\" && method == \"GET\" {")
-	gen.P("		return false")
-	gen.P("	}")
-	gen.P("	")
-	gen.P("	// First try exact match")
-	gen.P("	key := path + \"|\" + method")
-	gen.P("	rule, exists := authzMap[key]")
-	gen.P("	")
-	gen.P("	// If not found, try with normalized path for parameterized routes")
-	gen.P("	if !exists {")
-	gen.P("		normalizedPath := normalizePathForAuthzWithMap(authzMap, path, method)")
-	gen.P("		key = normalizedPath + \"|\" + method")
-	gen.P("		rule, exists = authzMap[key]")
-	gen.P("	}")
-	gen.P("	")
-	gen.P("	if !exists {")
-	gen.P("		return true // Default to requiring auth for undefined paths")
-	gen.P("	}")
-	gen.P("	return !rule.NoAuthRequired")
-	gen.P("}")
-	gen.P()
-
-	gen.P("// IsAuthRequired returns whether authentication is required for a given path and method")
-	gen.P("func IsAuthRequired(path, method string) bool {")
-	gen.P("	return IsAuthRequiredWithMap(generatedAuthzMap, path, method)")
-	gen.P("}")
-	gen.P()
-
-	gen.P("// HasPermissionWithMap checks if any of the user permissions is allowed for a given path and method using provided authz map")
-	gen.P("func HasPermissionWithMap(authzMap map[string]AuthzRule, path, method string, userPermissions []string) bool {")
-	gen.P("	// Health check endpoints do not require authentication")
-	gen.P("	if path == \"/v1/health\" && method == \"GET\" {")
-	gen.P("		return true")
-	gen.P("	}")
-	gen.P("	")
-	gen.P("	// First try exact match")
-	gen.P("	key := path + \"|\" + method")
-	gen.P("	rule, exists := authzMap[key]")
-	gen.P("	")
-	gen.P("	// If not found, try with normalized path for parameterized routes")
-	gen.P("	if !exists {")
-	gen.P("		normalizedPath := normalizePathForAuthzWithMap(authzMap, path, method)")
-	gen.P("		key = normalizedPath + \"|\" + method")
-	gen.P("		rule, exists = authzMap[key]")
-	gen.P("	}")
-	gen.P("	")
-	gen.P("	if !exists {")
-	gen.P("		return false")
-	gen.P("	}")
-	gen.P()
-	gen.P("	// If no auth is required, always allow")
-	gen.P("	if rule.NoAuthRequired {")
-	gen.P("		return true")
-	gen.P("	}")
-	gen.P("	")
-	gen.P("	// Check if user has any of the required permissions")
-	gen.P("	requiredPermissionMap := make(map[string]bool, len(rule.Permissions))")
-	gen.P("	for _, permission := range rule.Permissions {")
-	gen.P("		requiredPermissionMap[strings.ToLower(permission)] = true")
-	gen.P("	}")
-	gen.P("	for _, userPermission := range userPermissions {")
-	gen.P("		if requiredPermissionMap[strings.ToLower(userPermission)] {")
-	gen.P("			return true")
-	gen.P("		}")
-	gen.P("	}")
-	gen.P("	return false")
-	gen.P("}")
-	gen.P()
-
-	gen.P("// HasPermission checks if any of the user permissions is allowed for a given path and method")
-	gen.P("func HasPermission(path, method string, userPermissions []string) bool {")
-	gen.P("	return HasPermissionWithMap(generatedAuthzMap, path, method, userPermissions)")
-	gen.P("}")
+	params := newPluginParams()
+	options := protogen.Options{ParamFunc: params.set}
+	options.Run(func(plugin *protogen.Plugin) error {
+		plugin.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+
+		opts, err := params.options()
+		if err != nil {
+			return err
+		}
+		return authzgen.Generate(plugin, opts)
+	})
 }
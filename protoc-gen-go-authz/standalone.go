@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/aymenworks/public-medium-protocgen/protoc-gen-go-authz/authzgen"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// runStandalone parses the files of a FileDescriptorSet instead of a request sent by protoc, e.g. the image built by
+// buf build -o image.binpb, and writes their rules as the JSON document of the json target. The files to parse are
+// given as arguments, every file of the set by default. Without proto source, the set must include the file
+// declaring the authz extensions, buf includes the imports by default and protoc with --include_imports.
+func runStandalone(args []string) error {
+	flags := flag.NewFlagSet("protoc-gen-go-authz", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: protoc-gen-go-authz --descriptor_set_in=image.binpb [--param=strict=true,...] [--output=rules.json] [file.proto ...]")
+		flags.PrintDefaults()
+	}
+	descriptorSetIn := flags.String("descriptor_set_in", "", "FileDescriptorSet to read, including its imports")
+	param := flags.String("param", "", "plugin parameters, comma separated as in the opt of buf.gen.yaml")
+	output := flags.String("output", "-", "file to write the rules to, - for stdout")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if *descriptorSetIn == "" {
+		return errors.New("--descriptor_set_in is required outside of protoc")
+	}
+
+	content, err := os.ReadFile(*descriptorSetIn)
+	if err != nil {
+		return fmt.Errorf("failed to read descriptor set: %w", err)
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(content, &set); err != nil {
+		return fmt.Errorf("failed to decode descriptor set %s: %w", *descriptorSetIn, err)
+	}
+
+	// The request is the one protoc would send, the Go packages of the files without go_package being irrelevant to
+	// the rules they are mapped to their directory
+	request := &pluginpb.CodeGeneratorRequest{FileToGenerate: flags.Args(), ProtoFile: set.File}
+	var parameters []string
+	for _, file := range set.File {
+		if len(flags.Args()) == 0 {
+			request.FileToGenerate = append(request.FileToGenerate, file.GetName())
+		}
+		if file.GetOptions().GetGoPackage() == "" {
+			parameters = append(parameters, "M"+file.GetName()+"="+path.Join("authz", path.Dir(file.GetName())))
+		}
+	}
+	if *param != "" {
+		parameters = append(parameters, *param)
+	}
+	request.Parameter = proto.String(strings.Join(parameters, ","))
+
+	params := newPluginParams()
+	plugin, err := protogen.Options{ParamFunc: params.set}.New(request)
+	if err != nil {
+		return err
+	}
+	opts, err := params.options()
+	if err != nil {
+		return err
+	}
+	rules, err := authzgen.ParseRules(plugin, opts)
+	if err != nil {
+		return err
+	}
+
+	document, err := authzgen.MarshalRulesJSON(rules)
+	if err != nil {
+		return err
+	}
+	if *output == "-" {
+		_, err = os.Stdout.Write(document)
+		return err
+	}
+	return os.WriteFile(*output, document, 0o644)
+}